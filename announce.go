@@ -0,0 +1,79 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+var announcing int32
+
+// AnnounceWrites sets whether successful writes are announced to screen
+// readers via the platform accessibility APIs, for instance, "Copied 12
+// characters" or "Copied image". The announcements are disabled by
+// default.
+//
+// Announcements are posted using NSAccessibility on macOS,
+// UIAccessibility on iOS, and UI Automation on Windows. They are
+// not supported on Linux and Android yet.
+func AnnounceWrites(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&announcing, 1)
+	} else {
+		atomic.StoreInt32(&announcing, 0)
+	}
+}
+
+// announceWrite announces a successful write of buf in format t if
+// announcements are enabled.
+func announceWrite(t Format, buf []byte) {
+	if atomic.LoadInt32(&announcing) == 0 {
+		return
+	}
+	msg := announcement(t, buf)
+	if msg == "" {
+		return
+	}
+	if err := sys.announce(msg); err != nil && debug {
+		fmt.Fprintf(os.Stderr, "announce clipboard write err: %v\n", err)
+	}
+}
+
+// announcement returns the message that announces a write of buf in
+// format t.
+func announcement(t Format, buf []byte) string {
+	switch t {
+	case FmtText:
+		n := utf8.RuneCount(buf)
+		if n == 1 {
+			return "Copied 1 character"
+		}
+		return fmt.Sprintf("Copied %d characters", n)
+	case FmtImage, FmtImageRaw:
+		return "Copied image"
+	case FmtHTML, FmtRTF:
+		return "Copied formatted text"
+	case FmtURL:
+		return "Copied link"
+	case FmtColor:
+		return "Copied color"
+	case FmtVCard:
+		return "Copied contact"
+	case FmtICal:
+		return "Copied calendar"
+	case FmtFiles:
+		n := len(splitFiles(buf))
+		if n == 1 {
+			return "Copied 1 file"
+		}
+		return fmt.Sprintf("Copied %d files", n)
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestAnnouncement(t *testing.T) {
+	tests := []struct {
+		t    clipboard.Format
+		buf  []byte
+		want string
+	}{
+		{clipboard.FmtText, []byte("a"), "Copied 1 character"},
+		{clipboard.FmtText, []byte("你好，world"), "Copied 8 characters"},
+		{clipboard.FmtText, nil, "Copied 0 characters"},
+		{clipboard.FmtImage, []byte{0x89, 'P', 'N', 'G'}, "Copied image"},
+		{clipboard.FmtHTML, []byte("<b>a</b>"), "Copied formatted text"},
+		{clipboard.FmtRTF, []byte(`{\rtf1 a}`), "Copied formatted text"},
+		{clipboard.FmtURL, []byte("https://golang.design"), "Copied link"},
+		{clipboard.FmtColor, []byte{0, 0, 0, 0, 0, 0, 0xff, 0xff}, "Copied color"},
+		{clipboard.FmtVCard, []byte("BEGIN:VCARD"), "Copied contact"},
+		{clipboard.FmtICal, []byte("BEGIN:VCALENDAR"), "Copied calendar"},
+		{clipboard.FmtFiles, []byte("/a"), "Copied 1 file"},
+		{clipboard.FmtFiles, []byte("/a\n/b"), "Copied 2 files"},
+	}
+	for _, tt := range tests {
+		if got := clipboard.Announcement(tt.t, tt.buf); got != tt.want {
+			t.Fatalf("announcement of %q mismatch, got: %q, want: %q", tt.buf, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+var arbitrating int32
+
+// SetArbitration sets whether writes are arbitrated among the processes
+// on the same machine that use this package. The arbitration is
+// disabled by default.
+//
+// Without arbitration, processes that write to the clipboard at the
+// same time may steal the ownership of the clipboard from each other
+// while it is being handed over, for instance, on X11, where the
+// process that writes last owns the clipboard. With arbitration, the
+// cooperating processes take over the ownership one at a time: a write
+// waits for the ongoing write of another process to take the ownership
+// before taking it over, and fails with ErrUnavailable if the other
+// write does not complete in time. Processes that do not enable the
+// arbitration are not affected.
+//
+// On Windows, writes are already arbitrated by the system.
+func SetArbitration(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&arbitrating, 1)
+	} else {
+		atomic.StoreInt32(&arbitrating, 0)
+	}
+}
+
+// arbitrationTimeout is the maximum duration that a write waits for
+// the ongoing write of another process.
+const arbitrationTimeout = 5 * time.Second
+
+// arbitrate acquires the lock that is shared by the cooperating
+// processes if arbitration is enabled, and returns a function that
+// releases the lock once the write took the ownership of the clipboard.
+//
+// The caller must not hold the lock, as arbitrate waits for the writes
+// of other processes.
+func arbitrate() (release func(), err error) {
+	if atomic.LoadInt32(&arbitrating) == 0 || runtime.GOOS == "windows" {
+		return func() {}, nil
+	}
+
+	f, err := acquireArbitration()
+	if err != nil {
+		return nil, err
+	}
+	return func() { releaseArbitration(f) }, nil
+}
+
+// acquireArbitration acquires the lock that is shared by the cooperating
+// processes, and records the process as the current writer. It gives up
+// after arbitrationTimeout.
+func acquireArbitration() (*os.File, error) {
+	f, err := os.OpenFile(arbitrationFile(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("%w: arbitration: %v", ErrUnavailable, err)
+	}
+
+	deadline := time.Now().Add(arbitrationTimeout)
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%w: arbitration: %v", ErrUnavailable, err)
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			pid, _ := io.ReadAll(f)
+			f.Close()
+			return nil, fmt.Errorf("%w: process %s is writing for more than %v",
+				ErrUnavailable, bytes.TrimSpace(pid), arbitrationTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Record the current writer, which helps to find out a stuck one.
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintf(f, "%d\n", os.Getpid())
+	}
+	return f, nil
+}
+
+// releaseArbitration releases the lock that acquireArbitration acquired.
+func releaseArbitration(f *os.File) {
+	unlockFile(f)
+	f.Close()
+}
+
+// arbitrationFile returns the path of the lock file that is shared by
+// the cooperating processes of the same user.
+func arbitrationFile() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, fmt.Sprintf("golang-design-clipboard-%d.lock", os.Getuid()))
+}
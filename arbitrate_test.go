@@ -0,0 +1,130 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.design/x/clipboard"
+)
+
+func TestArbitrate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows arbitrates writes by the system")
+	}
+
+	clipboard.SetArbitration(true)
+	defer clipboard.SetArbitration(false)
+
+	release, err := clipboard.Arbitrate()
+	if err != nil {
+		t.Fatalf("failed to acquire the arbitration lock: %v", err)
+	}
+	acquired := make(chan struct{})
+	go func() {
+		if release, err := clipboard.Arbitrate(); err == nil {
+			release()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("arbitration lock is acquired twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatalf("arbitration lock is not acquired after release")
+	}
+}
+
+// writerEnv is set for the process that TestClipboardArbitration runs to
+// write as another cooperating process.
+const writerEnv = "CLIPBOARD_TEST_ARBITRATION_WRITER"
+
+func TestClipboardArbitration(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows arbitrates writes by the system")
+	}
+	if !clipboard.InitDone() {
+		t.Skip("the clipboard is not initialized")
+	}
+
+	clipboard.SetArbitration(true)
+	defer clipboard.SetArbitration(false)
+
+	writer := func() *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestArbitrationWriter$", "-test.count=1")
+		cmd.Env = append(os.Environ(), writerEnv+"=1")
+		return cmd
+	}
+
+	// Another process writes after the write of this process.
+	if _, err := clipboard.WriteErr(clipboard.FmtText, []byte("process A")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if out, err := writer().CombinedOutput(); err != nil {
+		t.Fatalf("the write of another process failed: %v\n%s", err, out)
+	}
+
+	// The write of another process waits for an ongoing write.
+	release, err := clipboard.Arbitrate()
+	if err != nil {
+		t.Fatalf("failed to acquire the arbitration lock: %v", err)
+	}
+	cmd := writer()
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Start(); err != nil {
+		release()
+		t.Fatalf("failed to start the writer process: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		release()
+		t.Fatalf("the write of another process does not wait for the ongoing write: %v\n%s", err, out.String())
+	case <-time.After(500 * time.Millisecond):
+	}
+	release()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("the write of another process failed: %v\n%s", err, out.String())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the write of another process does not complete")
+	}
+
+	if _, err := clipboard.WriteErr(clipboard.FmtText, []byte("process A")); err != nil {
+		t.Fatalf("failed to write after another process: %v", err)
+	}
+}
+
+// TestArbitrationWriter is the other process of TestClipboardArbitration.
+func TestArbitrationWriter(t *testing.T) {
+	if os.Getenv(writerEnv) == "" {
+		t.Skip("only runs as the writer process of TestClipboardArbitration")
+	}
+	if !clipboard.InitDone() {
+		t.Fatalf("the clipboard is not initialized")
+	}
+
+	clipboard.SetArbitration(true)
+	if _, err := clipboard.WriteErr(clipboard.FmtText, []byte("process B")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+}
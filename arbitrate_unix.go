@@ -0,0 +1,28 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package clipboard
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile tries to acquire an exclusive lock of the given file
+// without blocking, and reports whether the lock is acquired.
+func tryLockFile(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
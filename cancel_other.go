@@ -0,0 +1,15 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build !linux || android || !cgo
+
+package clipboard
+
+import "context"
+
+// cancelOn does nothing as the reads of the platform cannot be canceled
+// once they started.
+func cancelOn(ctx context.Context) (stop func()) { return func() {} }
@@ -0,0 +1,159 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"sync"
+	"time"
+)
+
+// tracker tracks the change channels of writes on platforms whose
+// clipboard takes over the data from the writer, such as macOS and
+// Windows, where an overwrite is only told by the sequence number of the
+// clipboard. A single goroutine checks the sequence number on behalf of
+// all outstanding channels, and exits once all of them are signaled or
+// untracked, rather than a goroutine per write that polls until the
+// clipboard changes.
+//
+// The change detection of Watch is not reused, as it stops checking the
+// clipboard while the watchers are suspended or the session is locked,
+// and the writes must not miss the changes meanwhile.
+type tracker struct {
+	mu      sync.Mutex
+	waiters map[<-chan struct{}]waiter
+	running bool
+	// wake receives notifications that the clipboard may have changed,
+	// which checks the sequence number before the next poll.
+	wake chan struct{}
+	// sequence returns the sequence number of the clipboard.
+	sequence func() (uint64, bool)
+	// interval returns the interval of the polls.
+	interval func() time.Duration
+}
+
+// waiter is a tracked change channel of a write.
+type waiter struct {
+	changed chan struct{}
+	// seq is the sequence number of the clipboard after the write.
+	seq uint64
+}
+
+// writes tracks the writes of the platform, which are never made by the
+// memory backend, hence it uses the sequence number of the platform.
+var writes = newTracker(sequence, changeInterval)
+
+func newTracker(sequence func() (uint64, bool), interval func() time.Duration) *tracker {
+	return &tracker{
+		waiters:  map[<-chan struct{}]waiter{},
+		wake:     make(chan struct{}, 1),
+		sequence: sequence,
+		interval: interval,
+	}
+}
+
+// changedFrom returns a channel that receives a signal once the sequence
+// number of the clipboard differs from seq, that is, the clipboard has
+// been overwritten after a write that resulted in seq.
+func changedFrom(seq uint64) <-chan struct{} {
+	return writes.track(seq)
+}
+
+// Untrack stops tracking the change channel of a write, which then never
+// receives a signal. Programs that write often and lose interest in the
+// overwrites call it, so that the clipboard is no longer checked for the
+// channels that are left behind as long as their data stays on the
+// clipboard. It has no effect on channels that are already signaled, and
+// on Linux, where the overwrites are told by the X server.
+func Untrack(changed <-chan struct{}) {
+	writes.untrack(changed)
+}
+
+// track registers a channel for the change from seq, and starts the
+// tracking loop if it is not running yet.
+func (tr *tracker) track(seq uint64) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.waiters[changed] = waiter{changed: changed, seq: seq}
+	if !tr.running {
+		tr.running = true
+		go tr.run()
+	}
+	return changed
+}
+
+// untrack unregisters the given channel, and stops the tracking loop if
+// no channel is left.
+func (tr *tracker) untrack(changed <-chan struct{}) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if _, ok := tr.waiters[changed]; !ok {
+		return
+	}
+	delete(tr.waiters, changed)
+	if len(tr.waiters) == 0 {
+		// Let the loop find out that nothing is left.
+		tr.notify()
+	}
+}
+
+// notify notifies the tracking loop that the clipboard may have changed.
+func (tr *tracker) notify() {
+	select {
+	case tr.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pending returns the number of channels that are not signaled yet.
+func (tr *tracker) pending() int {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	return len(tr.waiters)
+}
+
+func (tr *tracker) run() {
+	for {
+		t := time.NewTimer(tr.interval())
+		select {
+		case <-t.C:
+		case <-tr.wake:
+			t.Stop()
+		}
+		if !tr.check() {
+			return
+		}
+	}
+}
+
+// check signals the channels whose sequence number differs from the
+// current one. It reports false and stops the loop if no channel is
+// left.
+func (tr *tracker) check() bool {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	// Read the sequence number under the lock, so that it is never older
+	// than the sequence numbers of the registered writes.
+	cur, ok := tr.sequence()
+	for key, w := range tr.waiters {
+		if ok && w.seq != cur {
+			w.changed <- struct{}{}
+			close(w.changed)
+			delete(tr.waiters, key)
+		}
+	}
+	if len(tr.waiters) == 0 {
+		tr.running = false
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.design/x/clipboard"
+)
+
+// waitStopped waits for the tracking loop of tr to stop.
+func waitStopped(t *testing.T, tr *clipboard.Tracker) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for tr.Running() {
+		if time.Now().After(deadline) {
+			t.Fatalf("the tracking loop is still running")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTrackedWrites(t *testing.T) {
+	var seq uint64 = 1
+	tr := clipboard.NewTracker(func() (uint64, bool) {
+		return atomic.LoadUint64(&seq), true
+	}, time.Hour)
+
+	chs := make([]<-chan struct{}, 100)
+	for i := range chs {
+		chs[i] = tr.Track(1)
+	}
+	if !tr.Running() {
+		t.Fatalf("the tracking loop is not started")
+	}
+	atomic.StoreUint64(&seq, 2)
+	tr.Notify()
+
+	for i, ch := range chs {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("the change channel %d is not signaled", i)
+		}
+	}
+	if n := tr.Pending(); n != 0 {
+		t.Fatalf("expect no pending channels, got: %d", n)
+	}
+	waitStopped(t, tr)
+}
+
+func TestUntrackedWrites(t *testing.T) {
+	var polls int64
+	tr := clipboard.NewTracker(func() (uint64, bool) {
+		atomic.AddInt64(&polls, 1)
+		return 1, true
+	}, 10*time.Millisecond)
+
+	a := tr.Track(1)
+	b := tr.Track(1)
+	tr.Untrack(a)
+	if n := tr.Pending(); n != 1 {
+		t.Fatalf("expect 1 pending channel, got: %d", n)
+	}
+	if !tr.Running() {
+		t.Fatalf("the tracking loop stopped with a pending channel")
+	}
+
+	// The loop stops once the last channel is untracked, although the
+	// clipboard has not changed.
+	tr.Untrack(b)
+	tr.Untrack(b)
+	if n := tr.Pending(); n != 0 {
+		t.Fatalf("expect no pending channels, got: %d", n)
+	}
+	waitStopped(t, tr)
+
+	n := atomic.LoadInt64(&polls)
+	time.Sleep(50 * time.Millisecond)
+	if m := atomic.LoadInt64(&polls); m != n {
+		t.Fatalf("the clipboard is polled without tracked channels, %d polls", m-n)
+	}
+	select {
+	case <-a:
+		t.Fatalf("an untracked channel is signaled")
+	case <-b:
+		t.Fatalf("an untracked channel is signaled")
+	default:
+	}
+}
@@ -43,9 +43,11 @@ by others and the previously written data is lost. For instance:
 		println(`"text data" is no longer available from clipboard.`)
 	}
 
-You can ignore the returning channel if you don't need this type of
-notification. Furthermore, when you need more than just knowing whether
-clipboard data is changed, use the watcher API:
+If the write fails, the returned channel is closed immediately, and
+WriteErr reports the reason of the failure. You can ignore the returning
+channel if you don't need this type of notification. Furthermore, when
+you need more than just knowing whether clipboard data is changed, use
+the watcher API:
 
 	ch := clipboard.Watch(context.TODO(), clipboard.FmtText)
 	for data := range ch {
@@ -61,13 +63,27 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 )
 
 var (
 	// activate only for running tests.
-	debug          = false
-	errUnavailable = errors.New("clipboard unavailable")
-	errUnsupported = errors.New("unsupported format")
+	debug = false
+	// ErrUnavailable indicates the clipboard or the requested data
+	// is not available.
+	ErrUnavailable = errors.New("clipboard unavailable")
+	// ErrUnsupported indicates the requested format or operation is
+	// not supported on the current platform.
+	ErrUnsupported = errors.New("unsupported format")
+	// ErrCorrupt indicates the clipboard data is corrupted, for
+	// instance, text that misses its terminator.
+	ErrCorrupt = errors.New("clipboard data corrupted")
+	// ErrTimeout indicates the owner of the clipboard did not deliver
+	// the data in time, see WithReadTimeout.
+	ErrTimeout = errors.New("clipboard read timeout")
+	// ErrNotInitialized indicates the clipboard is used before Init
+	// succeeded.
+	ErrNotInitialized = errors.New("clipboard not initialized")
 )
 
 // Format represents the format of clipboard data.
@@ -77,10 +93,81 @@ type Format int
 const (
 	// FmtText indicates plain text clipboard format
 	FmtText Format = iota
-	// FmtImage indicates image/png clipboard format
+	// FmtImage indicates image/png clipboard format. Writes also accept
+	// JPEG, GIF, WebP, BMP and TIFF encoded images, which are transcoded
+	// to PNG. Images with 16 bits per channel are kept losslessly, except
+	// that applications on Windows that only understand DIBs receive 8
+	// bits per channel. On Windows, the alpha channel is kept in the
+	// CF_DIBV5, and the CF_DIB is composited onto the image matte, see
+	// SetImageMatte, or white.
 	FmtImage
+	// FmtHTML indicates text/html clipboard format, the data is a
+	// UTF-8 encoded HTML fragment without any platform envelope.
+	FmtHTML
+	// FmtRTF indicates text/rtf clipboard format
+	FmtRTF
+	// FmtFiles indicates a file list clipboard format, as copied from
+	// file managers. The data is newline separated absolute file paths,
+	// see ReadFiles and WriteFiles.
+	FmtFiles
+	// FmtURL indicates a link clipboard format, as copied from the
+	// address bar of browsers. The data is a UTF-8 encoded URL. Writes
+	// also offer the URL as plain text, and reads fall back to plain
+	// text that is an absolute URL.
+	FmtURL
+	// FmtImageRaw indicates an image clipboard format in the native
+	// encoding of the platform, which skips the transcoding from and to
+	// PNG. The data is a packed DIB, which is a BITMAPINFOHEADER or a
+	// BITMAPV5HEADER followed by the pixels, or PNG on Windows, TIFF or
+	// PNG on macOS, and PNG or any other image type that the owner offers
+	// on Linux. Writes accept the same encodings, as well as BMP files on
+	// Windows and Linux.
+	FmtImageRaw
+	// FmtColor indicates a color clipboard format, as copied from the
+	// color pickers of design tools. The data is 8 bytes of the
+	// non-premultiplied red, green, blue and alpha components, each a
+	// big-endian uint16, see ReadColor and WriteColor.
+	FmtColor
+	// FmtVCard indicates a contact clipboard format, as copied from
+	// contact managers. The data is a UTF-8 encoded vCard, which begins
+	// with BEGIN:VCARD.
+	FmtVCard
+	// FmtICal indicates a calendar clipboard format, as copied from
+	// calendar applications. The data is UTF-8 encoded iCalendar, which
+	// begins with BEGIN:VCALENDAR.
+	FmtICal
 )
 
+// allFormats are all supported formats.
+var allFormats = []Format{FmtText, FmtImage, FmtHTML, FmtRTF, FmtFiles, FmtURL, FmtImageRaw, FmtColor, FmtVCard, FmtICal}
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case FmtText:
+		return "text"
+	case FmtImage:
+		return "image"
+	case FmtHTML:
+		return "html"
+	case FmtRTF:
+		return "rtf"
+	case FmtFiles:
+		return "files"
+	case FmtURL:
+		return "url"
+	case FmtImageRaw:
+		return "image-raw"
+	case FmtColor:
+		return "color"
+	case FmtVCard:
+		return "vcard"
+	case FmtICal:
+		return "ical"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
 var (
 	// Due to the limitation on operating systems (such as darwin),
 	// concurrent read can even cause panic, use a global lock to
@@ -88,6 +175,8 @@ var (
 	lock = sync.Mutex{}
 	initOnce sync.Once
 	initError error
+	// initialized is 1 once Init succeeded.
+	initialized int32
 )
 
 // Init initializes the clipboard package. It returns an error
@@ -100,22 +189,60 @@ var (
 // 		panic(err)
 // 	}
 //
-// If Init returns an error, any subsequent Read/Write/Watch call
-// may result in an unrecoverable panic.
-func Init() error {
+// If Init is not called or returns an error, subsequent calls fail
+// with ErrNotInitialized, Read and Has report no data, and Watch returns
+// a closed channel. See InitDone.
+//
+// Init accepts options to configure the clipboard, for instance,
+//
+// 	err := clipboard.Init(clipboard.WithDisplay(":1"))
+//
+// The options only take effect in the first call of Init.
+func Init(opts ...Option) error {
 	initOnce.Do(func() {
-		initError = initialize()
+		c := config{pollInterval: defaultPollInterval, readTimeout: -1}
+		for _, opt := range opts {
+			opt(&c)
+		}
+		if c.err != nil {
+			initError = c.err
+			return
+		}
+		mon.setInterval(c.pollInterval)
+		ocr = c.ocr
+		ignoreOrientation = c.ignoreOrientation
+		useSideChannel = c.sideChannel
+		readLineEnding, writeLineEnding = c.readEnding, c.writeEnding
+		if c.backend == BackendMemory {
+			sys = (&memory{}).system()
+		} else {
+			initError = initialize(c)
+		}
+		if initError == nil {
+			atomic.StoreInt32(&initialized, 1)
+		}
 	})
 	return initError
 }
 
+// InitDone reports whether Init has been called and succeeded, hence
+// the clipboard is ready to use.
+func InitDone() bool {
+	return atomic.LoadInt32(&initialized) == 1
+}
+
+// ready returns ErrNotInitialized if the clipboard is not initialized.
+func ready() error {
+	if !InitDone() {
+		return ErrNotInitialized
+	}
+	return nil
+}
+
 // Read returns a chunk of bytes of the clipboard data if it presents
 // in the desired format t presents. Otherwise, it returns nil.
 func Read(t Format) []byte {
-	lock.Lock()
-	defer lock.Unlock()
-
-	buf, err := read(t)
+	buf, err := ReadErr(t)
 	if err != nil {
 		if debug {
 			fmt.Fprintf(os.Stderr, "read clipboard err: %v\n", err)
@@ -125,30 +252,409 @@ func Read(t Format) []byte {
 	return buf
 }
 
+// ReadErr is like Read but returns the error that fails the read,
+// rather than nil data.
+func ReadErr(t Format) ([]byte, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return readChecked(t)
+}
+
+// ReadCtx is like ReadErr but gives up the read once the given context
+// is done, and returns the error of the context. On Linux, canceling the
+// context aborts the wait for a hung selection owner immediately. On
+// other platforms, reads cannot be canceled once they started.
+func ReadCtx(ctx context.Context, t Format) ([]byte, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	stop := cancelOn(ctx)
+	buf, err := readChecked(t)
+	stop()
+	if cerr := ctx.Err(); cerr != nil {
+		return nil, cerr
+	}
+	return buf, err
+}
+
+// ReadAny reads the clipboard data in the first of the preferred formats
+// that the clipboard holds, and returns the data as well as its format.
+// All supported formats are preferred in the order of their definitions
+// if no format is given. It returns ErrUnavailable if the clipboard
+// holds no data in any of the preferred formats.
+//
+// The formats that the clipboard offers are inspected once for all
+// preferred formats, and only the data of the best match is transferred,
+// which saves round trips to the selection owner on Linux compared to
+// calling Read for each format.
+func ReadAny(preferred ...Format) ([]byte, Format, error) {
+	if err := ready(); err != nil {
+		return nil, 0, err
+	}
+	if len(preferred) == 0 {
+		preferred = allFormats
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Probe the availability first, so that the data of a format is
+	// only transferred if it is going to be returned.
+	offered := sys.prober()
+	for _, t := range preferred {
+		if !offered(t) {
+			continue
+		}
+		buf, err := readChecked(t)
+		if err != nil {
+			return nil, t, err
+		}
+		if len(buf) != 0 {
+			return buf, t, nil
+		}
+	}
+	return nil, 0, ErrUnavailable
+}
+
+// readChecked reads the clipboard data in format t, and validates the
+// data in strict mode. The caller must hold the lock.
+func readChecked(t Format) ([]byte, error) {
+	if err := ready(); err != nil {
+		return nil, err
+	}
+	buf, err := readCached(t)
+	if err != nil {
+		return nil, err
+	}
+	return checked(t, buf)
+}
+
+// checked converts the line endings of the read text, and validates the
+// read data in strict mode.
+func checked(t Format, buf []byte) ([]byte, error) {
+	if t == FmtText {
+		buf = convertLineEndings(buf, readLineEnding)
+	}
+	if atomic.LoadInt32(&strictRead) == 1 {
+		if err := validate(t, buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// Has reports whether the clipboard holds data in the desired format t,
+// including data that can be converted to the format using the registered
+// converters. Unlike Read, Has does not transfer the clipboard data,
+// hence it is cheap to check, for instance, whether an image is copied
+// before reading megabytes of pixels.
+func Has(t Format) bool {
+	if !InitDone() {
+		return false
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	return sys.has(t)
+}
+
 // Write writes a given buffer to the clipboard in a specified format.
 // Write returned a receive-only channel can receive an empty struct
 // as a signal, which indicates the clipboard has been overwritten from
 // this write.
 // If format t indicates an image, then the given buf assumes
 // the image data is PNG encoded.
+//
+// If the write fails, the returned channel is already closed, so that
+// receiving from it does not block forever. Use WriteErr to know why
+// the write failed, or SetStrictWrite to receive a nil channel instead.
 func Write(t Format, buf []byte) <-chan struct{} {
-	lock.Lock()
-	defer lock.Unlock()
-
-	changed, err := write(t, buf)
+	changed, err := WriteErr(t, buf)
 	if err != nil {
 		if debug {
 			fmt.Fprintf(os.Stderr, "write to clipboard err: %v\n", err)
 		}
-		return nil
+		return failedWrite()
 	}
 	return changed
 }
 
+// WriteErr is like Write but returns an error if the write fails.
+func WriteErr(t Format, buf []byte) (<-chan struct{}, error) {
+	w, err := writeAll(t, buf, modeNormal)
+	return w.changed, err
+}
+
+// WriteSeq is like WriteErr but also returns the sequence number of the
+// clipboard that results from the write. Comparing it with Sequence is
+// a cheap way to check whether the written data is still on the
+// clipboard, rather than reading and comparing the data. The sequence
+// number is zero on platforms without a sequence number, see Sequence.
+func WriteSeq(t Format, buf []byte) (seq uint64, changed <-chan struct{}, err error) {
+	w, err := writeAll(t, buf, modeNormal)
+	return w.seq, w.changed, err
+}
+
+// WriteOwned is like WriteErr but also returns a channel that receives a
+// signal once the process loses the ownership of the clipboard that it
+// took with the write, that is, another application or a later write
+// has taken over the clipboard. Unlike the changed channel, it does not
+// fire on changes of the clipboard that keep the ownership, for
+// instance, the clipboard owner rendering another format on Windows, or
+// the clipboard being cleared after WriteOnce has served a paste.
+//
+// The ownership is told by the SelectionClear event of the X selection
+// on Linux, the WM_DESTROYCLIPBOARD message to the clipboard owner on
+// Windows, and a change of the change count of the pasteboard on macOS
+// and iOS. The lost channel is nil on Android, which does not tell.
+func WriteOwned(t Format, buf []byte) (changed, lost <-chan struct{}, err error) {
+	w, err := writeAll(t, buf, modeNormal)
+	return w.changed, w.lost, err
+}
+
+// Sequence returns the sequence number of the clipboard, which changes
+// whenever the clipboard data changes, such as the change count of the
+// pasteboard on macOS and iOS, the clipboard sequence number on Windows,
+// or the number of changes of the selection owner that XFixes reports on
+// Linux. It returns false on Android, which does not offer a sequence
+// number, and on X servers without XFixes.
+func Sequence() (uint64, bool) {
+	if !InitDone() {
+		return 0, false
+	}
+	return sys.sequence()
+}
+
+// WriteRich writes the given HTML fragment along with its plain text in
+// a single write, so that applications that understand formatting, such
+// as word processors, paste the HTML, while others, such as terminals,
+// paste the plain text. Writing them using two calls of Write does not
+// work, as the second write overwrites the first one.
+//
+// Like WriteErr, the returned channel receives a signal if the clipboard
+// has been overwritten from this write.
+func WriteRich(html, plain []byte) (<-chan struct{}, error) {
+	plain = convertLineEndings(plain, writeLineEnding)
+	w, err := writeAll(FmtHTML, html, modeNormal, representation{mime: mimeText, data: plain})
+	return w.changed, err
+}
+
+// Alternative is the data of a write in another format, see
+// WriteFallback.
+type Alternative struct {
+	Format Format
+	Data   []byte
+}
+
+// WriteFallback is like WriteErr but falls back to the given alternatives
+// in order if the data cannot be written in format t, for instance, HTML
+// on Android, which only supports text, or an image that the platform
+// rejects. It returns the format of the data that has been written, so
+// that callers get the best effort of constrained platforms:
+//
+//	t, changed, err := clipboard.WriteFallback(clipboard.FmtImage, png,
+//		clipboard.Alternative{Format: clipboard.FmtFiles, Data: []byte(path)})
+//
+// If none of the data can be written, the error of the last write is
+// returned.
+func WriteFallback(t Format, buf []byte, alts ...Alternative) (Format, <-chan struct{}, error) {
+	w, err := writeAll(t, buf, modeNormal)
+	for _, alt := range alts {
+		if err == nil || errors.Is(err, ErrNotInitialized) {
+			break
+		}
+		if debug {
+			fmt.Fprintf(os.Stderr, "write %v failed, falling back to %v: %v\n", t, alt.Format, err)
+		}
+		t = alt.Format
+		w, err = writeAll(t, alt.Data, modeNormal)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return t, w.changed, nil
+}
+
+// prepared returns the data of format t as it is written, which transcodes
+// images to PNG, and converts the line endings of text.
+func prepared(t Format, buf []byte) ([]byte, error) {
+	if t == FmtImage {
+		// Images in other encodings are transcoded to PNG, which is
+		// the data of FmtImage.
+		if m := sniffImage(buf); m != "" && m != mimePNG {
+			return Convert(m, mimePNG, buf)
+		}
+	}
+	if t == FmtText {
+		buf = convertLineEndings(buf, writeLineEnding)
+	}
+	return buf, nil
+}
+
+// writeMode is the mode of a write.
+type writeMode int
+
+const (
+	modeNormal    writeMode = iota
+	modeSensitive           // marks the data as sensitive in its origin
+	modeOnce                // like modeSensitive, and serves a single paste
+)
+
+// written is the result of a write.
+type written struct {
+	// changed receives a signal if the clipboard has been overwritten
+	// from the write.
+	changed <-chan struct{}
+	// lost receives a signal once the process loses the ownership of
+	// the clipboard, see WriteOwned.
+	lost <-chan struct{}
+	// seq is the sequence number of the clipboard after the write.
+	seq uint64
+}
+
+// writeAll writes the given buffer to the clipboard along with its
+// additional representations, such as the origin metadata of the write
+// and the given more representations.
+func writeAll(t Format, buf []byte, mode writeMode, more ...representation) (written, error) {
+	if err := ready(); err != nil {
+		return written{}, err
+	}
+	buf, err := prepared(t, buf)
+	if err != nil {
+		return written{}, err
+	}
+	if r, ok := matted(t, buf); ok {
+		more = append(more[:len(more):len(more)], r)
+	}
+	var ref sideRef
+	if useSideChannel && mode == modeNormal {
+		ref = offerSide(t, buf, more)
+	}
+	extra := append([]representation{origin(mode != modeNormal, ref)}, more...)
+
+	// Wait for the writes of other processes before taking the lock.
+	release, err := arbitrate()
+	if err != nil {
+		side.withdraw(ref.Token)
+		return written{}, err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	put := sys.write
+	if mode == modeOnce {
+		put = sys.writeOnce
+	}
+	changed, err := put(t, buf, extra)
+	release()
+	if err != nil {
+		side.withdraw(ref.Token)
+		return written{}, err
+	}
+	if ref.Token != "" && changed != nil {
+		// Release the data once the clipboard is overwritten.
+		go func() {
+			<-changed
+			side.withdraw(ref.Token)
+		}()
+	}
+	// The sequence number is taken before announcing the write, which
+	// may take a while.
+	seq, ok := sys.sequence()
+	recordWrite(seq, ok, t, buf)
+	lost := sys.lost()
+	announceWrite(t, buf)
+	return written{changed: changed, lost: lost, seq: seq}, nil
+}
+
+var strictWrite int32
+
+// SetStrictWrite sets the behavior of Write regarding failed writes.
+// By default, Write is lenient and returns a closed channel if the
+// write fails. In strict mode, Write returns a nil channel as in the
+// previous versions of this package, and the caller is responsible for
+// checking the returned channel before receiving from it.
+func SetStrictWrite(strict bool) {
+	if strict {
+		atomic.StoreInt32(&strictWrite, 1)
+	} else {
+		atomic.StoreInt32(&strictWrite, 0)
+	}
+}
+
+// failedWrite returns the channel that Write returns for a failed write.
+func failedWrite() <-chan struct{} {
+	if atomic.LoadInt32(&strictWrite) == 1 {
+		return nil
+	}
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
 // Watch returns a receive-only channel that received the clipboard data
 // whenever any change of clipboard data in the desired format happens.
 //
 // The returned channel will be closed if the given context is canceled.
+// All watchers share a single change detection loop, hence it is cheap
+// to have many watchers at the same time. Use WatchEvents to also
+// observe the clipboard becomes cleared.
 func Watch(ctx context.Context, t Format) <-chan []byte {
-	return watch(ctx, t)
+	return watch(ctx, t, watchConfig{})
+}
+
+// watch is Watch with the configuration of the watcher.
+func watch(ctx context.Context, t Format, c watchConfig) <-chan []byte {
+	recv := make(chan []byte, 1)
+	if !InitDone() {
+		close(recv)
+		return recv
+	}
+	s := mon.subscribe(t, false, c)
+	go func() {
+		defer mon.unsubscribe(s)
+		defer close(recv)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-s.mail:
+				select {
+				case recv <- e.Data:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return recv
+}
+
+// RawCall calls fn with the low-level handle of the platform clipboard,
+// which serves as an escape hatch for platform-specific operations that
+// are not wrapped by this package. The call of fn is exclusive to other
+// clipboard operations of this package, and the handle must not be used
+// after fn returns. Depending on the platform, the handle is:
+//
+//   - Linux: the Display* of an Xlib connection to the X server, which
+//     is the locked display of the host if WithExistingDisplay is used
+//   - macOS: the NSPasteboard* of the general pasteboard
+//   - iOS: the UIPasteboard* of the general pasteboard
+//   - Windows: zero, fn is called on a locked OS thread that has opened
+//     the clipboard using OpenClipboard
+//
+// RawCall returns an error if the platform does not offer a handle,
+// otherwise it returns the error returned by fn.
+func RawCall(fn func(handle uintptr) error) error {
+	if err := ready(); err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	return sys.rawCall(fn)
 }
@@ -66,6 +66,18 @@ char *clipboard_read_string(uintptr_t java_vm, uintptr_t jni_env, uintptr_t ctx)
 	return copy;
 }
 
+int clipboard_has_text(uintptr_t java_vm, uintptr_t jni_env, uintptr_t ctx) {
+	JNIEnv *env = (JNIEnv*)jni_env;
+	jobject mgr = get_clipboard(jni_env, ctx);
+	if (mgr == NULL) {
+		return 0;
+	}
+
+	jclass mgrClass = (*env)->GetObjectClass(env, mgr);
+	jmethodID hasText = find_method(env, mgrClass, "hasText", "()Z");
+	return (*env)->CallBooleanMethod(env, mgr, hasText) ? 1 : 0;
+}
+
 void clipboard_write_string(uintptr_t java_vm, uintptr_t jni_env, uintptr_t ctx, char *str) {
 	JNIEnv *env = (JNIEnv*)jni_env;
 	jobject mgr = get_clipboard(jni_env, ctx);
@@ -13,20 +13,24 @@ package clipboard
 
 #include <stdlib.h>
 char *clipboard_read_string(uintptr_t java_vm, uintptr_t jni_env, uintptr_t ctx);
+int clipboard_has_text(uintptr_t java_vm, uintptr_t jni_env, uintptr_t ctx);
 void clipboard_write_string(uintptr_t java_vm, uintptr_t jni_env, uintptr_t ctx, char *str);
 
 */
 import "C"
 import (
-	"bytes"
-	"context"
-	"time"
+	"fmt"
 	"unsafe"
 
 	"golang.org/x/mobile/app"
 )
 
-func initialize() error { return nil }
+func initialize(c config) error {
+	if c.backend != BackendAuto {
+		return fmt.Errorf("%w: %v backend", ErrUnsupported, c.backend)
+	}
+	return nil
+}
 
 func read(t Format) (buf []byte, err error) {
 	switch t {
@@ -46,15 +50,40 @@ func read(t Format) (buf []byte, err error) {
 		}
 		return []byte(s), nil
 	case FmtImage:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
 	default:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
+	}
+}
+
+// readData returns an error as reading data of arbitrary MIME types
+// is not supported yet.
+func readData(mime string) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+func has(t Format) bool {
+	if t != FmtText {
+		return false
 	}
+	ok := false
+	app.RunOnJVM(func(vm, env, ctx uintptr) error {
+		ok = C.clipboard_has_text(C.uintptr_t(vm), C.uintptr_t(env), C.uintptr_t(ctx)) != 0
+		return nil
+	})
+	return ok
 }
 
+// prober returns a function that reports whether the clipboard holds
+// data in a format.
+func prober() func(Format) bool { return has }
+
+func imageInfo() (int, int, string, error) { return 0, 0, "", ErrUnsupported }
+
 // write writes the given data to clipboard and
-// returns true if success or false if failed.
-func write(t Format, buf []byte) (<-chan struct{}, error) {
+// returns true if success or false if failed. Additional
+// representations are not supported yet, hence they are ignored.
+func write(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
 	done := make(chan struct{}, 1)
 	switch t {
 	case FmtText:
@@ -70,33 +99,72 @@ func write(t Format, buf []byte) (<-chan struct{}, error) {
 		}
 		return done, nil
 	case FmtImage:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
 	default:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
 	}
 }
 
-func watch(ctx context.Context, t Format) <-chan []byte {
-	recv := make(chan []byte, 1)
-	ti := time.NewTicker(time.Second)
-	last := Read(t)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				close(recv)
-				return
-			case <-ti.C:
-				b := Read(t)
-				if b == nil {
-					continue
-				}
-				if bytes.Compare(last, b) != 0 {
-					recv <- b
-					last = b
-				}
-			}
-		}
-	}()
-	return recv
+// writeOnce returns an error as the ClipboardManager does not tell when
+// the data is pasted.
+func writeOnce(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnsupported
+}
+
+// writeData returns an error as writing data of arbitrary MIME types
+// is not supported yet.
+func writeData(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnsupported
+}
+
+// sequence returns false as there is no change count of the clipboard.
+func sequence() (uint64, bool) { return 0, false }
+
+func rawCall(fn func(uintptr) error) error {
+	return ErrUnsupported
+}
+
+func announce(msg string) error {
+	return ErrUnsupported
+}
+
+// virtualFiles returns ErrUnavailable, as Android does not offer files
+// that only exist as clipboard data.
+func virtualFiles(dir string) ([]string, error) {
+	return nil, ErrUnavailable
+}
+
+// lost returns nil, as the ClipboardManager does not tell the ownership
+// of the clipboard.
+func lost() <-chan struct{} {
+	return nil
+}
+
+// info returns the behavior of the ClipboardManager, which has no change
+// count, hence Watch compares the data. The data of a write is passed
+// through a Binder transaction, whose buffer is limited to 1MB.
+func info() Info {
+	return Info{
+		Protocol:   "Android ClipboardManager",
+		MaxPayload: 1 << 20,
+	}
+}
+
+// locked returns false, as the ClipboardManager is accessible to the
+// application whenever it runs.
+func locked() bool { return false }
+
+// newSystem returns ErrUnsupported, as there is a single clipboard on
+// Android.
+func newSystem(c config) (system, error) {
+	return system{}, fmt.Errorf("%w: multiple clipboards", ErrUnsupported)
+}
+
+// readSelection reads the clipboard for SelectionClipboard, as there are
+// no other selections.
+func readSelection(s Selection, t Format) ([]byte, error) {
+	if s != SelectionClipboard {
+		return nil, ErrUnsupported
+	}
+	return read(t)
 }
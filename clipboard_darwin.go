@@ -10,24 +10,65 @@ package clipboard
 
 /*
 #cgo CFLAGS: -x objective-c
-#cgo LDFLAGS: -framework Foundation -framework Cocoa
+#cgo LDFLAGS: -framework Foundation -framework Cocoa -framework CoreGraphics
 #import <Foundation/Foundation.h>
 #import <Cocoa/Cocoa.h>
 
 unsigned int clipboard_read_string(void **out);
 unsigned int clipboard_read_image(void **out);
+unsigned int clipboard_read_mime(const char *mime, void **out);
+unsigned int clipboard_read_files(void **out);
+unsigned int clipboard_read_url(void **out);
+int clipboard_has_promises();
+unsigned int clipboard_receive_promises(const char *dir, int64_t timeout, void **out);
+int clipboard_read_color(double *rgba);
+int clipboard_has(int typ, const char *mime);
 int clipboard_write_string(const void *bytes, NSInteger n);
 int clipboard_write_image(const void *bytes, NSInteger n);
+int clipboard_write_data(const char *mime, const void *bytes, NSInteger n);
+int clipboard_write_files(const void *bytes, NSInteger n);
+int clipboard_write_url(const void *bytes, NSInteger n);
+int clipboard_write_color(double r, double g, double b, double a);
+int clipboard_add_data(const char *mime, const void *bytes, NSInteger n);
+int clipboard_write_osascript(int kind, const void *bytes, NSInteger n);
 NSInteger clipboard_change_count();
+int clipboard_locked();
+void *clipboard_pasteboard();
+void clipboard_announce(const char *msg);
+void clipboard_watch(int64_t interval);
 */
 import "C"
 import (
-	"context"
+	"fmt"
+	"image/color"
 	"time"
 	"unsafe"
 )
 
-func initialize() error { return nil }
+func initialize(c config) error {
+	if c.backend != BackendAuto {
+		return fmt.Errorf("%w: %v backend", ErrUnsupported, c.backend)
+	}
+	d := c.pollInterval
+	if d < minWatchInterval {
+		d = minWatchInterval
+	}
+	C.clipboard_watch(C.int64_t(d))
+	return nil
+}
+
+// minWatchInterval is the shortest interval that the change count of the
+// pasteboard is checked at.
+const minWatchInterval = 50 * time.Millisecond
+
+// pasteboardChanged is called by the timer of the pasteboard whenever its
+// change count changes, which notifies the change channels of writes and
+// the change detection of Watch.
+//
+//export pasteboardChanged
+func pasteboardChanged() {
+	NotifyUpdate()
+}
 
 func read(t Format) (buf []byte, err error) {
 	var (
@@ -39,9 +80,40 @@ func read(t Format) (buf []byte, err error) {
 		n = C.clipboard_read_string(&data)
 	case FmtImage:
 		n = C.clipboard_read_image(&data)
+	case FmtImageRaw:
+		n = C.clipboard_read_image(&data)
+		if data == nil {
+			return readData(mimeTIFF)
+		}
+	case FmtFiles:
+		n = C.clipboard_read_files(&data)
+	case FmtURL:
+		n = C.clipboard_read_url(&data)
+		if data == nil {
+			if b, err := read(FmtText); err == nil {
+				if u := textURL(b); u != nil {
+					return u, nil
+				}
+			}
+		}
+	case FmtColor:
+		var rgba [4]C.double
+		if C.clipboard_read_color(&rgba[0]) != 0 {
+			return nil, ErrUnavailable
+		}
+		return encodeColor(color.NRGBA64{
+			R: component(rgba[0]), G: component(rgba[1]),
+			B: component(rgba[2]), A: component(rgba[3]),
+		}), nil
+	case FmtHTML, FmtRTF, FmtVCard, FmtICal:
+		return readData(pasteboardType(t))
+	default:
+		return nil, ErrUnsupported
 	}
 	if data == nil {
-		return nil, errUnavailable
+		// The pasteboard may only offer other representations,
+		// such as TIFF images, try converting them.
+		return negotiate(mimeOf(t), readData)
 	}
 	defer C.free(unsafe.Pointer(data))
 	if n == 0 {
@@ -50,9 +122,93 @@ func read(t Format) (buf []byte, err error) {
 	return C.GoBytes(data, C.int(n)), nil
 }
 
+// readData reads the pasteboard data of a given MIME type.
+func readData(mime string) ([]byte, error) {
+	cs := C.CString(mime)
+	defer C.free(unsafe.Pointer(cs))
+
+	var data unsafe.Pointer
+	n := C.clipboard_read_mime(cs, &data)
+	if data == nil {
+		return nil, ErrUnavailable
+	}
+	defer C.free(data)
+	if n == 0 {
+		return nil, nil
+	}
+	return C.GoBytes(data, C.int(n)), nil
+}
+
+func has(t Format) bool {
+	if t == FmtImageRaw {
+		t = FmtImage
+	}
+	switch t {
+	case FmtText:
+		if C.clipboard_has(0, nil) != 0 {
+			return true
+		}
+	case FmtImage:
+		if C.clipboard_has(1, nil) != 0 {
+			return true
+		}
+	case FmtFiles:
+		if C.clipboard_has(3, nil) != 0 {
+			return true
+		}
+	case FmtURL:
+		if C.clipboard_has(4, nil) != 0 {
+			return true
+		}
+	case FmtColor:
+		return C.clipboard_has(5, nil) != 0
+	case FmtHTML, FmtRTF, FmtVCard, FmtICal:
+		if hasData(pasteboardType(t)) {
+			return true
+		}
+	default:
+		return false
+	}
+	for _, from := range convertible(mimeOf(t)) {
+		if hasData(from) {
+			return true
+		}
+	}
+	return false
+}
+
+// pasteboardType returns the pasteboard type of a format whose data is
+// stored as is, either a MIME type or a UTI. Contacts and Calendar use
+// the UTIs of vCard and iCalendar files rather than their MIME types.
+func pasteboardType(t Format) string {
+	switch t {
+	case FmtVCard:
+		return "public.vcard"
+	case FmtICal:
+		return "com.apple.ical.ics"
+	}
+	return mimeOf(t)
+}
+
+// prober returns a function that reports whether the clipboard holds
+// data in a format. The availability is checked locally, hence probing
+// costs no more than calling has for each format.
+func prober() func(Format) bool { return has }
+
+// hasData reports whether the pasteboard offers data of the pasteboard
+// type that is identified by the given MIME type.
+func hasData(mime string) bool {
+	cs := C.CString(mime)
+	defer C.free(unsafe.Pointer(cs))
+
+	return C.clipboard_has(2, cs) != 0
+}
+
+func imageInfo() (int, int, string, error) { return readImageInfo(readData) }
+
 // write writes the given data to clipboard and
 // returns true if success or false if failed.
-func write(t Format, buf []byte) (<-chan struct{}, error) {
+func write(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
 	var ok C.int
 	switch t {
 	case FmtText:
@@ -69,54 +225,209 @@ func write(t Format, buf []byte) (<-chan struct{}, error) {
 			ok = C.clipboard_write_image(unsafe.Pointer(&buf[0]),
 				C.NSInteger(len(buf)))
 		}
+	case FmtImageRaw:
+		switch sniffImage(buf) {
+		case mimePNG:
+			ok = C.clipboard_write_image(unsafe.Pointer(&buf[0]),
+				C.NSInteger(len(buf)))
+		case mimeTIFF:
+			cs := C.CString(mimeTIFF)
+			defer C.free(unsafe.Pointer(cs))
+			ok = C.clipboard_write_data(cs, unsafe.Pointer(&buf[0]),
+				C.NSInteger(len(buf)))
+		default:
+			return nil, ErrUnsupported
+		}
+	case FmtFiles:
+		if len(buf) == 0 {
+			ok = C.clipboard_write_files(unsafe.Pointer(nil), 0)
+		} else {
+			ok = C.clipboard_write_files(unsafe.Pointer(&buf[0]),
+				C.NSInteger(len(buf)))
+		}
+	case FmtURL:
+		if len(buf) == 0 {
+			ok = C.clipboard_write_url(unsafe.Pointer(nil), 0)
+		} else {
+			ok = C.clipboard_write_url(unsafe.Pointer(&buf[0]),
+				C.NSInteger(len(buf)))
+		}
+	case FmtColor:
+		c, err := decodeColor(buf)
+		if err != nil {
+			return nil, err
+		}
+		ok = C.clipboard_write_color(C.double(c.R)/0xffff, C.double(c.G)/0xffff,
+			C.double(c.B)/0xffff, C.double(c.A)/0xffff)
+	case FmtHTML, FmtRTF, FmtVCard, FmtICal:
+		cs := C.CString(pasteboardType(t))
+		defer C.free(unsafe.Pointer(cs))
+		if len(buf) == 0 {
+			ok = C.clipboard_write_data(cs, unsafe.Pointer(nil), 0)
+		} else {
+			ok = C.clipboard_write_data(cs, unsafe.Pointer(&buf[0]),
+				C.NSInteger(len(buf)))
+		}
 	default:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
 	}
 	if ok != 0 {
-		return nil, errUnavailable
-	}
-
-	// use unbuffered data to prevent goroutine leak
-	changed := make(chan struct{}, 1)
-	cnt := C.long(C.clipboard_change_count())
-	go func() {
-		for {
-			// not sure if we are too slow or the user too fast :)
-			time.Sleep(time.Second)
-			cur := C.long(C.clipboard_change_count())
-			if cnt != cur {
-				changed <- struct{}{}
-				close(changed)
-				return
-			}
+		return nil, ErrUnavailable
+	}
+	return writeExtra(extra)
+}
+
+// writeOnce returns an error as the pasteboard keeps the written data
+// and does not tell when it is pasted.
+func writeOnce(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnsupported
+}
+
+// writeData writes the given data to clipboard as the pasteboard type of
+// a given MIME type or uniform type identifier.
+func writeData(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+	cs := C.CString(mime)
+	defer C.free(unsafe.Pointer(cs))
+
+	var ok C.int
+	if len(buf) == 0 {
+		ok = C.clipboard_write_data(cs, unsafe.Pointer(nil), 0)
+	} else {
+		ok = C.clipboard_write_data(cs, unsafe.Pointer(&buf[0]),
+			C.NSInteger(len(buf)))
+	}
+	if ok != 0 {
+		return nil, ErrUnavailable
+	}
+	return writeExtra(extra)
+}
+
+// writeExtra adds the additional representations to the pasteboard after
+// a write, and returns the channel that signals the write is overwritten.
+func writeExtra(extra []representation) (<-chan struct{}, error) {
+	var ok C.int
+	for _, r := range extra {
+		mime := r.mime
+		if mime == mimeText {
+			// The pasteboard type of plain text, which is not derived
+			// from the MIME type with a charset parameter.
+			mime = "public.utf8-plain-text"
 		}
-	}()
-	return changed, nil
-}
-
-func watch(ctx context.Context, t Format) <-chan []byte {
-	recv := make(chan []byte, 1)
-	// not sure if we are too slow or the user too fast :)
-	ti := time.NewTicker(time.Second)
-	lastCount := C.long(C.clipboard_change_count())
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				close(recv)
-				return
-			case <-ti.C:
-				this := C.long(C.clipboard_change_count())
-				if lastCount != this {
-					b := Read(t)
-					if b == nil {
-						continue
-					}
-					recv <- b
-					lastCount = this
-				}
-			}
+		cs := C.CString(mime)
+		if len(r.data) == 0 {
+			ok = C.clipboard_add_data(cs, unsafe.Pointer(nil), 0)
+		} else {
+			ok = C.clipboard_add_data(cs, unsafe.Pointer(&r.data[0]),
+				C.NSInteger(len(r.data)))
+		}
+		C.free(unsafe.Pointer(cs))
+		if ok != 0 {
+			return nil, ErrUnavailable
 		}
-	}()
-	return recv
+	}
+	return changedFrom(uint64(C.clipboard_change_count())), nil
+}
+
+// component converts a color component of the pasteboard, which ranges
+// from 0 to 1, to 16 bits.
+func component(v C.double) uint16 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 1:
+		return 0xffff
+	}
+	return uint16(v*0xffff + 0.5)
+}
+
+func writeOSAScript(kind OSAScriptKind, buf []byte) (<-chan struct{}, error) {
+	var ok C.int
+	if len(buf) == 0 {
+		ok = C.clipboard_write_osascript(C.int(kind), unsafe.Pointer(nil), 0)
+	} else {
+		ok = C.clipboard_write_osascript(C.int(kind), unsafe.Pointer(&buf[0]),
+			C.NSInteger(len(buf)))
+	}
+	if ok != 0 {
+		return nil, ErrUnavailable
+	}
+	return changedFrom(uint64(C.clipboard_change_count())), nil
+}
+
+// sequence returns the change count of the general pasteboard.
+func sequence() (uint64, bool) {
+	return uint64(C.clipboard_change_count()), true
+}
+
+func rawCall(fn func(uintptr) error) error {
+	return fn(uintptr(C.clipboard_pasteboard()))
+}
+
+func announce(msg string) error {
+	cs := C.CString(msg)
+	defer C.free(unsafe.Pointer(cs))
+
+	C.clipboard_announce(cs)
+	return nil
+}
+
+// promiseTimeout is the maximum duration that virtualFiles waits for the
+// senders of file promises to write the files.
+const promiseTimeout = 30 * time.Second
+
+// virtualFiles receives the files that the pasteboard promises into dir.
+func virtualFiles(dir string) ([]string, error) {
+	if C.clipboard_has_promises() == 0 {
+		return nil, ErrUnavailable
+	}
+	dir, err := virtualDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	cs := C.CString(dir)
+	defer C.free(unsafe.Pointer(cs))
+
+	var data unsafe.Pointer
+	n := C.clipboard_receive_promises(cs, C.int64_t(promiseTimeout), &data)
+	if data == nil {
+		return nil, ErrUnavailable
+	}
+	defer C.free(data)
+	return splitFiles(C.GoBytes(data, C.int(n))), nil
+}
+
+// lost returns the channel that receives a signal once the change count
+// of the pasteboard differs from the latest write, which is when another
+// application has taken over the pasteboard with clearContents.
+func lost() <-chan struct{} {
+	return changedFrom(uint64(C.clipboard_change_count()))
+}
+
+// info returns the behavior of NSPasteboard, which copies the data of a
+// write. Watch polls the change count of the pasteboard.
+func info() Info {
+	return Info{
+		Protocol:       "NSPasteboard",
+		SequenceNumber: true,
+	}
+}
+
+// locked reports whether the screen of the session is locked, or the
+// session is not on the console, for instance, the login window is shown
+// after switching users, where the pasteboard may be inaccessible.
+func locked() bool { return C.clipboard_locked() != 0 }
+
+// newSystem returns ErrUnsupported, as there is a single clipboard on
+// macOS.
+func newSystem(c config) (system, error) {
+	return system{}, fmt.Errorf("%w: multiple clipboards", ErrUnsupported)
+}
+
+// readSelection reads the clipboard for SelectionClipboard, as there are
+// no other selections.
+func readSelection(s Selection, t Format) ([]byte, error) {
+	if s != SelectionClipboard {
+		return nil, ErrUnsupported
+	}
+	return read(t)
 }
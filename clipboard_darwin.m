@@ -36,6 +36,190 @@ unsigned int clipboard_read_image(void **out) {
 	return siz;
 }
 
+// pasteboard_type returns the pasteboard type that is identified by the
+// given MIME type. A type without a slash is a uniform type identifier,
+// such as public.mp3, which is used as is. The caller is responsible for
+// releasing the returned type.
+static CFStringRef pasteboard_type(const char *mime) {
+	NSString *m = [NSString stringWithUTF8String:mime];
+	if ([m rangeOfString:@"/"].location == NSNotFound) {
+		return CFStringCreateCopy(NULL, (CFStringRef)m);
+	}
+	return UTTypeCreatePreferredIdentifierForTag(
+		kUTTagClassMIMEType, (CFStringRef)m, NULL);
+}
+
+// clipboard_read_mime reads the clipboard data of the pasteboard type
+// that is identified by the given MIME type.
+unsigned int clipboard_read_mime(const char *mime, void **out) {
+	CFStringRef uti = pasteboard_type(mime);
+	if (uti == NULL) {
+		return 0;
+	}
+	NSPasteboard * pasteboard = [NSPasteboard generalPasteboard];
+	NSData *data = [pasteboard dataForType:(NSString *)uti];
+	CFRelease(uti);
+	if (data == nil) {
+		return 0;
+	}
+	NSUInteger siz = [data length];
+	*out = malloc(siz);
+	[data getBytes: *out length: siz];
+	return siz;
+}
+
+// clipboard_read_files reads the file URLs of the pasteboard, and returns
+// their paths that are separated by newlines.
+unsigned int clipboard_read_files(void **out) {
+	@autoreleasepool {
+		NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+		NSArray *urls = [pasteboard readObjectsForClasses: @[[NSURL class]]
+			options: @{NSPasteboardURLReadingFileURLsOnlyKey: @YES}];
+		if (urls == nil || [urls count] == 0) {
+			return 0;
+		}
+		NSMutableArray *paths = [NSMutableArray array];
+		for (NSURL *url in urls) {
+			[paths addObject: [url path]];
+		}
+		const char *s = [[paths componentsJoinedByString: @"\n"] UTF8String];
+		size_t siz = strlen(s);
+		*out = malloc(siz);
+		memcpy(*out, s, siz);
+		return siz;
+	}
+}
+
+// clipboard_has_promises reads whether the pasteboard promises files,
+// as copied from Photos or Mail, whose data is only written on demand.
+int clipboard_has_promises() {
+	NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+	return [pasteboard canReadObjectForClasses: @[[NSFilePromiseReceiver class]]
+		options: nil];
+}
+
+// clipboard_receive_promises receives the files that the pasteboard
+// promises into the directory of the given path, and returns the paths
+// of the received files that are separated by newlines. It waits for the
+// senders to write the files at most timeout nanoseconds, files that are
+// not written in time are left out.
+unsigned int clipboard_receive_promises(const char *dir, int64_t timeout, void **out) {
+	@autoreleasepool {
+		NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+		NSArray *receivers = [pasteboard readObjectsForClasses:
+			@[[NSFilePromiseReceiver class]] options: nil];
+		if (receivers == nil || [receivers count] == 0) {
+			return 0;
+		}
+		NSURL *dest = [NSURL fileURLWithPath: [NSString stringWithUTF8String: dir]
+			isDirectory: YES];
+		NSOperationQueue *queue = [[[NSOperationQueue alloc] init] autorelease];
+		NSMutableArray *paths = [NSMutableArray array];
+		dispatch_group_t group = dispatch_group_create();
+		for (NSFilePromiseReceiver *r in receivers) {
+			// The reader is called once per promised file.
+			for (NSUInteger i = 0; i < [[r fileTypes] count]; i++) {
+				dispatch_group_enter(group);
+			}
+			[r receivePromisedFilesAtDestination: dest options: @{}
+				operationQueue: queue reader: ^(NSURL *url, NSError *err) {
+					if (err == nil) {
+						@synchronized (paths) {
+							[paths addObject: [url path]];
+						}
+					}
+					dispatch_group_leave(group);
+				}];
+		}
+		dispatch_group_wait(group, dispatch_time(DISPATCH_TIME_NOW, timeout));
+		dispatch_release(group);
+
+		NSString *joined;
+		@synchronized (paths) {
+			joined = [paths componentsJoinedByString: @"\n"];
+		}
+		if ([joined length] == 0) {
+			return 0;
+		}
+		const char *s = [joined UTF8String];
+		size_t siz = strlen(s);
+		*out = malloc(siz);
+		memcpy(*out, s, siz);
+		return siz;
+	}
+}
+
+// clipboard_read_url reads the URL of the pasteboard, as copied from
+// the address bar of browsers.
+unsigned int clipboard_read_url(void **out) {
+	NSPasteboard * pasteboard = [NSPasteboard generalPasteboard];
+	NSData *data = [pasteboard dataForType:NSPasteboardTypeURL];
+	if (data == nil) {
+		return 0;
+	}
+	NSUInteger siz = [data length];
+	*out = malloc(siz);
+	[data getBytes: *out length: siz];
+	return siz;
+}
+
+// clipboard_read_color reads the color of the pasteboard, as copied from
+// color pickers, into rgba as sRGB components that range from 0 to 1.
+int clipboard_read_color(double *rgba) {
+	NSPasteboard * pasteboard = [NSPasteboard generalPasteboard];
+	NSColor *color = [NSColor colorFromPasteboard:pasteboard];
+	if (color == nil) {
+		return -1;
+	}
+	color = [color colorUsingColorSpace:[NSColorSpace sRGBColorSpace]];
+	if (color == nil) {
+		return -1;
+	}
+	rgba[0] = [color redComponent];
+	rgba[1] = [color greenComponent];
+	rgba[2] = [color blueComponent];
+	rgba[3] = [color alphaComponent];
+	return 0;
+}
+
+// clipboard_has reads whether the pasteboard offers data of the given
+// type without reading the data. The type is 0 for text, 1 for image,
+// 3 for file URLs, 4 for URLs, 5 for colors, otherwise the pasteboard
+// type is identified by the given MIME type.
+int clipboard_has(int typ, const char *mime) {
+	NSPasteboardType t;
+	CFStringRef uti = NULL;
+	switch (typ) {
+	case 0:
+		t = NSPasteboardTypeString;
+		break;
+	case 1:
+		t = NSPasteboardTypePNG;
+		break;
+	case 3:
+		t = NSPasteboardTypeFileURL;
+		break;
+	case 4:
+		t = NSPasteboardTypeURL;
+		break;
+	case 5:
+		t = NSPasteboardTypeColor;
+		break;
+	default:
+		uti = pasteboard_type(mime);
+		if (uti == NULL) {
+			return 0;
+		}
+		t = (NSString *)uti;
+	}
+	NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+	NSString *avail = [pasteboard availableTypeFromArray:@[t]];
+	if (uti != NULL) {
+		CFRelease(uti);
+	}
+	return avail != nil;
+}
+
 int clipboard_write_string(const void *bytes, NSInteger n) {
 	NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
 	NSData *data = [NSData dataWithBytes: bytes length: n];
@@ -57,6 +241,220 @@ int clipboard_write_image(const void *bytes, NSInteger n) {
 	return 0;
 }
 
+// clipboard_add_data adds the given bytes to the pasteboard as the
+// pasteboard type that is identified by the given MIME type. It must
+// be called after a write that clears the pasteboard.
+int clipboard_add_data(const char *mime, const void *bytes, NSInteger n) {
+	CFStringRef uti = pasteboard_type(mime);
+	if (uti == NULL) {
+		return -1;
+	}
+	NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+	NSData *data = [NSData dataWithBytes: bytes length: n];
+	BOOL ok = [pasteboard setData: data forType:(NSString *)uti];
+	CFRelease(uti);
+	if (!ok) {
+		return -1;
+	}
+	return 0;
+}
+
+// clipboard_write_data writes the given bytes as the pasteboard type
+// that is identified by the given MIME type.
+int clipboard_write_data(const char *mime, const void *bytes, NSInteger n) {
+	[[NSPasteboard generalPasteboard] clearContents];
+	return clipboard_add_data(mime, bytes, n);
+}
+
+// clipboard_write_files writes the given newline separated absolute file
+// paths to the pasteboard as file URLs.
+int clipboard_write_files(const void *bytes, NSInteger n) {
+	@autoreleasepool {
+		NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+		NSData *data = [NSData dataWithBytes: bytes length: n];
+		NSString *s = [[[NSString alloc] initWithData: data
+			encoding: NSUTF8StringEncoding] autorelease];
+		if (s == nil) {
+			return -1;
+		}
+		NSMutableArray *urls = [NSMutableArray array];
+		for (NSString *path in [s componentsSeparatedByString: @"\n"]) {
+			if ([path length] == 0) {
+				continue;
+			}
+			[urls addObject: [NSURL fileURLWithPath: path]];
+		}
+
+		[pasteboard clearContents];
+		BOOL ok = [pasteboard writeObjects: urls];
+		if (!ok) {
+			return -1;
+		}
+		return 0;
+	}
+}
+
+// clipboard_write_url writes the given URL to the pasteboard, which is
+// also offered as plain text for applications that do not read URLs.
+int clipboard_write_url(const void *bytes, NSInteger n) {
+	NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+	NSData *data = [NSData dataWithBytes: bytes length: n];
+	[pasteboard clearContents];
+	BOOL ok = [pasteboard setData: data forType:NSPasteboardTypeURL] &&
+		[pasteboard setData: data forType:NSPasteboardTypeString];
+	if (!ok) {
+		return -1;
+	}
+	return 0;
+}
+
+// clipboard_write_color writes the color of the given sRGB components to
+// the pasteboard.
+int clipboard_write_color(double r, double g, double b, double a) {
+	NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+	NSColor *color = [NSColor colorWithSRGBRed:r green:g blue:b alpha:a];
+	[pasteboard clearContents];
+	[color writeToPasteboard:pasteboard];
+	if (![[pasteboard types] containsObject:NSPasteboardTypeColor]) {
+		return -1;
+	}
+	return 0;
+}
+
+// clipboard_write_osascript writes the given bytes as objects to the
+// pasteboard, which results in the same pasteboard types as AppleScript's
+// "set the clipboard to" command. See OSAScriptKind for the kinds.
+int clipboard_write_osascript(int kind, const void *bytes, NSInteger n) {
+	@autoreleasepool {
+		NSPasteboard *pasteboard = [NSPasteboard generalPasteboard];
+		NSData *data = [NSData dataWithBytes: bytes length: n];
+		NSMutableArray *objects = [NSMutableArray array];
+		switch (kind) {
+		case 0: {
+			NSString *s = [[[NSString alloc] initWithData: data
+				encoding: NSUTF8StringEncoding] autorelease];
+			if (s == nil) {
+				return -1;
+			}
+			[objects addObject: s];
+			break;
+		}
+		case 1: {
+			NSAttributedString *s = [[[NSAttributedString alloc] initWithRTF: data
+				documentAttributes: nil] autorelease];
+			if (s == nil) {
+				return -1;
+			}
+			[objects addObject: s];
+			break;
+		}
+		case 2: {
+			NSString *s = [[[NSString alloc] initWithData: data
+				encoding: NSUTF8StringEncoding] autorelease];
+			if (s == nil) {
+				return -1;
+			}
+			for (NSString *path in [s componentsSeparatedByString: @"\n"]) {
+				if ([path length] == 0) {
+					continue;
+				}
+				[objects addObject: [NSURL fileURLWithPath: path]];
+			}
+			break;
+		}
+		default:
+			return -1;
+		}
+
+		[pasteboard clearContents];
+		BOOL ok = [pasteboard writeObjects: objects];
+		if (!ok) {
+			return -1;
+		}
+		return 0;
+	}
+}
+
 NSInteger clipboard_change_count() {
 	return [[NSPasteboard generalPasteboard] changeCount];
 }
+
+// clipboard_locked returns 1 if the screen of the current session is
+// locked, or the session is not on the console. Processes outside of a
+// window server session, such as over ssh, have no session dictionary,
+// hence are never considered locked.
+int clipboard_locked() {
+	CFDictionaryRef session = CGSessionCopyCurrentDictionary();
+	if (session == NULL) {
+		return 0;
+	}
+	int locked = 0;
+	CFBooleanRef onConsole = CFDictionaryGetValue(session, kCGSessionOnConsoleKey);
+	if (onConsole != NULL && !CFBooleanGetValue(onConsole)) {
+		locked = 1;
+	}
+	CFBooleanRef screenLocked = CFDictionaryGetValue(session, CFSTR("CGSSessionScreenIsLocked"));
+	if (screenLocked != NULL && CFBooleanGetValue(screenLocked)) {
+		locked = 1;
+	}
+	CFRelease(session);
+	return locked;
+}
+
+void *clipboard_pasteboard() {
+	return [NSPasteboard generalPasteboard];
+}
+
+// clipboard_announce posts an announcement to the assistive technologies,
+// such as VoiceOver.
+void clipboard_announce(const char *msg) {
+	NSString *s = [NSString stringWithUTF8String: msg];
+	dispatch_async(dispatch_get_main_queue(), ^{
+		if (NSApp == nil) {
+			return;
+		}
+		id element = [NSApp mainWindow];
+		if (element == nil) {
+			element = NSApp;
+		}
+		NSAccessibilityPostNotificationWithUserInfo(element,
+			NSAccessibilityAnnouncementRequestedNotification, @{
+				NSAccessibilityAnnouncementKey: s,
+				NSAccessibilityPriorityKey: @(NSAccessibilityPriorityHigh),
+			});
+	});
+}
+
+// pasteboardChanged is a function from the Go side.
+extern void pasteboardChanged();
+
+// watch_timer checks the change count of the pasteboard on a private
+// serial queue, independently of the run loop of the host application.
+static dispatch_source_t watch_timer = NULL;
+
+// clipboard_watch starts or reschedules the check of the change count of
+// the pasteboard every interval nanoseconds, and calls pasteboardChanged
+// whenever the count changes. The system may defer each check by a tenth
+// of the interval to coalesce it with other timers, which saves power.
+void clipboard_watch(int64_t interval) {
+	static dispatch_once_t once;
+	dispatch_once(&once, ^{
+		dispatch_queue_t queue = dispatch_queue_create(
+			"design.golang.clipboard.watch", DISPATCH_QUEUE_SERIAL);
+		watch_timer = dispatch_source_create(
+			DISPATCH_SOURCE_TYPE_TIMER, 0, 0, queue);
+		__block NSInteger last = [[NSPasteboard generalPasteboard] changeCount];
+		dispatch_source_set_event_handler(watch_timer, ^{
+			@autoreleasepool {
+				NSInteger count = [[NSPasteboard generalPasteboard] changeCount];
+				if (count != last) {
+					last = count;
+					pasteboardChanged();
+				}
+			}
+		});
+		dispatch_resume(watch_timer);
+	});
+	dispatch_source_set_timer(watch_timer,
+		dispatch_time(DISPATCH_TIME_NOW, interval), interval, interval / 10);
+}
@@ -15,30 +15,54 @@ package clipboard
 #import <stdlib.h>
 void clipboard_write_string(char *s);
 char *clipboard_read_string();
+void *clipboard_pasteboard();
+long clipboard_change_count();
+int clipboard_has_string();
+void clipboard_announce(char *msg);
 */
 import "C"
 import (
-	"bytes"
-	"context"
-	"time"
+	"fmt"
 	"unsafe"
 )
 
-func initialize() error { return nil }
+func initialize(c config) error {
+	if c.backend != BackendAuto {
+		return fmt.Errorf("%w: %v backend", ErrUnsupported, c.backend)
+	}
+	return nil
+}
 
 func read(t Format) (buf []byte, err error) {
 	switch t {
 	case FmtText:
 		return []byte(C.GoString(C.clipboard_read_string())), nil
 	case FmtImage:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
 	default:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
 	}
 }
 
-// SetContent sets the clipboard content for iOS
-func write(t Format, buf []byte) (<-chan struct{}, error) {
+// readData returns an error as reading data of arbitrary MIME types
+// is not supported yet.
+func readData(mime string) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+func has(t Format) bool {
+	return t == FmtText && C.clipboard_has_string() != 0
+}
+
+// prober returns a function that reports whether the clipboard holds
+// data in a format.
+func prober() func(Format) bool { return has }
+
+func imageInfo() (int, int, string, error) { return 0, 0, "", ErrUnsupported }
+
+// SetContent sets the clipboard content for iOS. Additional
+// representations are not supported yet, hence they are ignored.
+func write(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
 	done := make(chan struct{}, 1)
 	switch t {
 	case FmtText:
@@ -48,33 +72,78 @@ func write(t Format, buf []byte) (<-chan struct{}, error) {
 		C.clipboard_write_string(cs)
 		return done, nil
 	case FmtImage:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
 	default:
-		return nil, errUnsupported
+		return nil, ErrUnsupported
+	}
+}
+
+// writeOnce returns an error as UIPasteboard does not tell when the
+// data is pasted.
+func writeOnce(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnsupported
+}
+
+// writeData returns an error as writing data of arbitrary MIME types
+// is not supported yet.
+func writeData(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnsupported
+}
+
+// sequence returns the change count of the general pasteboard.
+func sequence() (uint64, bool) {
+	return uint64(C.clipboard_change_count()), true
+}
+
+func rawCall(fn func(uintptr) error) error {
+	return fn(uintptr(C.clipboard_pasteboard()))
+}
+
+func announce(msg string) error {
+	cs := C.CString(msg)
+	defer C.free(unsafe.Pointer(cs))
+
+	C.clipboard_announce(cs)
+	return nil
+}
+
+// virtualFiles returns ErrUnavailable, as files are not supported on iOS
+// at the moment.
+func virtualFiles(dir string) ([]string, error) {
+	return nil, ErrUnavailable
+}
+
+// lost returns the channel that receives a signal once the change count
+// of the pasteboard differs from the latest write, as UIPasteboard does
+// not tell the ownership otherwise.
+func lost() <-chan struct{} {
+	return changedFrom(uint64(C.clipboard_change_count()))
+}
+
+// info returns the behavior of UIPasteboard, which copies the data of a
+// write. Watch polls the change count of the pasteboard.
+func info() Info {
+	return Info{
+		Protocol:       "UIPasteboard",
+		SequenceNumber: true,
 	}
 }
 
-func watch(ctx context.Context, t Format) <-chan []byte {
-	recv := make(chan []byte, 1)
-	ti := time.NewTicker(time.Second)
-	last := Read(t)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				close(recv)
-				return
-			case <-ti.C:
-				b := Read(t)
-				if b == nil {
-					continue
-				}
-				if bytes.Compare(last, b) != 0 {
-					recv <- b
-					last = b
-				}
-			}
-		}
-	}()
-	return recv
+// locked returns false, as UIPasteboard is accessible to the application
+// whenever it runs in the foreground.
+func locked() bool { return false }
+
+// newSystem returns ErrUnsupported, as there is a single clipboard on
+// iOS.
+func newSystem(c config) (system, error) {
+	return system{}, fmt.Errorf("%w: multiple clipboards", ErrUnsupported)
+}
+
+// readSelection reads the clipboard for SelectionClipboard, as there are
+// no other selections.
+func readSelection(s Selection, t Format) ([]byte, error) {
+	if s != SelectionClipboard {
+		return nil, ErrUnsupported
+	}
+	return read(t)
 }
@@ -18,3 +18,22 @@ char *clipboard_read_string() {
     NSString *str = [[UIPasteboard generalPasteboard] string];
     return (char *)[str UTF8String];
 }
+
+int clipboard_has_string() {
+    return [[UIPasteboard generalPasteboard] hasStrings];
+}
+
+long clipboard_change_count() {
+    return [[UIPasteboard generalPasteboard] changeCount];
+}
+
+void *clipboard_pasteboard() {
+    return [UIPasteboard generalPasteboard];
+}
+
+void clipboard_announce(char *msg) {
+    NSString *value = [NSString stringWithUTF8String:msg];
+    dispatch_async(dispatch_get_main_queue(), ^{
+        UIAccessibilityPostNotification(UIAccessibilityAnnouncementNotification, value);
+    });
+}
@@ -10,16 +10,44 @@
 #include <stdio.h>
 #include <stdint.h>
 #include <string.h>
+#include <time.h>
 #include <dlfcn.h>
+#include <errno.h>
+#include <sys/select.h>
+#include <sys/socket.h>
+#include <unistd.h>
 #include <X11/Xlib.h>
 #include <X11/Xatom.h>
 
 // syncStatus is a function from the Go side.
 extern void syncStatus(uintptr_t handle, int status);
+// renderTarget is a function from the Go side, which renders the data of
+// a target that is converted on demand.
+extern int renderTarget(uintptr_t handle, int target, unsigned char **buf, size_t *n);
 
 void *libX11;
 
-Display* (*P_XOpenDisplay)(int);
+// display_name is the name of the display to connect, the DISPLAY
+// environment variable is used if it is NULL.
+char *display_name = NULL;
+
+// thread_display is the name of the display that the connections of the
+// calling thread connect to instead of display_name, which binds the
+// calls of the thread to the display of a Clipboard instance of the Go
+// side, see clipboard_use_display.
+static __thread char *thread_display = NULL;
+
+// connect_name returns the name of the display that the calling thread
+// connects to.
+static const char *connect_name() {
+    return thread_display != NULL ? thread_display : display_name;
+}
+
+// shared_display is the display connection of the host application,
+// which is used instead of connecting to the display if it is not NULL.
+Display *shared_display = NULL;
+
+Display* (*P_XOpenDisplay)(const char*);
 void (*P_XCloseDisplay)(Display*);
 Window (*P_XDefaultRootWindow)(Display*);
 Window (*P_XCreateSimpleWindow)(Display*, Window, int, int, int, int, int, int, int);
@@ -33,6 +61,88 @@ int (*P_XGetWindowProperty) (Display*, Window, Atom, long, long, Bool, Atom, Ato
 void (*P_XFree) (void*);
 void (*P_XDeleteProperty) (Display*, Window, Atom);
 void (*P_XConvertSelection)(Display*, Atom, Atom, Atom, Window, Time);
+char* (*P_XGetAtomName)(Display*, Atom);
+int (*P_XSelectInput)(Display*, Window, long);
+int (*P_XPending)(Display*);
+int (*P_XConnectionNumber)(Display*);
+int (*P_XSync)(Display*, Bool);
+long (*P_XMaxRequestSize)(Display*);
+long (*P_XExtendedMaxRequestSize)(Display*);
+XErrorHandler (*P_XSetErrorHandler)(XErrorHandler);
+int (*P_XInitThreads)(void);
+void (*P_XLockDisplay)(Display*);
+void (*P_XUnlockDisplay)(Display*);
+int (*P_XCheckTypedWindowEvent)(Display*, Window, int, XEvent*);
+int (*P_XDestroyWindow)(Display*, Window);
+char* (*P_XDisplayString)(Display*);
+
+// The default error handler of Xlib terminates the process, hence errors
+// caused by racing selection owners, for instance a requestor window that
+// is destroyed during a transfer, would take down the host application.
+// Errors on the display of the ongoing operation of the calling thread are
+// recorded for the operation instead, and other errors are passed to the
+// previously installed handler.
+static XErrorHandler prev_error_handler;
+static __thread Display *err_display;
+static __thread int err_code;
+static __thread XID err_resource;
+
+static int error_handler(Display *d, XErrorEvent *e) {
+    if (d != NULL && d == err_display) {
+        err_code     = e->error_code;
+        err_resource = e->resourceid;
+        return 0;
+    }
+    if (prev_error_handler != NULL) {
+        return prev_error_handler(d, e);
+    }
+    return 0;
+}
+
+// watch_errors records the errors of the given display for the ongoing
+// operation of the calling thread until the display is closed.
+static void watch_errors(Display *d) {
+    err_display  = d;
+    err_code     = 0;
+    err_resource = None;
+}
+
+// open_display opens a connection to the display, or locks the shared
+// display of the host application for the calling thread. The caller is
+// responsible for releasing the display using close_display.
+static Display *open_display() {
+    if (shared_display != NULL && thread_display == NULL) {
+        (*P_XLockDisplay)(shared_display);
+        return shared_display;
+    }
+
+    Display* d = NULL;
+    for (int i = 0; i < 42; i++) {
+        d = (*P_XOpenDisplay)(connect_name());
+        if (d == NULL) {
+            continue;
+        }
+        break;
+    }
+    return d;
+}
+
+// close_display releases a display of open_display. The window w that
+// is created on the display is destroyed, unless it is None.
+static void close_display(Display *d, Window w) {
+    if (d == shared_display) {
+        if (w != None) {
+            (*P_XDestroyWindow)(d, w);
+        }
+        (*P_XSync)(d, False);
+        (*P_XUnlockDisplay)(d);
+    } else {
+        (*P_XCloseDisplay)(d);
+    }
+    if (err_display == d) {
+        err_display = NULL;
+    }
+}
 
 int initX11() {
 	if (libX11) {
@@ -42,7 +152,7 @@ int initX11() {
 	if (!libX11) {
 		return 0;
 	}
-	P_XOpenDisplay = (Display* (*)(int)) dlsym(libX11, "XOpenDisplay");
+	P_XOpenDisplay = (Display* (*)(const char*)) dlsym(libX11, "XOpenDisplay");
 	P_XCloseDisplay = (void (*)(Display*)) dlsym(libX11, "XCloseDisplay");
 	P_XDefaultRootWindow = (Window (*)(Display*)) dlsym(libX11, "XDefaultRootWindow");
 	P_XCreateSimpleWindow = (Window (*)(Display*, Window, int, int, int, int, int, int, int)) dlsym(libX11, "XCreateSimpleWindow");
@@ -56,40 +166,305 @@ int initX11() {
 	P_XFree = (void (*)(void*)) dlsym(libX11, "XFree");
 	P_XDeleteProperty = (void (*)(Display*, Window, Atom)) dlsym(libX11, "XDeleteProperty");
 	P_XConvertSelection = (void (*)(Display*, Atom, Atom, Atom, Window, Time)) dlsym(libX11, "XConvertSelection");
+	P_XGetAtomName = (char* (*)(Display*, Atom)) dlsym(libX11, "XGetAtomName");
+	P_XSelectInput = (int (*)(Display*, Window, long)) dlsym(libX11, "XSelectInput");
+	P_XPending = (int (*)(Display*)) dlsym(libX11, "XPending");
+	P_XConnectionNumber = (int (*)(Display*)) dlsym(libX11, "XConnectionNumber");
+	P_XSync = (int (*)(Display*, Bool)) dlsym(libX11, "XSync");
+	P_XMaxRequestSize = (long (*)(Display*)) dlsym(libX11, "XMaxRequestSize");
+	P_XExtendedMaxRequestSize = (long (*)(Display*)) dlsym(libX11, "XExtendedMaxRequestSize");
+	P_XSetErrorHandler = (XErrorHandler (*)(XErrorHandler)) dlsym(libX11, "XSetErrorHandler");
+	P_XInitThreads = (int (*)(void)) dlsym(libX11, "XInitThreads");
+	P_XLockDisplay = (void (*)(Display*)) dlsym(libX11, "XLockDisplay");
+	P_XUnlockDisplay = (void (*)(Display*)) dlsym(libX11, "XUnlockDisplay");
+	P_XCheckTypedWindowEvent = (int (*)(Display*, Window, int, XEvent*)) dlsym(libX11, "XCheckTypedWindowEvent");
+	P_XDestroyWindow = (int (*)(Display*, Window)) dlsym(libX11, "XDestroyWindow");
+	P_XDisplayString = (char* (*)(Display*)) dlsym(libX11, "XDisplayString");
+
+	// The connections are used from multiple threads, and other libraries
+	// of the process, such as GLFW or SDL, may use Xlib concurrently.
+	// XInitThreads must be the first Xlib call of the process, and is
+	// a no-op if it was called already.
+	(*P_XInitThreads)();
+	prev_error_handler = (*P_XSetErrorHandler)(error_handler);
 	return 1;
 }
 
+// clipboard_set_display sets the name of the display to connect.
+void clipboard_set_display(const char *name) {
+    free(display_name);
+    display_name = name == NULL ? NULL : strdup(name);
+}
+
+// clipboard_use_display binds the connections of the calling thread to
+// the display of the given name, or to the display of the package if name
+// is NULL.
+void clipboard_use_display(const char *name) {
+    free(thread_display);
+    thread_display = name == NULL ? NULL : strdup(name);
+}
+
+// clipboard_set_shared_display sets the display connection of the host
+// application to use, which must be initialized for threads. Separate
+// connections, such as the one of clipboard_write, connect to the same
+// display unless a display name is set.
+void clipboard_set_shared_display(void *d) {
+	if (!initX11()) {
+		return;
+	}
+    shared_display = (Display *)d;
+    if (display_name == NULL) {
+        display_name = strdup((*P_XDisplayString)(shared_display));
+    }
+}
+
 int clipboard_test() {
 	if (!initX11()) {
 		return -1;
 	}
 
-    Display* d = NULL;
-    for (int i = 0; i < 42; i++) {
-        d = (*P_XOpenDisplay)(0);
-        if (d == NULL) {
-            continue;
-        }
-        break;
-    }
+    Display* d = open_display();
     if (d == NULL) {
         return -1;
     }
-    (*P_XCloseDisplay)(d);
+    close_display(d, None);
     return 0;
 }
 
-// clipboard_write writes the given buf of size n as type typ.
+// clipboard_open opens a connection to the X server, the caller is
+// responsible for closing the connection using clipboard_close.
+void *clipboard_open() {
+	if (!initX11()) {
+		return NULL;
+	}
+
+    return open_display();
+}
+
+void clipboard_close(void *d) {
+    close_display((Display *)d, None);
+}
+
+static long elapsed_us(struct timespec *start) {
+    struct timespec now;
+    clock_gettime(CLOCK_MONOTONIC, &now);
+    return (now.tv_sec - start->tv_sec) * 1000000L +
+        (now.tv_nsec - start->tv_nsec) / 1000L;
+}
+
+// clipboard_latency measures the average round trip time to the display
+// server in microseconds, or returns -1 if the display is not available.
+long clipboard_latency() {
+	if (!initX11()) {
+		return -1;
+	}
+
+    Display* d = open_display();
+    if (d == NULL) {
+        return -1;
+    }
+
+    const int rounds = 3;
+    struct timespec start;
+    clock_gettime(CLOCK_MONOTONIC, &start);
+    for (int i = 0; i < rounds; i++) {
+        (*P_XSync)(d, False);
+    }
+    long us = elapsed_us(&start) / rounds;
+    close_display(d, None);
+    return us;
+}
+
+// wait_event waits for the next event of the given type for window w
+// until timeout milliseconds elapsed. It returns 1 if the event arrives,
+// or 0 if the wait timed out. A non-positive timeout waits forever. If
+// cancel is a valid file descriptor, the wait is canceled once it becomes
+// readable, and -1 is returned. Other events are left in the queue, as
+// they may belong to the host application on a shared display.
+static int wait_event(Display *d, Window w, int type, XEvent *event, long timeout, int cancel) {
+    struct timespec start;
+    clock_gettime(CLOCK_MONOTONIC, &start);
+    int fd = (*P_XConnectionNumber)(d);
+    for (;;) {
+        (*P_XPending)(d);
+        if ((*P_XCheckTypedWindowEvent)(d, w, type, event)) {
+            return 1;
+        }
+
+        struct timeval tv, *ptv = NULL;
+        if (timeout > 0) {
+            long left = timeout * 1000L - elapsed_us(&start);
+            if (left <= 0) {
+                return 0;
+            }
+            tv.tv_sec  = left / 1000000L;
+            tv.tv_usec = left % 1000000L;
+            ptv = &tv;
+        }
+
+        fd_set fds;
+        FD_ZERO(&fds);
+        FD_SET(fd, &fds);
+        int nfds = fd + 1;
+        if (cancel >= 0) {
+            FD_SET(cancel, &fds);
+            if (cancel >= fd) {
+                nfds = cancel + 1;
+            }
+        }
+        select(nfds, &fds, NULL, NULL, ptv);
+        if (cancel >= 0 && FD_ISSET(cancel, &fds)) {
+            return -1;
+        }
+    }
+}
+
+// max_chunk returns the maximum number of bytes that can be transferred
+// by a single property change request of the given display.
+static size_t max_chunk(Display *d) {
+    long n = (*P_XExtendedMaxRequestSize)(d);
+    if (n == 0) {
+        n = (*P_XMaxRequestSize)(d);
+    }
+    // max request size is in 4-byte units, reserve space for the
+    // request header.
+    return (size_t)n * 4 - 256;
+}
+
+// incr is the state of an incremental transfer to a requestor, see:
+// https://www.x.org/releases/X11R7.6/doc/xorg-docs/specs/ICCCM/icccm.html#incr_properties
+struct incr {
+    Window requestor;
+    Atom   property;
+    int    target; // index of the transferred target
+    size_t offset;
+    int    active;
+};
+
+#define MAX_INCR 16
+
+// render_target renders the data of target i if it is rendered on demand
+// and not rendered yet, where render is the handle of the renderers of
+// the Go side, or 0 if the data of all targets is given. It reports
+// whether the data of the target is available.
+static int render_target(uintptr_t render, unsigned char **bufs, size_t *ns, int i) {
+    if (render == 0 || ns[i] != 0) {
+        return 1;
+    }
+    return renderTarget(render, i, &bufs[i], &ns[i]);
+}
+
+// serve_multiple serves a request of the MULTIPLE target, whose property
+// lists pairs of the requested targets and the properties to store them,
+// see:
+// https://www.x.org/releases/X11R7.6/doc/xorg-docs/specs/ICCCM/icccm.html#multiple
+// Clipboard managers, such as the one of GNOME, request the data to save
+// this way. Targets that are not offered, or are too large to transfer
+// without INCR, are refused by replacing their property with None. It
+// returns -1 if the pairs cannot be read, or the index of the foremost
+// served target otherwise, which is count if no target is served.
+static int serve_multiple(Display *d, Window requestor, Atom property,
+    Atom *targets, int *formats, unsigned char **bufs, size_t *ns, int count,
+    size_t chunk, uintptr_t render) {
+    unsigned char *data = NULL;
+    Atom actual;
+    int format;
+    unsigned long n = 0, after = 0;
+    int ret = (*P_XGetWindowProperty)(d, requestor, property, 0L, (~0L), 0,
+        AnyPropertyType, &actual, &format, &n, &after, &data);
+    if (ret != Success || data == NULL) {
+        return -1;
+    }
+    if (format != 32) {
+        (*P_XFree)(data);
+        return -1;
+    }
+
+    // Atoms of format 32 are stored as longs on the client side.
+    Atom *pairs = (Atom *)data;
+    int served = count;
+    for (unsigned long i = 0; i + 1 < n; i += 2) {
+        int target = -1;
+        for (int j = 0; j < count; j++) {
+            if (pairs[i] == targets[j]) {
+                target = j;
+                break;
+            }
+        }
+        if (target >= 0 && !render_target(render, bufs, ns, target)) {
+            target = -1;
+        }
+        if (pairs[i] == targets[count]) {
+            (*P_XChangeProperty)(d, requestor, pairs[i+1], XA_ATOM, 32,
+                PropModeReplace, (unsigned char *)targets, count + 1);
+        } else if (target >= 0 && ns[target] <= chunk && pairs[i+1] != None) {
+            (*P_XChangeProperty)(d, requestor, pairs[i+1], targets[target],
+                formats[target], PropModeReplace, bufs[target],
+                ns[target] * 8 / formats[target]);
+            if (target < served) {
+                served = target;
+            }
+        } else {
+            pairs[i+1] = None;
+        }
+    }
+
+    // Write back the pairs, which tells the refused targets.
+    (*P_XChangeProperty)(d, requestor, property, actual, 32, PropModeReplace,
+        data, (int)n);
+    (*P_XFree)(data);
+    return served;
+}
+
+// save_targets asks the clipboard manager, if any, to save the given
+// targets using the SAVE_TARGETS target of the CLIPBOARD_MANAGER
+// selection, see:
+// https://www.freedesktop.org/wiki/ClipboardManager/
+// The manager then requests the data from the owner, and takes over the
+// clipboard once the owner exits. The request is asynchronous, and its
+// SelectionNotify is ignored by the owner.
+static void save_targets(Display *d, Window w, Atom *targets, int count) {
+    Atom manager = (*P_XInternAtom)(d, "CLIPBOARD_MANAGER", 0);
+    if ((*P_XGetSelectionOwner)(d, manager) == None) {
+        return;
+    }
+    Atom save = (*P_XInternAtom)(d, "SAVE_TARGETS", 0);
+    Atom prop = (*P_XInternAtom)(d, "GOLANG_DESIGN_SAVE", 0);
+    (*P_XChangeProperty)(d, w, prop, XA_ATOM, 32, PropModeReplace,
+        (unsigned char *)targets, count);
+    (*P_XConvertSelection)(d, manager, save, prop, w, CurrentTime);
+}
+
+// clipboard_write writes the given count bufs, where bufs[i] of size ns[i]
+// is offered as target typs[i]. The first target is the primary one, and
+// the others are alternative representations of the data.
 // if start is provided, the value of start will be changed to 1 to indicate
 // if the write is availiable for reading.
-int clipboard_write(char *typ, unsigned char *buf, size_t n, uintptr_t handle) {
+//
+// Data larger than chunk bytes, or larger than the maximum request size of
+// the display, is transferred incrementally using the INCR mechanism.
+//
+// If once is positive, the ownership is given up after the data of any of
+// the first once targets has been transferred to a requestor, which
+// clears the clipboard.
+//
+// If save is non-zero, the clipboard manager is asked to save the data,
+// so that it survives the exit of the process.
+//
+// If render is non-zero, the targets of empty data are rendered on demand
+// by renderTarget of the Go side when a requestor asks for them, so that
+// conversions, such as of an image to other encodings, are only paid for
+// if they are requested.
+int clipboard_write(char **typs, unsigned char **bufs, size_t *ns, int count, size_t chunk, int once, int save, uintptr_t handle, uintptr_t render) {
 	if (!initX11()) {
 		return -1;
 	}
 
+    // The owner serves requests using its own connection, as the events
+    // of the selection cannot be shared with the event loop of the host.
     Display* d = NULL;
     for (int i = 0; i < 42; i++) {
-        d = (*P_XOpenDisplay)(0);
+        d = (*P_XOpenDisplay)(connect_name());
         if (d == NULL) {
             continue;
         }
@@ -99,32 +474,52 @@ int clipboard_write(char *typ, unsigned char *buf, size_t n, uintptr_t handle) {
         syncStatus(handle, -1);
         return -1;
     }
+    watch_errors(d);
     Window w = (*P_XCreateSimpleWindow)(d, (*P_XDefaultRootWindow)(d), 0, 0, 1, 1, 0, 0, 0);
 
     // Use False because these may not available for the first time.
     Atom sel         = (*P_XInternAtom)(d, "CLIPBOARD", 0);
-    Atom atomString  = (*P_XInternAtom)(d, "UTF8_STRING", 0);
-    Atom atomImage   = (*P_XInternAtom)(d, "image/png", 0);
     Atom targetsAtom = (*P_XInternAtom)(d, "TARGETS", 0);
+    Atom incrAtom    = (*P_XInternAtom)(d, "INCR", 0);
+    Atom multiAtom   = (*P_XInternAtom)(d, "MULTIPLE", 0);
 
-    // Use True to makesure the requested type is a valid type.
-    Atom target = (*P_XInternAtom)(d, typ, 1);
-    if (target == None) {
-        (*P_XCloseDisplay)(d);
-        syncStatus(handle, -2);
-        return -2;
+    // The offered targets, followed by TARGETS itself.
+    Atom *targets = (Atom *)malloc((count + 1) * sizeof(Atom));
+    for (int i = 0; i < count; i++) {
+        targets[i] = (*P_XInternAtom)(d, typs[i], 0);
+    }
+    targets[count] = targetsAtom;
+
+    // The property format of each target. application/x-color is an
+    // array of 16-bit components, which requestors such as GTK only
+    // accept in format 16.
+    int *formats = (int *)malloc(count * sizeof(int));
+    for (int i = 0; i < count; i++) {
+        formats[i] = strcmp(typs[i], "application/x-color") == 0 ? 16 : 8;
     }
 
     (*P_XSetSelectionOwner)(d, sel, w, CurrentTime);
     if ((*P_XGetSelectionOwner)(d, sel) != w) {
-        (*P_XCloseDisplay)(d);
+        free(targets);
+        free(formats);
+        close_display(d, w);
         syncStatus(handle, -3);
         return -3;
     }
 
+    size_t limit = max_chunk(d);
+    if (chunk == 0 || chunk > limit) {
+        chunk = limit;
+    }
+    struct incr incrs[MAX_INCR] = {0};
+    if (save) {
+        save_targets(d, w, targets, count);
+    }
+
     XEvent event;
     XSelectionRequestEvent* xsr;
     int notified = 0;
+    int served   = 0;
     for (;;) {
         if (notified == 0) {
             syncStatus(handle, 1); // notify Go side
@@ -132,18 +527,70 @@ int clipboard_write(char *typ, unsigned char *buf, size_t n, uintptr_t handle) {
         }
 
         (*P_XNextEvent)(d, &event);
+        if (err_code != 0) {
+            // A requestor vanished during a transfer, abandon its
+            // incremental transfer and keep serving others.
+            for (int i = 0; i < MAX_INCR; i++) {
+                if (incrs[i].active && incrs[i].requestor == err_resource) {
+                    incrs[i].active = 0;
+                }
+            }
+            err_code = 0;
+        }
         switch (event.type) {
         case SelectionClear:
             // For debugging:
             // printf("x11write: lost ownership of clipboard selection.\n");
             // fflush(stdout);
-            (*P_XCloseDisplay)(d);
-            return 0;
+            free(targets);
+            free(formats);
+            close_display(d, w);
+            return 1;
         case SelectionNotify:
             // For debugging:
             // printf("x11write: notify.\n");
             // fflush(stdout);
             break;
+        case PropertyNotify:
+            if (event.xproperty.state != PropertyDelete) {
+                break;
+            }
+            for (int i = 0; i < MAX_INCR; i++) {
+                struct incr *t = &incrs[i];
+                if (!t->active || t->requestor != event.xproperty.window ||
+                    t->property != event.xproperty.atom) {
+                    continue;
+                }
+
+                // The requestor deleted the property, send the next chunk.
+                // A zero-length chunk indicates the end of the transfer.
+                size_t n = ns[t->target];
+                size_t m = n - t->offset;
+                if (m > chunk) {
+                    m = chunk;
+                }
+                (*P_XChangeProperty)(d, t->requestor, t->property,
+                    targets[t->target], formats[t->target], PropModeReplace,
+                    bufs[t->target] + t->offset, m * 8 / formats[t->target]);
+                t->offset += m;
+                if (m == 0) {
+                    t->active = 0;
+                    served |= t->target < once;
+                    // Keep watching the requestor for its other transfers.
+                    int busy = 0;
+                    for (int j = 0; j < MAX_INCR; j++) {
+                        if (incrs[j].active && incrs[j].requestor == t->requestor) {
+                            busy = 1;
+                            break;
+                        }
+                    }
+                    if (!busy) {
+                        (*P_XSelectInput)(d, t->requestor, NoEventMask);
+                    }
+                }
+                break;
+            }
+            break;
         case SelectionRequest:
             if (event.xselectionrequest.selection != sel) {
                 break;
@@ -161,20 +608,62 @@ int clipboard_write(char *typ, unsigned char *buf, size_t n, uintptr_t handle) {
             ev.target    = xsr->target;
             ev.property  = xsr->property;
 
-            if (ev.target == atomString && ev.target == target) {
-                R = (*P_XChangeProperty)(ev.display, ev.requestor, ev.property,
-                    atomString, 8, PropModeReplace, buf, n);
-            } else if (ev.target == atomImage && ev.target == target) {
+            int target = -1;
+            for (int i = 0; i < count; i++) {
+                if (ev.target == targets[i]) {
+                    target = i;
+                    break;
+                }
+            }
+            if (target >= 0 && !render_target(render, bufs, ns, target)) {
+                // The conversion failed, refuse the request.
+                target = -1;
+            }
+
+            if (target >= 0 && ns[target] > chunk) {
+                struct incr *t = NULL;
+                for (int i = 0; i < MAX_INCR; i++) {
+                    if (!incrs[i].active) {
+                        t = &incrs[i];
+                        break;
+                    }
+                }
+                if (t == NULL) {
+                    // Too many concurrent transfers, refuse the request.
+                    ev.property = None;
+                } else {
+                    t->requestor = ev.requestor;
+                    t->property  = ev.property;
+                    t->target    = target;
+                    t->offset    = 0;
+                    t->active    = 1;
+
+                    // Watch for property deletions of the requestor, and
+                    // announce the lower bound of the size of the data.
+                    long size = (long)ns[target];
+                    (*P_XSelectInput)(d, ev.requestor, PropertyChangeMask);
+                    R = (*P_XChangeProperty)(ev.display, ev.requestor, ev.property,
+                        incrAtom, 32, PropModeReplace, (unsigned char *)&size, 1);
+                }
+            } else if (target >= 0) {
                 R = (*P_XChangeProperty)(ev.display, ev.requestor, ev.property,
-                    atomImage, 8, PropModeReplace, buf, n);
+                    targets[target], formats[target], PropModeReplace,
+                    bufs[target], ns[target] * 8 / formats[target]);
+                served |= target < once;
             } else if (ev.target == targetsAtom) {
-                // Reply atoms for supported targets, other clients should
+                // Reply atoms for the offered targets, other clients should
                 // request the clipboard again and obtain the data if their
                 // implementation is correct.
-                Atom targets[] = { atomString, atomImage };
                 R = (*P_XChangeProperty)(ev.display, ev.requestor, ev.property,
                     XA_ATOM, 32, PropModeReplace,
-                    (unsigned char *)&targets, sizeof(targets)/sizeof(Atom));
+                    (unsigned char *)targets, count + 1);
+            } else if (ev.target == multiAtom && ev.property != None) {
+                int i = serve_multiple(d, ev.requestor, ev.property,
+                    targets, formats, bufs, ns, count, chunk, render);
+                if (i < 0) {
+                    ev.property = None;
+                }
+                served |= i >= 0 && i < once;
             } else {
                 ev.property = None;
             }
@@ -182,9 +671,49 @@ int clipboard_write(char *typ, unsigned char *buf, size_t n, uintptr_t handle) {
             if ((R & 2) == 0) (*P_XSendEvent)(d, ev.requestor, 0, 0, (XEvent *)&ev);
             break;
         }
+
+        if (served) {
+            // The data has been pasted once, clear the clipboard.
+            (*P_XSetSelectionOwner)(d, sel, None, CurrentTime);
+            free(targets);
+            free(formats);
+            close_display(d, w);
+            return 0;
+        }
     }
 }
 
+// clipboard_serviceable checks whether the owner of the clipboard selection
+// is serving requests, by requesting the TARGETS of the selection. It
+// returns 0 if the owner responded within timeout milliseconds.
+int clipboard_serviceable(long timeout) {
+	if (!initX11()) {
+		return -1;
+	}
+
+    Display* d = open_display();
+    if (d == NULL) {
+        return -1;
+    }
+    watch_errors(d);
+
+    Window w = (*P_XCreateSimpleWindow)(d, (*P_XDefaultRootWindow)(d), 0, 0, 1, 1, 0, 0, 0);
+    Atom sel     = (*P_XInternAtom)(d, "CLIPBOARD", False);
+    Atom prop    = (*P_XInternAtom)(d, "GOLANG_DESIGN_DATA", False);
+    Atom targets = (*P_XInternAtom)(d, "TARGETS", False);
+
+    (*P_XConvertSelection)(d, sel, targets, prop, w, CurrentTime);
+    XEvent event;
+    int ret = -2;
+    if (wait_event(d, w, SelectionNotify, &event, timeout, -1) > 0 &&
+        event.xselection.property != None) {
+        (*P_XDeleteProperty)(d, w, prop);
+        ret = 0;
+    }
+    close_display(d, w);
+    return ret;
+}
+
 // read_data reads the property of a selection if the target atom matches
 // the actual atom.
 unsigned long read_data(XSelectionEvent *sev, Atom sel, Atom prop, Atom target, char **buf) {
@@ -207,57 +736,428 @@ unsigned long read_data(XSelectionEvent *sev, Atom sel, Atom prop, Atom target,
         return 0;
     }
 
+    // Items of format 16 and 32 are stored as shorts and longs on the
+    // client side.
+    size_t unit = sizeof(char);
+    if (format == 16) {
+        unit = sizeof(short);
+    } else if (format == 32) {
+        unit = sizeof(long);
+    }
     if (actual == target && buf != NULL) {
-        *buf = (char *)malloc(size * sizeof(char));
-        memcpy(*buf, data, size*sizeof(char));
+        *buf = (char *)malloc(size * unit);
+        memcpy(*buf, data, size * unit);
     }
     (*P_XFree)(data);
     (*P_XDeleteProperty)(sev->display, sev->requestor, sev->property);
-    return size * sizeof(char);
+    return size * unit;
 }
 
-// clipboard_read reads the clipboard selection in given format typ.
-// the readed bytes is written into buf and returns the size of the buffer.
+// is_incr reports whether the owner announced an incremental transfer of
+// the requested data by the INCR type of the property.
+static int is_incr(Display *d, XSelectionEvent *sev, Atom sel, Atom prop) {
+    if (sev->property == None || sev->selection != sel || sev->property != prop) {
+        return 0;
+    }
+    unsigned char *data = NULL;
+    Atom actual;
+    int format;
+    unsigned long n = 0, after = 0;
+    int ret = (*P_XGetWindowProperty)(d, sev->requestor, prop, 0L, 0L, 0,
+        AnyPropertyType, &actual, &format, &n, &after, &data);
+    if (data != NULL) {
+        (*P_XFree)(data);
+    }
+    return ret == Success && actual == (*P_XInternAtom)(d, "INCR", False);
+}
+
+// read_incr receives the data of an incremental transfer that the owner
+// announced by the INCR type of the property, see:
+// https://www.x.org/releases/X11R7.6/doc/xorg-docs/specs/ICCCM/icccm.html#incr_properties
+// The requestor deletes the property to start the transfer and after each
+// chunk, and the owner appends the data chunk by chunk, which ends with
+// a zero-length chunk. The timeout applies to the wait for each chunk.
+// It returns the size of the data, or -3 or -4 if the transfer timed out
+// or is canceled.
+static long read_incr(Display *d, Window w, Atom prop, Atom target, char **buf, long timeout, int cancel) {
+    char *out = NULL;
+    size_t size = 0;
+    // The owner set the INCR property before it notified the conversion,
+    // drop the stale notifications so that they are not taken as the
+    // first chunk once the property is deleted.
+    XEvent stale;
+    while ((*P_XCheckTypedWindowEvent)(d, w, PropertyNotify, &stale)) {
+    }
+    (*P_XDeleteProperty)(d, w, prop);
+    for (;;) {
+        XEvent event;
+        int ok = wait_event(d, w, PropertyNotify, &event, timeout, cancel);
+        if (ok <= 0) {
+            free(out);
+            return ok == 0 ? -3 : -4;
+        }
+        if (event.xproperty.atom != prop || event.xproperty.state != PropertyNewValue) {
+            continue;
+        }
+
+        unsigned char *data = NULL;
+        Atom actual;
+        int format;
+        unsigned long n = 0, after = 0;
+        int ret = (*P_XGetWindowProperty)(d, w, prop, 0L, (~0L), True,
+            AnyPropertyType, &actual, &format, &n, &after, &data);
+        if (ret != Success || err_code != 0) {
+            free(out);
+            return 0;
+        }
+        if (n == 0) {
+            if (data != NULL) {
+                (*P_XFree)(data);
+            }
+            if (actual == None) {
+                // The property is gone, wait for the next chunk.
+                continue;
+            }
+            // The zero-length chunk ends the transfer.
+            break;
+        }
+
+        size_t unit = sizeof(char);
+        if (format == 16) {
+            unit = sizeof(short);
+        } else if (format == 32) {
+            unit = sizeof(long);
+        }
+        if (actual == target) {
+            out = (char *)realloc(out, size + n * unit);
+            memcpy(out + size, data, n * unit);
+            size += n * unit;
+        }
+        (*P_XFree)(data);
+    }
+    if (out == NULL) {
+        return 0;
+    }
+    *buf = out;
+    return size;
+}
+
+// clipboard_read reads the given selection, such as CLIPBOARD, in given
+// format typ. the readed bytes is written into buf and returns the size
+// of the buffer.
+// The read gives up if the selection owner does not respond within
+// timeout milliseconds, or once the cancel file descriptor becomes
+// readable, and returns -3 or -4 respectively. If an X protocol error
+// fails the read, -5 is returned and the error code is written to xerr.
+// Data that the owner transfers incrementally using the INCR mechanism
+// is received chunk by chunk.
 //
 // The caller of this function should responsible for the free of the buf.
-unsigned long clipboard_read(char* typ, char **buf) {
+unsigned long clipboard_read(char *selection, char* typ, char **buf, long timeout, int cancel, int *xerr) {
 	if (!initX11()) {
 		return -1;
 	}
 
-    Display* d = NULL;
-    for (int i = 0; i < 42; i++) {
-        d = (*P_XOpenDisplay)(0);
-        if (d == NULL) {
-            continue;
-        }
-        break;
-    }
+    Display* d = open_display();
     if (d == NULL) {
         return -1;
     }
+    watch_errors(d);
 
     Window w = (*P_XCreateSimpleWindow)(d, (*P_XDefaultRootWindow)(d), 0, 0, 1, 1, 0, 0, 0);
 
     // Use False because these may not available for the first time.
-    Atom sel  = (*P_XInternAtom)(d, "CLIPBOARD", False);
+    Atom sel  = (*P_XInternAtom)(d, selection, False);
     Atom prop = (*P_XInternAtom)(d, "GOLANG_DESIGN_DATA", False);
 
     // Use True to makesure the requested type is a valid type.
     Atom target = (*P_XInternAtom)(d, typ, True);
     if (target == None) {
-        (*P_XCloseDisplay)(d);
+        close_display(d, w);
         return -2;
     }
 
+    // Watch the property for incremental transfers.
+    (*P_XSelectInput)(d, w, PropertyChangeMask);
     (*P_XConvertSelection)(d, sel, target, prop, w, CurrentTime);
     XEvent event;
-    for (;;) {
-        (*P_XNextEvent)(d, &event);
-        if (event.type != SelectionNotify) continue;
-        break;
+    int ok = wait_event(d, w, SelectionNotify, &event, timeout, cancel);
+    if (ok <= 0) {
+        close_display(d, w);
+        return ok == 0 ? -3 : -4;
     }
-    unsigned long n = read_data((XSelectionEvent *)&event.xselection, sel, prop, target, buf);
-    (*P_XCloseDisplay)(d);
+
+    unsigned long n;
+    if (is_incr(d, (XSelectionEvent *)&event.xselection, sel, prop)) {
+        long m = read_incr(d, w, prop, target, buf, timeout, cancel);
+        if (m < 0) {
+            close_display(d, w);
+            return m;
+        }
+        n = m;
+    } else {
+        n = read_data((XSelectionEvent *)&event.xselection, sel, prop, target, buf);
+    }
+    if (err_code != 0) {
+        // The owner may have destroyed the property or its window
+        // during the transfer.
+        *xerr = err_code;
+        free(*buf);
+        *buf = NULL;
+        close_display(d, w);
+        return -5;
+    }
+    close_display(d, w);
     return n;
 }
+
+// clipboard_read_cut_buffer reads the Latin-1 encoded text of the cut
+// buffer CUT_BUFFER0 of the root window, which legacy applications, such
+// as xterm, store text in, if the given selection has no owner. It
+// returns -2 if the selection has an owner, and 0 if the cut buffer holds
+// no text.
+//
+// The caller of this function should responsible for the free of the buf.
+long clipboard_read_cut_buffer(char *selection, char **buf) {
+	if (!initX11()) {
+		return -1;
+	}
+
+    Display* d = open_display();
+    if (d == NULL) {
+        return -1;
+    }
+    watch_errors(d);
+
+    Atom sel = (*P_XInternAtom)(d, selection, False);
+    if ((*P_XGetSelectionOwner)(d, sel) != None) {
+        close_display(d, None);
+        return -2;
+    }
+
+    unsigned char *data = NULL;
+    Atom actual;
+    int format;
+    unsigned long n = 0, after = 0;
+    int ret = (*P_XGetWindowProperty)(d, (*P_XDefaultRootWindow)(d), XA_CUT_BUFFER0,
+        0L, (~0L), 0, XA_STRING, &actual, &format, &n, &after, &data);
+    if (ret != Success || actual != XA_STRING || format != 8 || n == 0) {
+        if (data != NULL) {
+            (*P_XFree)(data);
+        }
+        close_display(d, None);
+        return 0;
+    }
+    *buf = (char *)malloc(n);
+    memcpy(*buf, data, n);
+    (*P_XFree)(data);
+    close_display(d, None);
+    return n;
+}
+
+// clipboard_targets requests the TARGETS of the clipboard selection, which
+// lists the available formats without transferring the data. The names
+// of the targets are written into buf separated by newlines, and the size
+// of buf is returned. Like clipboard_read, the request can be canceled
+// using the cancel file descriptor, and X protocol errors are reported
+// using xerr.
+//
+// The caller of this function should responsible for the free of the buf.
+unsigned long clipboard_targets(char **buf, long timeout, int cancel, int *xerr) {
+	if (!initX11()) {
+		return -1;
+	}
+
+    Display* d = open_display();
+    if (d == NULL) {
+        return -1;
+    }
+    watch_errors(d);
+
+    Window w = (*P_XCreateSimpleWindow)(d, (*P_XDefaultRootWindow)(d), 0, 0, 1, 1, 0, 0, 0);
+    Atom sel     = (*P_XInternAtom)(d, "CLIPBOARD", False);
+    Atom prop    = (*P_XInternAtom)(d, "GOLANG_DESIGN_DATA", False);
+    Atom targets = (*P_XInternAtom)(d, "TARGETS", False);
+
+    (*P_XConvertSelection)(d, sel, targets, prop, w, CurrentTime);
+    XEvent event;
+    int ok = wait_event(d, w, SelectionNotify, &event, timeout, cancel);
+    if (ok <= 0) {
+        close_display(d, w);
+        return ok == 0 ? -3 : -4;
+    }
+    if (event.xselection.property != prop) {
+        // There is no owner of the selection, or the owner refused.
+        close_display(d, w);
+        return 0;
+    }
+
+    unsigned char *data;
+    Atom actual;
+    int format;
+    unsigned long n = 0, after = 0;
+    int ret = (*P_XGetWindowProperty)(d, w, prop, 0L, (~0L), 0, AnyPropertyType,
+        &actual, &format, &n, &after, &data);
+    if (ret != Success) {
+        close_display(d, w);
+        if (err_code != 0) {
+            *xerr = err_code;
+            return -5;
+        }
+        return 0;
+    }
+    if (actual != XA_ATOM || format != 32) {
+        (*P_XFree)(data);
+        close_display(d, w);
+        return 0;
+    }
+
+    // Atoms of format 32 are stored as longs on the client side.
+    Atom *atoms = (Atom *)data;
+    size_t size = 0;
+    char *names = NULL;
+    for (unsigned long i = 0; i < n; i++) {
+        // Invalid atoms of a racing owner fail with BadAtom, which
+        // are skipped.
+        char *name = (*P_XGetAtomName)(d, atoms[i]);
+        if (name == NULL) {
+            continue;
+        }
+        size_t m = strlen(name);
+        names = (char *)realloc(names, size + m + 1);
+        memcpy(names + size, name, m);
+        names[size + m] = '\n';
+        size += m + 1;
+        (*P_XFree)(name);
+    }
+    (*P_XFree)(data);
+    (*P_XDeleteProperty)(d, w, prop);
+    close_display(d, w);
+    *buf = names;
+    return size;
+}
+
+// Declarations of the used XFixes API, which are not part of the X11
+// development package, see:
+// https://gitlab.freedesktop.org/xorg/lib/libxfixes/-/blob/master/include/X11/extensions/Xfixes.h
+enum {
+    XFixesSelectionNotify                   = 0,
+    XFixesSetSelectionOwnerNotifyMask       = 1L << 0,
+    XFixesSelectionWindowDestroyNotifyMask  = 1L << 1,
+    XFixesSelectionClientCloseNotifyMask    = 1L << 2,
+};
+
+void *libXfixes;
+
+Bool (*P_XFixesQueryExtension)(Display*, int*, int*);
+void (*P_XFixesSelectSelectionInput)(Display*, Window, Atom, unsigned long);
+
+int initXfixes() {
+	if (libXfixes) {
+		return 1;
+	}
+	libXfixes = dlopen("libXfixes.so.3", RTLD_LAZY);
+	if (!libXfixes) {
+		return 0;
+	}
+	P_XFixesQueryExtension = (Bool (*)(Display*, int*, int*)) dlsym(libXfixes, "XFixesQueryExtension");
+	P_XFixesSelectSelectionInput = (void (*)(Display*, Window, Atom, unsigned long)) dlsym(libXfixes, "XFixesSelectSelectionInput");
+	if (!P_XFixesQueryExtension || !P_XFixesSelectSelectionInput) {
+		dlclose(libXfixes);
+		libXfixes = NULL;
+		return 0;
+	}
+	return 1;
+}
+
+// owner_display is the connection that receives the XFixes events of
+// the changes of the owner of the clipboard selection, and owner_changes
+// counts the received events.
+static Display *owner_display = NULL;
+static int owner_unsupported = 0;
+static int owner_event_base;
+static unsigned long owner_changes = 0;
+
+// connection_lost reports whether the X server has closed the connection
+// of the given display, for instance, the X server has restarted. It
+// peeks the socket without involving Xlib, as Xlib terminates the process
+// on a lost connection.
+static int connection_lost(Display *d) {
+    int fd = (*P_XConnectionNumber)(d);
+    fd_set fds;
+    FD_ZERO(&fds);
+    FD_SET(fd, &fds);
+    struct timeval tv = {0, 0};
+    if (select(fd + 1, &fds, NULL, NULL, &tv) <= 0) {
+        return 0;
+    }
+    char c;
+    ssize_t n = recv(fd, &c, 1, MSG_PEEK | MSG_DONTWAIT);
+    if (n == 0) {
+        return 1;
+    }
+    return n < 0 && errno != EAGAIN && errno != EWOULDBLOCK && errno != EINTR;
+}
+
+// clipboard_owner_changes returns the number of changes of the owner of
+// the clipboard selection since the first call, or -1 if the X server or
+// the client lacks XFixes. Every write of the clipboard takes over the
+// ownership, hence the count changes whenever the clipboard data changes.
+// If the connection is lost, -1 is returned until the X server can be
+// connected again, and the count changes on reconnection, as the
+// clipboard may have changed meanwhile. The caller must serialize the
+// calls.
+long clipboard_owner_changes() {
+	if (!initX11()) {
+		return -1;
+	}
+
+    if (owner_unsupported) {
+        return -1;
+    }
+    if (owner_display != NULL && connection_lost(owner_display)) {
+        // Closing the display would flush the dead connection, which
+        // invokes the fatal I/O error handler of Xlib, hence only the
+        // socket is closed and the display is abandoned.
+        close((*P_XConnectionNumber)(owner_display));
+        owner_display = NULL;
+        owner_changes++;
+    }
+    if (owner_display == NULL) {
+        if (!initXfixes()) {
+            owner_unsupported = 1;
+            return -1;
+        }
+        // The events are received using a separate connection, as they
+        // cannot be shared with the event loop of the host.
+        Display *d = (*P_XOpenDisplay)(display_name);
+        if (d == NULL) {
+            return -1;
+        }
+        int event_base, error_base;
+        if (!(*P_XFixesQueryExtension)(d, &event_base, &error_base)) {
+            (*P_XCloseDisplay)(d);
+            owner_unsupported = 1;
+            return -1;
+        }
+        Atom sel = (*P_XInternAtom)(d, "CLIPBOARD", False);
+        (*P_XFixesSelectSelectionInput)(d, (*P_XDefaultRootWindow)(d), sel,
+            XFixesSetSelectionOwnerNotifyMask |
+            XFixesSelectionWindowDestroyNotifyMask |
+            XFixesSelectionClientCloseNotifyMask);
+        owner_display    = d;
+        owner_event_base = event_base;
+    }
+
+    // The round trip makes sure the events of the changes that happened
+    // before the call have arrived.
+    (*P_XSync)(owner_display, False);
+    XEvent event;
+    while ((*P_XPending)(owner_display) > 0) {
+        (*P_XNextEvent)(owner_display, &event);
+        if (event.type == owner_event_base + XFixesSelectionNotify) {
+            owner_changes++;
+        }
+    }
+    return (long)owner_changes;
+}
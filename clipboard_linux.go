@@ -15,25 +15,44 @@ package clipboard
 #include <stdint.h>
 #include <string.h>
 
+void clipboard_set_display(const char *name);
+void clipboard_set_shared_display(void *d);
+void clipboard_use_display(const char *name);
 int clipboard_test();
+long clipboard_latency();
+void *clipboard_open();
+void clipboard_close(void *d);
 int clipboard_write(
-	char*          typ,
-	unsigned char* buf,
-	size_t         n,
-	uintptr_t      handle
+	char**          typs,
+	unsigned char** bufs,
+	size_t*         ns,
+	int             count,
+	size_t          chunk,
+	int             once,
+	int             save,
+	uintptr_t       handle,
+	uintptr_t       render
 );
-unsigned long clipboard_read(char* typ, char **out);
+unsigned long clipboard_read(char *selection, char* typ, char **out, long timeout, int cancel, int *xerr);
+long clipboard_read_cut_buffer(char *selection, char **out);
+unsigned long clipboard_targets(char **out, long timeout, int cancel, int *xerr);
+int clipboard_serviceable(long timeout);
+long clipboard_owner_changes();
 */
 import "C"
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"image/color"
 	"os"
 	"runtime"
-	"runtime/cgo"
+	"strings"
+	"sync"
 	"time"
 	"unsafe"
+
+	"golang.design/x/clipboard/formats"
 )
 
 var helpmsg = `%w: Failed to initialize the X11 display, and the clipboard package
@@ -54,32 +73,198 @@ and initialize a virtual frame buffer:
 Then this package should be ready to use.
 `
 
-func initialize() error {
+func initialize(c config) error {
+	switch c.backend {
+	case BackendAuto, BackendX11:
+	default:
+		return fmt.Errorf("%w: %v backend", ErrUnsupported, c.backend)
+	}
+
+	display := os.Getenv("DISPLAY")
+	if c.display != "" {
+		display = c.display
+		cs := C.CString(display)
+		C.clipboard_set_display(cs)
+		C.free(unsafe.Pointer(cs))
+	}
+	if c.xdisplay != nil {
+		C.clipboard_set_shared_display(c.xdisplay)
+	}
+
 	ok := C.clipboard_test()
 	if ok != 0 {
-		return fmt.Errorf(helpmsg, errUnavailable)
+		return fmt.Errorf(helpmsg, ErrUnavailable)
 	}
+
+	persist = c.persist
+
+	latency := time.Duration(C.clipboard_latency()) * time.Microsecond
+	tu := tuneFor(display, latency)
+	if c.readTimeout >= 0 {
+		tu.ReadTimeout = c.readTimeout
+	}
+	SetTuning(tu)
 	return nil
 }
 
-func read(t Format) (buf []byte, err error) {
+// target returns the selection target of a given format.
+func target(t Format) string {
 	switch t {
 	case FmtText:
-		return readc("UTF8_STRING")
+		return "UTF8_STRING"
 	case FmtImage:
-		return readc("image/png")
+		return "image/png"
+	case FmtHTML:
+		return "text/html"
+	case FmtRTF:
+		return "text/rtf"
+	case FmtFiles:
+		return "text/uri-list"
+	case FmtURL:
+		return "text/x-moz-url"
+	case FmtColor:
+		return "application/x-color"
+	case FmtVCard:
+		return "text/vcard"
+	case FmtICal:
+		return "text/calendar"
 	}
-	return nil, errUnsupported
+	return ""
 }
 
-func readc(t string) ([]byte, error) {
+// targetXVCard is the legacy target of vCards, which contact managers,
+// such as Evolution, still offer and request.
+const targetXVCard = "text/x-vcard"
+
+func read(t Format) (buf []byte, err error) { return readFrom(selClipboard, t) }
+
+// X selections, see ReadSelection.
+const (
+	selClipboard = "CLIPBOARD"
+	selPrimary   = "PRIMARY"
+	selSecondary = "SECONDARY"
+)
+
+// readSelection reads the data in format t of the given selection. Text
+// falls back to the cut buffer if the selection has no owner, see
+// ReadSelection.
+func readSelection(s Selection, t Format) ([]byte, error) {
+	sel := selClipboard
+	switch s {
+	case SelectionPrimary:
+		sel = selPrimary
+	case SelectionSecondary:
+		sel = selSecondary
+	}
+	buf, err := readFrom(sel, t)
+	if t == FmtText && (err == ErrUnavailable || (err == nil && buf == nil)) {
+		if b, ok := readCutBuffer(sel); ok {
+			return b, nil
+		}
+	}
+	return buf, err
+}
+
+// readFrom reads the data in format t of the given selection.
+func readFrom(sel string, t Format) (buf []byte, err error) {
+	readc := func(typ string) ([]byte, error) { return readTarget(sel, typ) }
+	if t == FmtImageRaw {
+		return readImageRaw(readc)
+	}
+	typ := target(t)
+	if typ == "" {
+		return nil, ErrUnsupported
+	}
+	buf, err = readc(typ)
+	if err == ErrTimeout || err == errDisplay {
+		// The owner is unresponsive, or the X server is gone, reading
+		// other targets would only wait longer.
+		return nil, err
+	}
+	if err == nil && buf != nil {
+		switch t {
+		case FmtHTML:
+			buf = formats.DecodeHTML(buf)
+		case FmtFiles:
+			buf = joinFiles(formats.DecodeURIList(buf))
+		case FmtURL:
+			buf = decodeURL16(buf)
+		case FmtColor:
+			return fromXColor(buf)
+		}
+		return buf, nil
+	}
+	if t == FmtText {
+		// Legacy applications may only offer Latin-1 encoded text.
+		if b, serr := readc("STRING"); serr == nil && b != nil {
+			return decodeLatin1(b), nil
+		}
+	}
+	if t == FmtVCard {
+		if b, verr := readc(targetXVCard); verr == nil && b != nil {
+			return b, nil
+		}
+	}
+	if t == FmtURL {
+		if b, terr := readc(target(FmtText)); terr == nil {
+			if u := textURL(b); u != nil {
+				return u, nil
+			}
+		}
+	}
+
+	// The selection owner may only offer other representations,
+	// X11 targets are MIME types in most cases, try converting them.
+	if b, nerr := negotiate(mimeOf(t), readc); nerr == nil {
+		return b, nil
+	}
+	return buf, err
+}
+
+// readImageRaw reads the image of the selection as PNG, or any other
+// image type that the owner offers without converting it.
+func readImageRaw(readc func(string) ([]byte, error)) ([]byte, error) {
+	for _, typ := range append([]string{mimePNG}, convertible(mimePNG)...) {
+		buf, err := readc(typ)
+		if err == ErrTimeout {
+			return nil, err
+		}
+		if err == nil && len(buf) > 0 {
+			return buf, nil
+		}
+	}
+	return nil, ErrUnavailable
+}
+
+// readData reads the clipboard data of the target of a given MIME type.
+func readData(mime string) ([]byte, error) { return readc(mime) }
+
+func readc(t string) ([]byte, error) { return readTarget(selClipboard, t) }
+
+// readTarget reads the data of the target of the given selection.
+func readTarget(sel, t string) ([]byte, error) {
+	cs := C.CString(sel)
+	defer C.free(unsafe.Pointer(cs))
 	ct := C.CString(t)
 	defer C.free(unsafe.Pointer(ct))
 
-	var data *C.char
-	n := C.clipboard_read(ct, &data)
+	timeout := Tuning().ReadTimeout.Milliseconds()
+
+	var (
+		data *C.char
+		xerr C.int
+	)
+	n := C.clipboard_read(cs, ct, &data, C.long(timeout), cancelFD, &xerr)
 	if data == nil {
-		return nil, errUnavailable
+		switch C.long(n) {
+		case -1:
+			return nil, errDisplay
+		case -3:
+			return nil, ErrTimeout
+		case -5:
+			return nil, xError(xerr)
+		}
+		return nil, ErrUnavailable
 	}
 	defer C.free(unsafe.Pointer(data))
 	switch {
@@ -90,77 +275,601 @@ func readc(t string) ([]byte, error) {
 	}
 }
 
+// readCutBuffer reads the text of the cut buffer CUT_BUFFER0 if the given
+// selection has no owner.
+func readCutBuffer(sel string) ([]byte, bool) {
+	cs := C.CString(sel)
+	defer C.free(unsafe.Pointer(cs))
+
+	var data *C.char
+	n := C.clipboard_read_cut_buffer(cs, &data)
+	if data == nil || n <= 0 {
+		return nil, false
+	}
+	defer C.free(unsafe.Pointer(data))
+	return decodeLatin1(C.GoBytes(unsafe.Pointer(data), C.int(n))), true
+}
+
+func has(t Format) bool { return prober()(t) }
+
+// prober returns a function that reports whether the clipboard holds
+// data in a format. The targets of the selection owner are requested
+// once and shared by all probes.
+func prober() func(Format) bool {
+	avail, err := targets()
+	if err != nil {
+		if debug {
+			fmt.Fprintf(os.Stderr, "read clipboard targets err: %v\n", err)
+		}
+		return func(Format) bool { return false }
+	}
+	return func(t Format) bool { return offers(avail, t) }
+}
+
+// offers reports whether the given targets offer data in format t.
+func offers(avail map[string]bool, t Format) bool {
+	if t == FmtImageRaw {
+		t = FmtImage
+	}
+	typ := target(t)
+	if typ == "" {
+		return false
+	}
+	if avail[typ] || (t == FmtText && avail["STRING"]) || (t == FmtVCard && avail[targetXVCard]) {
+		return true
+	}
+	for _, from := range convertible(mimeOf(t)) {
+		if avail[from] {
+			return true
+		}
+	}
+	return false
+}
+
+// targets returns the targets that the selection owner offers.
+func targets() (map[string]bool, error) {
+	timeout := Tuning().ReadTimeout.Milliseconds()
+
+	var (
+		data *C.char
+		xerr C.int
+	)
+	n := C.clipboard_targets(&data, C.long(timeout), cancelFD, &xerr)
+	if data == nil {
+		switch C.long(n) {
+		case 0:
+			return nil, nil
+		case -3:
+			return nil, ErrTimeout
+		case -5:
+			return nil, xError(xerr)
+		}
+		return nil, ErrUnavailable
+	}
+	defer C.free(unsafe.Pointer(data))
+
+	avail := map[string]bool{}
+	for _, name := range strings.Split(C.GoStringN(data, C.int(n)), "\n") {
+		if name != "" {
+			avail[name] = true
+		}
+	}
+	return avail, nil
+}
+
+// xErrorNames are the names of the X protocol errors that racing
+// selection owners cause in most cases.
+var xErrorNames = map[C.int]string{
+	3:  "BadWindow",
+	5:  "BadAtom",
+	8:  "BadMatch",
+	11: "BadAlloc",
+}
+
+// errDisplay indicates the X server cannot be connected during a
+// clipboard operation, for instance, the X server has exited.
+var errDisplay = fmt.Errorf("%w: cannot connect to the X server", ErrUnavailable)
+
+// xError returns the error of an X protocol error code that failed a
+// clipboard operation.
+func xError(code C.int) error {
+	name, ok := xErrorNames[code]
+	if !ok {
+		name = fmt.Sprintf("error code %d", int(code))
+	}
+	return fmt.Errorf("%w: X protocol error: %s", ErrUnavailable, name)
+}
+
+// xColor returns the application/x-color data of the given color, which
+// is the red, green, blue and alpha components as unsigned shorts in the
+// byte order of the client.
+func xColor(c color.NRGBA64) []byte {
+	x := [4]C.ushort{C.ushort(c.R), C.ushort(c.G), C.ushort(c.B), C.ushort(c.A)}
+	return C.GoBytes(unsafe.Pointer(&x[0]), C.int(unsafe.Sizeof(x)))
+}
+
+// fromXColor returns the data of FmtColor of the given application/x-color
+// data.
+func fromXColor(buf []byte) ([]byte, error) {
+	var x [4]C.ushort
+	if len(buf) != int(unsafe.Sizeof(x)) {
+		return nil, &MalformedError{Format: FmtColor, Reason: "invalid application/x-color size"}
+	}
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&x[0])), len(buf)), buf)
+	return encodeColor(color.NRGBA64{R: uint16(x[0]), G: uint16(x[1]), B: uint16(x[2]), A: uint16(x[3])}), nil
+}
+
+func imageInfo() (int, int, string, error) { return readImageInfo(readData) }
+
 // write writes the given data to clipboard and
 // returns true if success or false if failed.
-func write(t Format, buf []byte) (<-chan struct{}, error) {
-	var s string
+func write(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	reps, err := representations(t, buf)
+	if err != nil {
+		return nil, err
+	}
+	return writeReps(append(reps, extra...), false)
+}
+
+// writeOnce is like write but gives up the ownership of the clipboard
+// selection once the data has been transferred to a requestor.
+func writeOnce(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	reps, err := representations(t, buf)
+	if err != nil {
+		return nil, err
+	}
+	return writeReps(append(reps, extra...), true)
+}
+
+// representations returns the targets that offer the given data of
+// format t.
+func representations(t Format, buf []byte) ([]representation, error) {
+	s := target(t)
+	if t == FmtImageRaw {
+		s = sniffImage(buf)
+	}
+	if s == "" {
+		return nil, ErrUnsupported
+	}
+	reps := []representation{{mime: s, data: buf}}
 	switch t {
-	case FmtText:
-		s = "UTF8_STRING"
-	case FmtImage:
-		s = "image/png"
+	case FmtFiles:
+		// GNOME based file managers only paste files that are offered
+		// in their own target.
+		reps = []representation{
+			{mime: s, data: formats.EncodeURIList(splitFiles(buf))},
+			{mime: "x-special/gnome-copied-files", data: formats.EncodeGNOMEFiles(splitFiles(buf))},
+		}
+	case FmtURL:
+		reps = []representation{
+			{mime: s, data: formats.EncodeUTF16(string(buf))},
+			{mime: target(FmtText), data: buf},
+		}
+	case FmtColor:
+		c, err := decodeColor(buf)
+		if err != nil {
+			return nil, err
+		}
+		reps = []representation{{mime: s, data: xColor(c)}}
+	case FmtVCard:
+		reps = append(reps, representation{mime: targetXVCard, data: buf})
+	case FmtImage, FmtImageRaw:
+		if s == mimePNG {
+			// Applications such as LibreOffice request images in
+			// other encodings than PNG.
+			reps = append(reps, conversions(buf, mimePNG, mimeBMP, mimeJPEG)...)
+		}
+	}
+	return reps, nil
+}
+
+// conversions returns the representations that offer the given data of
+// MIME type from in the given MIME types, which are converted once a
+// requestor asks for them.
+func conversions(buf []byte, from string, to ...string) []representation {
+	reps := make([]representation, 0, len(to))
+	for _, mime := range to {
+		mime := mime
+		reps = append(reps, representation{mime: mime, render: func() ([]byte, error) {
+			return Convert(from, mime, buf)
+		}})
+	}
+	return reps
+}
+
+// textTargets returns the representations that offer the text among the
+// given representations in the text targets that are not offered yet,
+// which are the targets that applications request text as. Legacy
+// applications, such as xterm, request the Latin-1 encoded STRING.
+func textTargets(reps []representation) []representation {
+	offered := map[string]bool{}
+	var text []byte
+	found := false
+	for _, r := range reps {
+		offered[r.mime] = true
+		if !found && r.render == nil && (r.mime == mimeText || r.mime == target(FmtText)) {
+			text, found = r.data, true
+		}
+	}
+	if !found {
+		return nil
+	}
+	var more []representation
+	if !offered[target(FmtText)] {
+		more = append(more, representation{mime: target(FmtText), data: text})
+	}
+	if !offered[mimeText] {
+		more = append(more, representation{mime: mimeText, data: text})
+	}
+	if !offered[targetString] {
+		more = append(more, representation{mime: targetString, render: func() ([]byte, error) {
+			return encodeLatin1(text), nil
+		}})
+	}
+	return more
+}
+
+// targetString is the target of Latin-1 encoded text.
+const targetString = "STRING"
+
+// writeData writes the given data to clipboard as the target of a given
+// MIME type.
+func writeData(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return writeReps(append([]representation{{mime: mime, data: buf}}, extra...), false)
+}
+
+// writeReps takes the ownership of the clipboard selection and serves
+// the given representations, where the first one is the primary data.
+// If once is true, the ownership is given up once a requestor received
+// any of the representations except the origin metadata.
+func writeReps(reps []representation, once bool) (<-chan struct{}, error) {
+	done, lost, err := writeRepsOn("", reps, once)
+	if err != nil {
+		return nil, err
+	}
+	lastLost = lost
+	return done, nil
+}
+
+// writeRepsOn is like writeReps but takes the ownership of the clipboard
+// selection of the given display, or the display of Init if it is empty.
+// It returns the channel that is signaled once the ownership terminated,
+// and the channel that is signaled if another client took it over.
+func writeRepsOn(display string, reps []representation, once bool) (done, lost <-chan struct{}, err error) {
+	reps = append(reps, textTargets(reps)...)
+	served := 0
+	if once {
+		// Move the origin metadata to the end, so that the served
+		// representations are the leading ones. Cooperating clipboard
+		// managers read the origin to skip sensitive data, which must
+		// not count as a paste.
+		data := make([]representation, 0, len(reps))
+		var meta []representation
+		for _, r := range reps {
+			if r.mime == mimeOrigin {
+				meta = append(meta, r)
+			} else {
+				data = append(data, r)
+			}
+		}
+		served = len(data)
+		reps = append(data, meta...)
 	}
 
+	save := 0
+	if persist && !once && !sensitiveData(reps) {
+		save = 1
+	}
+
+	chunk := Tuning().ChunkSize
 	start := make(chan int)
-	done := make(chan struct{}, 1)
+	terminated := make(chan struct{}, 1)
+	tookOver := make(chan struct{}, 1)
 
 	go func() { // serve as a daemon until the ownership is terminated.
 		runtime.LockOSThread()
 		defer runtime.UnlockOSThread()
+		if display != "" {
+			useDisplay(display)
+			defer useDisplay("")
+		}
 
-		cs := C.CString(s)
-		defer C.free(unsafe.Pointer(cs))
-
-		h := cgo.NewHandle(start)
-		var ok C.int
-		if len(buf) == 0 {
-			ok = C.clipboard_write(cs, nil, 0, C.uintptr_t(h))
-		} else {
-			ok = C.clipboard_write(cs, (*C.uchar)(unsafe.Pointer(&(buf[0]))), C.size_t(len(buf)), C.uintptr_t(h))
+		// Representations that are rendered on demand have no data
+		// until the C side asks renderTarget for it.
+		n := len(reps)
+		renders := make([]func() ([]byte, error), n)
+		var rh uintptr
+		for i, r := range reps {
+			renders[i] = r.render
+			if r.render != nil && rh == 0 {
+				rh = tokens.put(renders)
+			}
+		}
+		if rh != 0 {
+			defer tokens.take(rh)
 		}
-		if ok != C.int(0) {
+		// The data is copied to C memory, as it is served by C until
+		// the ownership is terminated.
+		ctyps := unsafe.Slice((**C.char)(C.malloc(C.size_t(n)*C.size_t(unsafe.Sizeof((*C.char)(nil))))), n)
+		cbufs := unsafe.Slice((**C.uchar)(C.malloc(C.size_t(n)*C.size_t(unsafe.Sizeof((*C.uchar)(nil))))), n)
+		cns := unsafe.Slice((*C.size_t)(C.malloc(C.size_t(n)*C.size_t(unsafe.Sizeof(C.size_t(0))))), n)
+		for i, r := range reps {
+			ctyps[i] = C.CString(r.mime)
+			cbufs[i], cns[i] = nil, 0
+			if r.render == nil {
+				cbufs[i] = (*C.uchar)(C.CBytes(r.data))
+				cns[i] = C.size_t(len(r.data))
+			}
+		}
+		defer func() {
+			for i := range reps {
+				C.free(unsafe.Pointer(ctyps[i]))
+				C.free(unsafe.Pointer(cbufs[i]))
+			}
+			C.free(unsafe.Pointer(&ctyps[0]))
+			C.free(unsafe.Pointer(&cbufs[0]))
+			C.free(unsafe.Pointer(&cns[0]))
+		}()
+
+		h := tokens.put(start)
+		ok := C.clipboard_write(&ctyps[0], &cbufs[0], &cns[0], C.int(n), C.size_t(chunk), C.int(served), C.int(save), C.uintptr_t(h), C.uintptr_t(rh))
+		if ok < C.int(0) {
 			fmt.Fprintf(os.Stderr, "write failed with status: %d\n", int(ok))
 		}
-		done <- struct{}{}
-		close(done)
+		if ok == C.int(1) {
+			tookOver <- struct{}{}
+			close(tookOver)
+		}
+		terminated <- struct{}{}
+		close(terminated)
 	}()
 
 	status := <-start
 	if status < 0 {
-		return nil, errUnavailable
+		return nil, nil, ErrUnavailable
 	}
-	// wait until enter event loop
-	return done, nil
+
+	// The selection is owned, make sure the owner is serving requests
+	// before returning, so that a subsequent Read observes this write.
+	timeout := Tuning().ReadTimeout.Milliseconds()
+	var serviceable C.int
+	onDisplay(display, func() { serviceable = C.clipboard_serviceable(C.long(timeout)) })
+	if serviceable != 0 {
+		return nil, nil, ErrUnavailable
+	}
+	return terminated, tookOver, nil
 }
 
-func watch(ctx context.Context, t Format) <-chan []byte {
-	recv := make(chan []byte, 1)
-	ti := time.NewTicker(time.Second)
-	last := Read(t)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				close(recv)
-				return
-			case <-ti.C:
-				b := Read(t)
-				if b == nil {
-					continue
-				}
-				if !bytes.Equal(last, b) {
-					recv <- b
-					last = b
-				}
+// useDisplay binds the connections of the calling thread to the given
+// display, or to the display of Init if it is empty. The caller must
+// lock the goroutine to its thread.
+func useDisplay(display string) {
+	if display == "" {
+		C.clipboard_use_display(nil)
+		return
+	}
+	cs := C.CString(display)
+	defer C.free(unsafe.Pointer(cs))
+	C.clipboard_use_display(cs)
+}
+
+// onDisplay calls fn with the connections of the calling goroutine bound
+// to the given display, or to the display of Init if it is empty.
+func onDisplay(display string, fn func()) {
+	if display == "" {
+		fn()
+		return
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	useDisplay(display)
+	defer useDisplay("")
+	fn()
+}
+
+// newSystem returns the clipboard functions that are bound to the display
+// of the given configuration, see New.
+func newSystem(c config) (system, error) {
+	switch c.backend {
+	case BackendAuto, BackendX11:
+	default:
+		return system{}, fmt.Errorf("%w: %v backend", ErrUnsupported, c.backend)
+	}
+	d := c.display
+	if d == "" {
+		d = os.Getenv("DISPLAY")
+	}
+	if d == "" {
+		return system{}, fmt.Errorf("%w: no display is specified", ErrUnavailable)
+	}
+	var ok C.int
+	onDisplay(d, func() { ok = C.clipboard_test() })
+	if ok != 0 {
+		return system{}, fmt.Errorf("%w: failed to connect to display %s", ErrUnavailable, d)
+	}
+
+	put := func(once bool) func(Format, []byte, []representation) (<-chan struct{}, error) {
+		return func(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+			reps, err := representations(t, buf)
+			if err != nil {
+				return nil, err
 			}
+			done, _, err := writeRepsOn(d, append(reps, extra...), once)
+			return done, err
+		}
+	}
+	return system{
+		read: func(t Format) (buf []byte, err error) {
+			onDisplay(d, func() { buf, err = read(t) })
+			return buf, err
+		},
+		readData: func(mime string) (buf []byte, err error) {
+			onDisplay(d, func() { buf, err = readData(mime) })
+			return buf, err
+		},
+		write:     put(false),
+		writeOnce: put(true),
+		writeData: func(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+			done, _, err := writeRepsOn(d, append([]representation{{mime: mime, data: buf}}, extra...), false)
+			return done, err
+		},
+	}, nil
+}
+
+// persist indicates the clipboard manager is asked to save the data of
+// the writes, see WithPersistence.
+var persist bool
+
+// sensitiveData reports whether the origin metadata among the given
+// representations marks the data as sensitive, which must not be saved
+// by the clipboard manager.
+func sensitiveData(reps []representation) bool {
+	for _, r := range reps {
+		if r.mime != mimeOrigin {
+			continue
+		}
+		var o Origin
+		if err := json.Unmarshal(r.data, &o); err != nil {
+			return true
+		}
+		return o.Sensitive
+	}
+	return false
+}
+
+// info returns the behavior of the X11 backend. The data of a write is
+// served by the owner of the selection, and transfers that exceed the
+// maximum request size of the display are incremental, hence unlimited.
+// Watch polls the owner changes that XFixes counts, if available.
+func info() Info {
+	_, ok := sequence()
+	return Info{
+		Backend:          BackendX11,
+		Protocol:         "X11 ICCCM 2.0",
+		SequenceNumber:   ok,
+		DelayedRendering: true,
+	}
+}
+
+// locked returns false, as X11 has no standard state of a locked
+// session, and screen lockers only grab the input while the selections
+// keep working.
+func locked() bool { return false }
+
+// lastLost is the channel that receives a signal once the latest write
+// loses the ownership of the clipboard selection. It is protected by the
+// lock.
+var lastLost <-chan struct{}
+
+// lost returns the channel of the latest write that receives a signal
+// once another client takes over the clipboard selection, which the X
+// server tells the owner by SelectionClear.
+func lost() <-chan struct{} {
+	return lastLost
+}
+
+// cancelFD is the file descriptor that becomes readable once the ongoing
+// read is canceled, or -1 if the read cannot be canceled. It is protected
+// by the lock.
+var cancelFD C.int = -1
+
+// cancelOn makes the reads before calling the returned stop function
+// cancelable by the given context. Canceling the context aborts an
+// ongoing conversion of the selection immediately, instead of waiting
+// for the selection owner until the read times out.
+func cancelOn(ctx context.Context) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	cancelFD = C.int(r.Fd())
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			w.Write([]byte{0})
+		case <-done:
 		}
 	}()
-	return recv
+	return func() {
+		close(done)
+		<-exited
+		cancelFD = -1
+		r.Close()
+		w.Close()
+	}
+}
+
+// ownerMu serializes the counting of the changes of the selection owner.
+var ownerMu sync.Mutex
+
+// sequence returns the number of changes of the owner of the clipboard
+// selection, which are observed using the XFixes extension, so that the
+// change detection of Watch only reads the clipboard if it changed. It
+// returns false if XFixes is not available.
+func sequence() (uint64, bool) {
+	ownerMu.Lock()
+	defer ownerMu.Unlock()
+
+	n := C.clipboard_owner_changes()
+	if n < 0 {
+		return 0, false
+	}
+	return uint64(n), true
+}
+
+func rawCall(fn func(uintptr) error) error {
+	d := C.clipboard_open()
+	if d == nil {
+		return ErrUnavailable
+	}
+	defer C.clipboard_close(d)
+	return fn(uintptr(d))
+}
+
+//export renderTarget
+func renderTarget(h C.uintptr_t, i C.int, buf **C.uchar, n *C.size_t) C.int {
+	renders, _ := tokens.get(uintptr(h)).([]func() ([]byte, error))
+	if renders == nil {
+		return 0
+	}
+	render := renders[i]
+	if render == nil {
+		// The data of the target is not rendered on demand.
+		return 1
+	}
+	b, err := render()
+	if err != nil || len(b) == 0 {
+		if debug {
+			fmt.Fprintf(os.Stderr, "failed to render the target: %v\n", err)
+		}
+		return 0
+	}
+	C.free(unsafe.Pointer(*buf))
+	*buf = (*C.uchar)(C.CBytes(b))
+	*n = C.size_t(len(b))
+	return 1
 }
 
 //export syncStatus
 func syncStatus(h uintptr, val int) {
-	v := cgo.Handle(h).Value().(chan int)
-	v <- val
-	cgo.Handle(h).Delete()
+	if v, ok := tokens.take(h).(chan int); ok {
+		v <- val
+	}
+}
+
+func announce(msg string) error {
+	return ErrUnsupported
+}
+
+// virtualFiles returns ErrUnavailable, as the X selections only offer
+// file references.
+func virtualFiles(dir string) ([]string, error) {
+	return nil, ErrUnavailable
 }
@@ -1,10 +1,8 @@
-//go:build !windows && !cgo
+//go:build (darwin || linux) && !cgo
 
 package clipboard
 
-import "context"
-
-func initialize() error {
+func initialize(c config) error {
 	panic("clipboard: cannot use when CGO_ENABLED=0")
 }
 
@@ -16,10 +14,66 @@ func readc(t string) ([]byte, error) {
 	panic("clipboard: cannot use when CGO_ENABLED=0")
 }
 
-func write(t Format, buf []byte) (<-chan struct{}, error) {
+func has(t Format) bool {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func prober() func(Format) bool {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func readData(mime string) ([]byte, error) {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func imageInfo() (int, int, string, error) {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func write(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func writeOnce(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func writeData(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func sequence() (uint64, bool) {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func rawCall(fn func(uintptr) error) error {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func announce(msg string) error {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func readSelection(s Selection, t Format) ([]byte, error) {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func virtualFiles(dir string) ([]string, error) {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func lost() <-chan struct{} {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func info() Info {
+	panic("clipboard: cannot use when CGO_ENABLED=0")
+}
+
+func locked() bool {
 	panic("clipboard: cannot use when CGO_ENABLED=0")
 }
 
-func watch(ctx context.Context, t Format) <-chan []byte {
+func newSystem(c config) (system, error) {
 	panic("clipboard: cannot use when CGO_ENABLED=0")
 }
@@ -0,0 +1,56 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build !windows && !darwin && !linux
+
+// The platform has no clipboard backend, for instance, plan9 or aix.
+// The package still compiles, so that multi-platform projects can gate
+// the clipboard at runtime: Init returns ErrUnavailable, and the other
+// functions fail with ErrNotInitialized.
+
+package clipboard
+
+func initialize(c config) error { return ErrUnavailable }
+
+func read(t Format) (buf []byte, err error) { return nil, ErrUnavailable }
+
+func has(t Format) bool { return false }
+
+func prober() func(Format) bool { return has }
+
+func readData(mime string) ([]byte, error) { return nil, ErrUnavailable }
+
+func imageInfo() (int, int, string, error) { return 0, 0, "", ErrUnavailable }
+
+func write(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnavailable
+}
+
+func writeOnce(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnavailable
+}
+
+func writeData(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnavailable
+}
+
+func sequence() (uint64, bool) { return 0, false }
+
+func rawCall(fn func(uintptr) error) error { return ErrUnavailable }
+
+func announce(msg string) error { return ErrUnavailable }
+
+func readSelection(s Selection, t Format) ([]byte, error) { return nil, ErrUnavailable }
+
+func virtualFiles(dir string) ([]string, error) { return nil, ErrUnavailable }
+
+func lost() <-chan struct{} { return nil }
+
+func info() Info { return Info{} }
+
+func locked() bool { return false }
+
+func newSystem(c config) (system, error) { return system{}, ErrUnavailable }
@@ -10,11 +10,18 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"image"
 	"image/color"
+	"image/jpeg"
 	"image/png"
 	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,6 +32,44 @@ func init() {
 	clipboard.Debug = true
 }
 
+func TestMain(m *testing.M) {
+	// Initialize the clipboard for all tests, unless cgo is disabled,
+	// where TestClipboardInit expects Init to panic. The tests run
+	// against the in-memory clipboard if CLIPBOARD_TEST_BACKEND=memory,
+	// which needs neither cgo nor a display.
+	if os.Getenv("CLIPBOARD_TEST_BACKEND") == "memory" {
+		clipboard.Init(clipboard.WithBackend(clipboard.BackendMemory))
+	} else if val, ok := os.LookupEnv("CGO_ENABLED"); runtime.GOOS == "windows" || !ok || val != "0" {
+		clipboard.Init()
+	}
+	os.Exit(m.Run())
+}
+
+func TestNotInitialized(t *testing.T) {
+	if clipboard.InitDone() {
+		t.Skip("the clipboard is initialized")
+	}
+
+	if _, err := clipboard.ReadErr(clipboard.FmtText); !errors.Is(err, clipboard.ErrNotInitialized) {
+		t.Fatalf("expect ErrNotInitialized from read, got: %v", err)
+	}
+	if _, err := clipboard.WriteErr(clipboard.FmtText, []byte("x")); !errors.Is(err, clipboard.ErrNotInitialized) {
+		t.Fatalf("expect ErrNotInitialized from write, got: %v", err)
+	}
+	if _, err := clipboard.WriteOSAScriptErr(clipboard.OSAScriptText, []byte("x")); !errors.Is(err, clipboard.ErrNotInitialized) {
+		t.Fatalf("expect ErrNotInitialized from AppleScript write, got: %v", err)
+	}
+	if _, err := clipboard.ReadData("text/plain"); !errors.Is(err, clipboard.ErrNotInitialized) {
+		t.Fatalf("expect ErrNotInitialized from data read, got: %v", err)
+	}
+	if clipboard.Has(clipboard.FmtText) {
+		t.Fatalf("uninitialized clipboard should not have data")
+	}
+	if _, ok := <-clipboard.Watch(context.Background(), clipboard.FmtText); ok {
+		t.Fatalf("watch of an uninitialized clipboard should be closed")
+	}
+}
+
 func TestClipboardInit(t *testing.T) {
 	t.Run("no-cgo", func(t *testing.T) {
 		if val, ok := os.LookupEnv("CGO_ENABLED"); !ok || val != "0" {
@@ -33,6 +78,9 @@ func TestClipboardInit(t *testing.T) {
 		if runtime.GOOS == "windows" {
 			t.Skip("Windows does not need to check for cgo")
 		}
+		if clipboard.InitDone() {
+			t.Skip("the clipboard is initialized")
+		}
 
 		defer func() {
 			if r := recover(); r != nil {
@@ -189,153 +237,1482 @@ func TestClipboardMultipleWrites(t *testing.T) {
 	}
 }
 
-func TestClipboardConcurrentRead(t *testing.T) {
+func TestClipboardWriteReadOrdering(t *testing.T) {
 	if runtime.GOOS != "windows" {
 		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
 			t.Skip("CGO_ENABLED is set to 0")
 		}
 	}
 
-	// This test check that concurrent read/write to the clipboard does
-	// not cause crashes on some specific platform, such as macOS.
-	done := make(chan bool, 2)
-	go func() {
-		defer func() {
-			done <- true
-		}()
-		clipboard.Read(clipboard.FmtText)
-	}()
-	go func() {
-		defer func() {
-			done <- true
-		}()
-		clipboard.Read(clipboard.FmtImage)
-	}()
-	<-done
-	<-done
+	// A Read immediately after a Write must observe the written data.
+	for i := 0; i < 10; i++ {
+		want := []byte(fmt.Sprintf("golang.design/x/clipboard %d", i))
+		if _, err := clipboard.WriteErr(clipboard.FmtText, want); err != nil {
+			t.Fatalf("failed to write to clipboard: %v", err)
+		}
+		if got := clipboard.Read(clipboard.FmtText); !bytes.Equal(got, want) {
+			t.Fatalf("read after write observes stale data, want: %s, got: %s", want, got)
+		}
+	}
 }
 
-func TestClipboardWriteEmpty(t *testing.T) {
+func TestClipboardHas(t *testing.T) {
 	if runtime.GOOS != "windows" {
 		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
 			t.Skip("CGO_ENABLED is set to 0")
 		}
 	}
 
-	chg1 := clipboard.Write(clipboard.FmtText, nil)
-	if got := clipboard.Read(clipboard.FmtText); got != nil {
-		t.Fatalf("write nil to clipboard should read nil, got: %v", string(got))
+	if _, err := clipboard.WriteErr(clipboard.FmtText, []byte("golang.design/x/clipboard")); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
 	}
-	clipboard.Write(clipboard.FmtText, []byte(""))
-	<-chg1
+	if !clipboard.Has(clipboard.FmtText) {
+		t.Fatalf("clipboard that stores text data should have text")
+	}
+	if clipboard.Has(clipboard.FmtImage) {
+		t.Fatalf("clipboard that stores text data should not have image")
+	}
+}
 
-	if got := clipboard.Read(clipboard.FmtText); string(got) != "" {
-		t.Fatalf("write empty string to clipboard should read empty string, got: `%v`", string(got))
+func TestClipboardHTML(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("HTML is not supported on mobile platforms")
+	}
+
+	want := []byte("<b>golang.design</b>/x/clipboard")
+	if _, err := clipboard.WriteErr(clipboard.FmtHTML, want); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+	if got := clipboard.Read(clipboard.FmtHTML); !bytes.Equal(got, want) {
+		t.Fatalf("read html mismatch, got: %s, want: %s", got, want)
+	}
+	if !clipboard.Has(clipboard.FmtHTML) {
+		t.Fatalf("clipboard that stores html data should have html")
 	}
 }
 
-func TestClipboardWatch(t *testing.T) {
+func TestClipboardRich(t *testing.T) {
 	if runtime.GOOS != "windows" {
 		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
 			t.Skip("CGO_ENABLED is set to 0")
 		}
 	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("HTML is not supported on mobile platforms")
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
-	defer cancel()
+	html := []byte("<b>golang.design</b>")
+	plain := []byte("golang.design")
+	if _, err := clipboard.WriteRich(html, plain); err != nil {
+		t.Fatalf("failed to write rich text: %v", err)
+	}
+	if got := clipboard.Read(clipboard.FmtHTML); !bytes.Equal(got, html) {
+		t.Fatalf("read html mismatch, got: %s, want: %s", got, html)
+	}
+	if got := clipboard.Read(clipboard.FmtText); !bytes.Equal(got, plain) {
+		t.Fatalf("read text mismatch, got: %s, want: %s", got, plain)
+	}
+}
 
-	// clear clipboard
-	clipboard.Write(clipboard.FmtText, []byte(""))
-	lastRead := clipboard.Read(clipboard.FmtText)
+func TestClipboardRTF(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("RTF is not supported on mobile platforms")
+	}
 
-	changed := clipboard.Watch(ctx, clipboard.FmtText)
+	want := []byte(`{\rtf1\ansi {\b golang.design}/x/clipboard}`)
+	if _, err := clipboard.WriteErr(clipboard.FmtRTF, want); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+	if got := clipboard.Read(clipboard.FmtRTF); !bytes.Equal(got, want) {
+		t.Fatalf("read rtf mismatch, got: %s, want: %s", got, want)
+	}
+}
 
-	want := []byte("golang.design/x/clipboard")
-	go func(ctx context.Context) {
-		t := time.NewTicker(time.Millisecond * 500)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-t.C:
-				clipboard.Write(clipboard.FmtText, want)
-			}
+func TestClipboardVCardICal(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
 		}
-	}(ctx)
-	for {
-		select {
-		case <-ctx.Done():
-			if string(lastRead) == "" {
-				t.Fatalf("clipboard watch never receives a notification")
-			}
-			t.Log(string(lastRead))
-			return
-		case data, ok := <-changed:
-			if !ok {
-				if string(lastRead) == "" {
-					t.Fatalf("clipboard watch never receives a notification")
-				}
-				return
-			}
-			if !bytes.Equal(data, want) {
-				t.Fatalf("received data from watch mismatch, want: %v, got %v", string(want), string(data))
-			}
-			lastRead = data
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("vCard and iCalendar are not supported on mobile platforms")
+	}
+
+	tests := []struct {
+		t    clipboard.Format
+		data []byte
+	}{
+		{clipboard.FmtVCard, []byte("BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Gopher\r\nEND:VCARD\r\n")},
+		{clipboard.FmtICal, []byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nSUMMARY:GopherCon\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")},
+	}
+	for _, tt := range tests {
+		if _, err := clipboard.WriteErr(tt.t, tt.data); err != nil {
+			t.Fatalf("failed to write %v to clipboard: %v", tt.t, err)
+		}
+		if !clipboard.Has(tt.t) {
+			t.Fatalf("clipboard should have %v data", tt.t)
+		}
+		if got := clipboard.Read(tt.t); !bytes.Equal(got, tt.data) {
+			t.Fatalf("read %v mismatch, got: %q, want: %q", tt.t, got, tt.data)
 		}
 	}
 }
 
-func BenchmarkClipboard(b *testing.B) {
-	b.Run("text", func(b *testing.B) {
-		data := []byte("golang.design/x/clipboard")
+func TestClipboardFiles(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("files are not supported on mobile platforms")
+	}
 
-		b.ReportAllocs()
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			clipboard.Write(clipboard.FmtText, data)
-			_ = clipboard.Read(clipboard.FmtText)
+	want, err := filepath.Abs("tests/testdata/clipboard.png")
+	if err != nil {
+		t.Fatalf("failed to resolve gold file: %v", err)
+	}
+	if _, err := clipboard.WriteFiles([]string{"tests/testdata/clipboard.png"}); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+	got, err := clipboard.ReadFiles()
+	if err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{want}) {
+		t.Fatalf("read files mismatch, got: %v, want: %v", got, want)
+	}
+
+	// Files of file managers are pasted as they are.
+	got, err = clipboard.PasteFiles("")
+	if err != nil {
+		t.Fatalf("failed to paste files: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{want}) {
+		t.Fatalf("pasted files mismatch, got: %v, want: %v", got, want)
+	}
+}
+
+func TestClipboardURL(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
 		}
-	})
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("URL is not supported on mobile platforms")
+	}
+
+	want := []byte("https://golang.design/x/clipboard")
+	if _, err := clipboard.WriteErr(clipboard.FmtURL, want); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+	if got := clipboard.Read(clipboard.FmtURL); !bytes.Equal(got, want) {
+		t.Fatalf("read url mismatch, got: %s, want: %s", got, want)
+	}
+	if got := clipboard.Read(clipboard.FmtText); !bytes.Equal(got, want) {
+		t.Fatalf("url is not offered as text, got: %s, want: %s", got, want)
+	}
+
+	// A URL that is copied as plain text can be read as a URL.
+	clipboard.Write(clipboard.FmtText, want)
+	if got := clipboard.Read(clipboard.FmtURL); !bytes.Equal(got, want) {
+		t.Fatalf("read url from text mismatch, got: %s, want: %s", got, want)
+	}
 }
 
-func TestClipboardNoCgo(t *testing.T) {
-	if val, ok := os.LookupEnv("CGO_ENABLED"); !ok || val != "0" {
-		t.Skip("CGO_ENABLED is set to 1")
+func TestClipboardWriteSeq(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
 	}
-	if runtime.GOOS == "windows" {
-		t.Skip("Windows should always be tested")
+	if _, ok := clipboard.Sequence(); !ok {
+		t.Skip("the platform has no sequence number")
 	}
 
-	t.Run("Read", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r != nil {
-				return
-			}
-			t.Fatalf("expect to fail when CGO_ENABLED=0")
-		}()
+	seq, _, err := clipboard.WriteSeq(clipboard.FmtText, []byte("golang.design"))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if cur, _ := clipboard.Sequence(); cur != seq {
+		t.Fatalf("sequence number mismatch, got: %d, want: %d", cur, seq)
+	}
+	clipboard.Write(clipboard.FmtText, []byte("x"))
+	if cur, _ := clipboard.Sequence(); cur == seq {
+		t.Fatalf("sequence number did not change after another write")
+	}
+}
 
-		clipboard.Read(clipboard.FmtText)
-	})
+func TestClipboardWriteOwned(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "android" {
+		t.Skip("the ownership is not told on Android")
+	}
 
-	t.Run("Write", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r != nil {
-				return
-			}
-			t.Fatalf("expect to fail when CGO_ENABLED=0")
-		}()
+	_, lost, err := clipboard.WriteOwned(clipboard.FmtText, []byte("golang.design"))
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	select {
+	case <-lost:
+		t.Fatalf("ownership is lost before another write")
+	case <-time.After(100 * time.Millisecond):
+	}
+	clipboard.Write(clipboard.FmtText, []byte("x"))
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		t.Fatalf("ownership is not lost after another write")
+	}
+}
 
-		clipboard.Write(clipboard.FmtText, []byte("dummy"))
-	})
+func TestClipboardBackendInfo(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
 
-	t.Run("Watch", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r != nil {
-				return
-			}
-			t.Fatalf("expect to fail when CGO_ENABLED=0")
-		}()
+	info := clipboard.BackendInfo()
+	if info.Protocol == "" {
+		t.Fatalf("missing protocol of the backend: %+v", info)
+	}
+	if _, ok := clipboard.Sequence(); ok != info.SequenceNumber {
+		t.Fatalf("sequence number mismatch, Sequence: %v, info: %+v", ok, info)
+	}
+	want := clipboard.BackendAuto
+	switch {
+	case os.Getenv("CLIPBOARD_TEST_BACKEND") == "memory":
+		want = clipboard.BackendMemory
+	case runtime.GOOS == "linux":
+		want = clipboard.BackendX11
+	}
+	if info.Backend != want {
+		t.Fatalf("backend mismatch, got: %v, want: %v", info.Backend, want)
+	}
+	if info.DelayedRendering != (want == clipboard.BackendX11) {
+		t.Fatalf("only X11 serves the data on demand, got: %+v", info)
+	}
+}
+
+func TestClipboardLocked(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clipboard.Write(clipboard.FmtText, []byte("before"))
+	events := clipboard.WatchEvents(ctx, clipboard.FmtText)
 
-		clipboard.Watch(context.TODO(), clipboard.FmtText)
+	var locked int32
+	restore := clipboard.SetLocked(func() bool { return atomic.LoadInt32(&locked) == 1 })
+	defer restore()
+
+	next := func() clipboard.Event {
+		t.Helper()
+		clipboard.NotifyUpdate()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(3 * time.Second):
+			t.Fatalf("no event is delivered")
+		}
+		return clipboard.Event{}
+	}
+
+	atomic.StoreInt32(&locked, 1)
+	if e := next(); e.Kind != clipboard.EventSuspended {
+		t.Fatalf("expect EventSuspended, got: %v", e.Kind)
+	}
+	clipboard.Write(clipboard.FmtText, []byte("meanwhile"))
+	clipboard.NotifyUpdate()
+	select {
+	case e := <-events:
+		t.Fatalf("the change is delivered while locked: %v", e.Kind)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	atomic.StoreInt32(&locked, 0)
+	if e := next(); e.Kind != clipboard.EventResumed {
+		t.Fatalf("expect EventResumed, got: %v", e.Kind)
+	}
+	e := next()
+	if e.Kind != clipboard.EventChanged || string(e.Data) != "meanwhile" {
+		t.Fatalf("expect the change while locked, got: %v %q", e.Kind, e.Data)
+	}
+}
+
+func TestClipboardCoalescingWriter(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	w := clipboard.NewCoalescingWriter(100 * time.Millisecond)
+	before, seqOK := clipboard.Sequence()
+	buf := []byte("golang.design/x/clipboard ")
+	for i := 0; i < 10; i++ {
+		w.Write(clipboard.FmtText, append(buf, byte('0'+i)))
+	}
+	if seq, _ := clipboard.Sequence(); seqOK && seq != before {
+		t.Fatalf("clipboard is written before the writes settled")
+	}
+	time.Sleep(500 * time.Millisecond)
+	if err := w.Err(); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if got, want := clipboard.Read(clipboard.FmtText), append(buf, '9'); !bytes.Equal(got, want) {
+		t.Fatalf("coalesced write mismatch, got: %s, want: %s", got, want)
+	}
+	if seq, _ := clipboard.Sequence(); seqOK && seq != before+1 {
+		t.Fatalf("expect a single write, the sequence number changed from %d to %d", before, seq)
+	}
+
+	want := []byte("flushed")
+	w.Write(clipboard.FmtText, want)
+	if _, err := w.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	if got := clipboard.Read(clipboard.FmtText); !bytes.Equal(got, want) {
+		t.Fatalf("flushed write mismatch, got: %s, want: %s", got, want)
+	}
+}
+
+func TestClipboardReadCache(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if _, ok := clipboard.Sequence(); !ok {
+		t.Skip("the platform has no sequence number")
+	}
+	clipboard.SetReadCache(true)
+	defer clipboard.SetReadCache(false)
+
+	want := []byte("golang.design/x/clipboard")
+	clipboard.Write(clipboard.FmtText, want)
+	if got := clipboard.Read(clipboard.FmtText); !bytes.Equal(got, want) {
+		t.Fatalf("read mismatch, got: %s, want: %s", got, want)
+	}
+	got, ok := clipboard.CachedRead(clipboard.FmtText)
+	if !ok || !bytes.Equal(got, want) {
+		t.Fatalf("expect the read to be cached, got: %s, %v", got, ok)
+	}
+	// Modifying the returned data does not modify the cache.
+	clipboard.Read(clipboard.FmtText)[0] = 'x'
+	if got := clipboard.Read(clipboard.FmtText); !bytes.Equal(got, want) {
+		t.Fatalf("cached read mismatch, got: %s, want: %s", got, want)
+	}
+
+	want = []byte("golang.design/x/clipboard/cache")
+	clipboard.Write(clipboard.FmtText, want)
+	if _, ok := clipboard.CachedRead(clipboard.FmtText); ok {
+		t.Fatalf("expect the cache to be invalidated by a write")
+	}
+	if got := clipboard.Read(clipboard.FmtText); !bytes.Equal(got, want) {
+		t.Fatalf("read after write mismatch, got: %s, want: %s", got, want)
+	}
+}
+
+func TestClipboardSelection(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	want := []byte("golang.design/x/clipboard")
+	clipboard.Write(clipboard.FmtText, want)
+	got, err := clipboard.ReadSelection(clipboard.SelectionClipboard, clipboard.FmtText)
+	if err != nil {
+		t.Fatalf("failed to read the clipboard selection: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("read selection mismatch, got: %s, want: %s", got, want)
+	}
+	if runtime.GOOS == "linux" && os.Getenv("CLIPBOARD_TEST_BACKEND") != "memory" {
+		return
+	}
+	_, err = clipboard.ReadSelection(clipboard.SelectionSecondary, clipboard.FmtText)
+	if !errors.Is(err, clipboard.ErrUnsupported) {
+		t.Fatalf("expect ErrUnsupported for the secondary selection, got: %v", err)
+	}
+}
+
+func TestClipboardX11Targets(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Getenv("CLIPBOARD_TEST_BACKEND") == "memory" {
+		t.Skip("the targets are specific to X11")
+	}
+	if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+		t.Skip("CGO_ENABLED is set to 0")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode the image: %v", err)
+	}
+	if _, err := clipboard.WriteErr(clipboard.FmtImage, buf.Bytes()); err != nil {
+		t.Fatalf("failed to write the image: %v", err)
+	}
+	for _, mime := range []string{"image/bmp", "image/jpeg"} {
+		b, err := clipboard.ReadData(mime)
+		if err != nil {
+			t.Fatalf("failed to read the image as %s: %v", mime, err)
+		}
+		if _, _, err := image.Decode(bytes.NewReader(b)); err != nil {
+			t.Fatalf("failed to decode the image as %s: %v", mime, err)
+		}
+	}
+
+	if _, err := clipboard.WriteErr(clipboard.FmtText, []byte("café €")); err != nil {
+		t.Fatalf("failed to write the text: %v", err)
+	}
+	want := map[string]string{
+		"STRING":                   "caf\xe9 ?",
+		"text/plain;charset=utf-8": "café €",
+	}
+	for target, text := range want {
+		b, err := clipboard.ReadData(target)
+		if err != nil {
+			t.Fatalf("failed to read the text as %s: %v", target, err)
+		}
+		if string(b) != text {
+			t.Fatalf("text of %s mismatch, got: %q, want: %q", target, b, text)
+		}
+	}
+}
+
+func TestClipboardWriteFallback(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	// A color of an invalid size is rejected on all platforms.
+	want := []byte("golang.design/x/clipboard")
+	got, _, err := clipboard.WriteFallback(clipboard.FmtColor, []byte{1, 2, 3},
+		clipboard.Alternative{Format: clipboard.FmtText, Data: want})
+	if err != nil {
+		t.Fatalf("failed to write the alternative: %v", err)
+	}
+	if got != clipboard.FmtText {
+		t.Fatalf("written format mismatch, got: %v, want: %v", got, clipboard.FmtText)
+	}
+	if b := clipboard.Read(clipboard.FmtText); !bytes.Equal(b, want) {
+		t.Fatalf("read mismatch, got: %s, want: %s", b, want)
+	}
+
+	if _, _, err := clipboard.WriteFallback(clipboard.FmtColor, []byte{1, 2, 3}); err == nil {
+		t.Fatalf("expect an error without alternatives")
+	}
+}
+
+func TestClipboardIncremental(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	// Data larger than the chunk size is transferred incrementally
+	// between the owner and the reader on X11.
+	tu := clipboard.Tuning()
+	defer clipboard.SetTuning(tu)
+	small := tu
+	small.ChunkSize = 4 << 10
+	clipboard.SetTuning(small)
+
+	want := bytes.Repeat([]byte("golang.design/x/clipboard\n"), 40000)
+	if _, err := clipboard.WriteErr(clipboard.FmtText, want); err != nil {
+		t.Fatalf("failed to write the data: %v", err)
+	}
+	got, err := clipboard.ReadErr(clipboard.FmtText)
+	if err != nil {
+		t.Fatalf("failed to read the data: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("read mismatch, got %d bytes, want %d bytes", len(got), len(want))
+	}
+}
+
+func TestClipboardIncrementalMaxRequest(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	// Without a chunk size, data larger than the maximum request size
+	// of the X server, including big requests, can only be transferred
+	// incrementally on X11.
+	tu := clipboard.Tuning()
+	defer clipboard.SetTuning(tu)
+	unlimited := tu
+	unlimited.ChunkSize = 0
+	clipboard.SetTuning(unlimited)
+
+	want := bytes.Repeat([]byte("golang.design/x/clipboard\n"), 700000)
+	if _, err := clipboard.WriteErr(clipboard.FmtText, want); err != nil {
+		t.Fatalf("failed to write the data: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		got, err := clipboard.ReadErr(clipboard.FmtText)
+		if err != nil {
+			t.Fatalf("failed to read the data: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("read mismatch, got %d bytes, want %d bytes", len(got), len(want))
+		}
+	}
+}
+
+func TestClipboardWriteOnce(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	want := []byte("golang.design/x/clipboard")
+	changed, err := clipboard.WriteOnce(clipboard.FmtText, want)
+	if runtime.GOOS != "linux" {
+		if !errors.Is(err, clipboard.ErrUnsupported) {
+			t.Fatalf("expect ErrUnsupported, got: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("failed to write once: %v", err)
+	}
+	if _, err := clipboard.ReadOrigin(); err != nil {
+		t.Fatalf("failed to read origin: %v", err)
+	}
+	if got := clipboard.Read(clipboard.FmtText); !bytes.Equal(got, want) {
+		t.Fatalf("first read mismatch, got: %s, want: %s", got, want)
+	}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatalf("clipboard is not cleared after the paste")
+	}
+	if got := clipboard.Read(clipboard.FmtText); got != nil {
+		t.Fatalf("second read should return nil, got: %s", got)
+	}
+}
+
+func TestClipboardOSAScript(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "darwin" && os.Getenv("CLIPBOARD_TEST_BACKEND") != "memory" {
+		t.Skip("AppleScript writes are supported on macOS")
+	}
+
+	_, err := clipboard.WriteOSAScriptErr(clipboard.OSAScriptText, []byte("golang.design"))
+	if !errors.Is(err, clipboard.ErrUnsupported) {
+		t.Fatalf("expect ErrUnsupported, got: %v", err)
+	}
+	select {
+	case <-clipboard.WriteOSAScript(clipboard.OSAScriptText, []byte("golang.design")):
+	default:
+		t.Fatalf("the channel of a failed write is not closed")
+	}
+}
+
+func TestClipboardData(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("arbitrary data is not supported on mobile platforms")
+	}
+
+	const mime = "application/x-golang-design-test"
+	want := []byte{0x00, 0xff, 0x10, 0x00, 0x42}
+	if _, err := clipboard.WriteData(mime, want); err != nil {
+		t.Fatalf("failed to write data: %v", err)
+	}
+	got, err := clipboard.ReadData(mime)
+	if err != nil {
+		t.Fatalf("failed to read data: %v", err)
+	}
+	if !bytes.HasPrefix(got, want) {
+		t.Fatalf("read data mismatch, got: %v, want: %v", got, want)
+	}
+	if b := clipboard.Read(clipboard.FmtText); len(b) != 0 {
+		t.Fatalf("data should not be offered as text, got: %q", b)
+	}
+}
+
+func TestClipboardColor(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
+		t.Skip("color is only supported on macOS and Linux")
+	}
+
+	want := color.NRGBA64{R: 0x1234, G: 0xabcd, B: 0xffff, A: 0x8000}
+	if _, err := clipboard.WriteColor(want); err != nil {
+		t.Fatalf("failed to write color: %v", err)
+	}
+	got, err := clipboard.ReadColor()
+	if err != nil {
+		t.Fatalf("failed to read color: %v", err)
+	}
+	// Pasteboards may store colors at a lower precision.
+	near := func(a, b uint16) bool { return a-b < 0x100 || b-a < 0x100 }
+	if !near(got.R, want.R) || !near(got.G, want.G) || !near(got.B, want.B) || !near(got.A, want.A) {
+		t.Fatalf("read color mismatch, got: %v, want: %v", got, want)
+	}
+
+	if _, err := clipboard.WriteErr(clipboard.FmtColor, []byte{1, 2, 3}); err == nil {
+		t.Fatalf("expect to fail writing malformed color")
+	}
+}
+
+func TestClipboardImageRaw(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("raw images are not supported on mobile platforms")
+	}
+
+	want, err := os.ReadFile("tests/testdata/clipboard.png")
+	if err != nil {
+		t.Fatalf("failed to read gold file: %v", err)
+	}
+	if _, err := clipboard.WriteErr(clipboard.FmtImageRaw, want); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+	if !clipboard.Has(clipboard.FmtImageRaw) {
+		t.Fatalf("clipboard does not offer the written raw image")
+	}
+	if got := clipboard.Read(clipboard.FmtImageRaw); !bytes.Equal(got, want) {
+		t.Fatalf("read raw image mismatch, got %d bytes, want %d bytes", len(got), len(want))
+	}
+
+	if _, err := clipboard.WriteErr(clipboard.FmtImageRaw, []byte("not an image")); !errors.Is(err, clipboard.ErrUnsupported) {
+		t.Fatalf("expect ErrUnsupported for unknown encodings, got: %v", err)
+	}
+}
+
+func TestClipboardImage16(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("16-bit images are not supported on mobile platforms")
+	}
+
+	want := image.NewNRGBA64(image.Rect(0, 0, 4, 4))
+	for i := range want.Pix {
+		want.Pix[i] = byte(i * 7)
+	}
+	if _, err := clipboard.WriteImage(want); err != nil {
+		t.Fatalf("failed to write 16-bit image: %v", err)
+	}
+	got, err := clipboard.ReadImage()
+	if err != nil {
+		t.Fatalf("failed to read 16-bit image: %v", err)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if g, w := color.NRGBA64Model.Convert(got.At(x, y)), want.At(x, y); g != w {
+				t.Fatalf("16-bit image is not lossless at (%d, %d), got: %v, want: %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+func TestClipboardImageAlpha(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" {
+		t.Skip("images are not supported on iOS")
+	}
+
+	want := image.NewNRGBA(image.Rect(0, 0, 5, 1))
+	for x := 0; x < 5; x++ {
+		want.SetNRGBA(x, 0, color.NRGBA{R: 255, A: uint8(x * 255 / 4)})
+	}
+	if _, err := clipboard.WriteImage(want); err != nil {
+		t.Fatalf("failed to write transparent image: %v", err)
+	}
+	got, err := clipboard.ReadImage()
+	if err != nil {
+		t.Fatalf("failed to read transparent image: %v", err)
+	}
+	for x := 0; x < 5; x++ {
+		g := color.NRGBAModel.Convert(got.At(x, 0)).(color.NRGBA)
+		if w := want.NRGBAAt(x, 0); g.A != w.A {
+			t.Fatalf("alpha is not retained at %d, got: %d, want: %d", x, g.A, w.A)
+		}
+	}
+}
+
+func TestClipboardImageEncodings(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" {
+		t.Skip("images are not supported on iOS")
+	}
+
+	data, err := os.ReadFile("tests/testdata/clipboard.png")
+	if err != nil {
+		t.Fatalf("failed to read gold file: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode gold file: %v", err)
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		t.Fatalf("failed to encode jpeg: %v", err)
+	}
+
+	if _, err := clipboard.WriteErr(clipboard.FmtImage, b.Bytes()); err != nil {
+		t.Fatalf("failed to write jpeg to clipboard: %v", err)
+	}
+	got, err := png.Decode(bytes.NewReader(clipboard.Read(clipboard.FmtImage)))
+	if err != nil {
+		t.Fatalf("written jpeg is not read as png: %v", err)
+	}
+	if got.Bounds() != img.Bounds() {
+		t.Fatalf("read image has different bounds, got: %v, want: %v", got.Bounds(), img.Bounds())
+	}
+
+	out, err := clipboard.ReadImageAs("image/jpeg")
+	if err != nil {
+		t.Fatalf("failed to read image as jpeg: %v", err)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("read image is not jpeg encoded: %v", err)
+	}
+}
+
+func TestClipboardHelpers(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	t.Run("string", func(t *testing.T) {
+		want := "golang.design/x/clipboard"
+		clipboard.WriteString(want)
+		if got := clipboard.ReadString(); got != want {
+			t.Fatalf("read string mismatch, got: %s, want: %s", got, want)
+		}
+	})
+	t.Run("image", func(t *testing.T) {
+		if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+			t.Skip("Image is not supported on mobile platforms")
+		}
+
+		data, err := os.ReadFile("tests/testdata/clipboard.png")
+		if err != nil {
+			t.Fatalf("failed to read gold file: %v", err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to decode gold file: %v", err)
+		}
+		if _, err := clipboard.WriteImage(img); err != nil {
+			t.Fatalf("failed to write image: %v", err)
+		}
+		got, err := clipboard.ReadImage()
+		if err != nil {
+			t.Fatalf("failed to read image: %v", err)
+		}
+		if got.Bounds() != img.Bounds() {
+			t.Fatalf("read image has different bounds, got: %v, want: %v", got.Bounds(), img.Bounds())
+		}
+	})
+	t.Run("ocr", func(t *testing.T) {
+		if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+			t.Skip("Image is not supported on mobile platforms")
+		}
+
+		data, err := os.ReadFile("tests/testdata/clipboard.png")
+		if err != nil {
+			t.Fatalf("failed to read gold file: %v", err)
+		}
+		clipboard.Write(clipboard.FmtImage, data)
+		if _, err := clipboard.ReadTextOrOCR(); !errors.Is(err, clipboard.ErrUnsupported) {
+			t.Fatalf("expect ErrUnsupported without OCR engine, got: %v", err)
+		}
+
+		clipboard.SetOCR(func(png []byte) (string, error) { return "golang.design", nil })
+		defer clipboard.SetOCR(nil)
+		if got, err := clipboard.ReadTextOrOCR(); err != nil || got != "golang.design" {
+			t.Fatalf("read text from image mismatch, got: %q, %v", got, err)
+		}
+		clipboard.WriteString("text")
+		if got, err := clipboard.ReadTextOrOCR(); err != nil || got != "text" {
+			t.Fatalf("text should be preferred, got: %q, %v", got, err)
+		}
+	})
+}
+
+func TestClipboardImageInfo(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("Image is not supported on mobile platforms")
+	}
+
+	data, err := os.ReadFile("tests/testdata/clipboard.png")
+	if err != nil {
+		t.Fatalf("failed to read gold file: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode gold file: %v", err)
+	}
+	if _, err := clipboard.WriteErr(clipboard.FmtImage, data); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+
+	w, h, mime, err := clipboard.ImageInfo()
+	if err != nil {
+		t.Fatalf("failed to read image info: %v", err)
+	}
+	if w != img.Bounds().Dx() || h != img.Bounds().Dy() {
+		t.Fatalf("image size mismatch, got: %dx%d, want: %dx%d", w, h, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+	if mime == "" {
+		t.Fatalf("image mime type is empty")
+	}
+}
+
+func TestClipboardReadAny(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	want := []byte("golang.design/x/clipboard")
+	if _, err := clipboard.WriteErr(clipboard.FmtText, want); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+	got, format, err := clipboard.ReadAny(clipboard.FmtImage, clipboard.FmtText)
+	if err != nil {
+		t.Fatalf("failed to read clipboard: %v", err)
+	}
+	if format != clipboard.FmtText || !bytes.Equal(got, want) {
+		t.Fatalf("read data mismatch, got: %v %s, want: %v %s", format, got, clipboard.FmtText, want)
+	}
+	if _, _, err := clipboard.ReadAny(clipboard.FmtImage); err == nil {
+		t.Fatalf("read image from clipboard that stores text should fail")
+	}
+}
+
+func TestClipboardOrigin(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("Origin is not supported on mobile platforms")
+	}
+
+	clipboard.SetOriginApp("clipboard.test")
+	if _, err := clipboard.WriteSensitive(clipboard.FmtText, []byte("golang.design/x/clipboard")); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+	o, err := clipboard.ReadOrigin()
+	if err != nil {
+		t.Fatalf("failed to read origin: %v", err)
+	}
+	if o.App != "clipboard.test" || o.PID != os.Getpid() || !o.Sensitive {
+		t.Fatalf("origin mismatch, got: %+v", o)
+	}
+	if got := clipboard.Read(clipboard.FmtText); string(got) != "golang.design/x/clipboard" {
+		t.Fatalf("read data mismatch, got: %s", got)
+	}
+}
+
+func TestClipboardConcurrentRead(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	// This test check that concurrent read/write to the clipboard does
+	// not cause crashes on some specific platform, such as macOS.
+	done := make(chan bool, 2)
+	go func() {
+		defer func() {
+			done <- true
+		}()
+		clipboard.Read(clipboard.FmtText)
+	}()
+	go func() {
+		defer func() {
+			done <- true
+		}()
+		clipboard.Read(clipboard.FmtImage)
+	}()
+	<-done
+	<-done
+}
+
+func TestClipboardWriteEmpty(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	chg1 := clipboard.Write(clipboard.FmtText, nil)
+	if got := clipboard.Read(clipboard.FmtText); got != nil {
+		t.Fatalf("write nil to clipboard should read nil, got: %v", string(got))
+	}
+	clipboard.Write(clipboard.FmtText, []byte(""))
+	<-chg1
+
+	if got := clipboard.Read(clipboard.FmtText); string(got) != "" {
+		t.Fatalf("write empty string to clipboard should read empty string, got: `%v`", string(got))
+	}
+}
+
+func TestClipboardWatch(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	// clear clipboard
+	clipboard.Write(clipboard.FmtText, []byte(""))
+	lastRead := clipboard.Read(clipboard.FmtText)
+
+	changed := clipboard.Watch(ctx, clipboard.FmtText)
+
+	want := []byte("golang.design/x/clipboard")
+	go func(ctx context.Context) {
+		t := time.NewTicker(time.Millisecond * 500)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				clipboard.Write(clipboard.FmtText, want)
+			}
+		}
+	}(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			if string(lastRead) == "" {
+				t.Fatalf("clipboard watch never receives a notification")
+			}
+			t.Log(string(lastRead))
+			return
+		case data, ok := <-changed:
+			if !ok {
+				if string(lastRead) == "" {
+					t.Fatalf("clipboard watch never receives a notification")
+				}
+				return
+			}
+			if !bytes.Equal(data, want) {
+				t.Fatalf("received data from watch mismatch, want: %v, got %v", string(want), string(data))
+			}
+			lastRead = data
+		}
+	}
+}
+
+func TestClipboardReadCtx(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	want := []byte("golang.design/x/clipboard")
+	clipboard.Write(clipboard.FmtText, want)
+
+	got, err := clipboard.ReadCtx(context.Background(), clipboard.FmtText)
+	if err != nil {
+		t.Fatalf("failed to read clipboard: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("read data mismatch, want: %v, got %v", string(want), string(got))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := clipboard.ReadCtx(ctx, clipboard.FmtText); !errors.Is(err, context.Canceled) {
+		t.Fatalf("read with canceled context returns unexpected error: %v", err)
+	}
+}
+
+func TestClipboardOnChange(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	// clear clipboard
+	clipboard.Write(clipboard.FmtText, []byte(""))
+
+	changed := make(chan []byte, 1)
+	cancel := clipboard.OnChange(clipboard.FmtText, func(data []byte) {
+		select {
+		case changed <- data:
+		default:
+		}
+	})
+	defer cancel()
+
+	want := []byte("golang.design/x/clipboard")
+	clipboard.Write(clipboard.FmtText, want)
+	select {
+	case data := <-changed:
+		if !bytes.Equal(data, want) {
+			t.Fatalf("received data from callback mismatch, want: %v, got %v", string(want), string(data))
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("clipboard callback is never called")
+	}
+}
+
+func TestClipboardWatchEvents(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	want := []byte("golang.design/x/clipboard")
+	clipboard.Write(clipboard.FmtText, want)
+	events := clipboard.WatchEvents(ctx, clipboard.FmtText)
+
+	// clear clipboard
+	clipboard.Write(clipboard.FmtText, []byte(""))
+	select {
+	case <-ctx.Done():
+		t.Fatalf("clipboard watch never receives a cleared event")
+	case e, ok := <-events:
+		if !ok {
+			t.Fatalf("events channel is closed before receiving the cleared event")
+		}
+		if e.Kind != clipboard.EventCleared || e.Format != clipboard.FmtText {
+			t.Fatalf("expect a cleared event of text, got: %+v", e)
+		}
+	}
+
+	clipboard.Write(clipboard.FmtText, want)
+	select {
+	case <-ctx.Done():
+		t.Fatalf("clipboard watch never receives a changed event")
+	case e := <-events:
+		if e.Kind != clipboard.EventChanged || !bytes.Equal(e.Data, want) {
+			t.Fatalf("expect a changed event with %s, got: %+v", want, e)
+		}
+		if e.Size != len(want) || e.Time.IsZero() {
+			t.Fatalf("expect the size and time of the change, got: %+v", e)
+		}
+		// Other tests may still be writing, hence the sequence number
+		// may have advanced since the change.
+		if seq, ok := clipboard.Sequence(); ok && (e.Seq == 0 || e.Seq > seq) {
+			t.Fatalf("expect the sequence number of the change up to %d, got: %d", seq, e.Seq)
+		}
+	}
+}
+
+func TestClipboardWatchEventsError(t *testing.T) {
+	if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+		t.Skip("CGO_ENABLED is set to 0")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("other platforms transcode malformed text on write")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	clipboard.SetStrictRead(true)
+	defer clipboard.SetStrictRead(false)
+
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	events := clipboard.WatchEvents(ctx, clipboard.FmtText)
+
+	clipboard.Write(clipboard.FmtText, []byte("\xff\xfe"))
+	select {
+	case <-ctx.Done():
+		t.Fatalf("clipboard watch never receives an error event")
+	case e := <-events:
+		var merr *clipboard.MalformedError
+		if e.Kind != clipboard.EventError || !errors.As(e.Err, &merr) {
+			t.Fatalf("expect an error event of malformed text, got: %+v", e)
+		}
+	}
+
+	// The watcher resumes once the clipboard can be read again.
+	want := []byte("golang.design/x/clipboard/recovered")
+	clipboard.Write(clipboard.FmtText, want)
+	for _, kind := range []clipboard.EventKind{clipboard.EventReconnected, clipboard.EventChanged} {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("clipboard watch never recovers from the error")
+		case e := <-events:
+			if e.Kind != kind {
+				t.Fatalf("expect event kind %v, got: %+v", kind, e)
+			}
+			if kind == clipboard.EventChanged && !bytes.Equal(e.Data, want) {
+				t.Fatalf("expect a changed event with %s, got: %+v", want, e)
+			}
+		}
+	}
+}
+
+func TestClipboardWatchEventsFormats(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	events := clipboard.WatchEvents(ctx, clipboard.FmtText, clipboard.FmtHTML)
+
+	want := []byte("<b>golang.design/x/clipboard</b>")
+	clipboard.Write(clipboard.FmtHTML, want)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("clipboard watch never receives a changed event of html")
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("events channel is closed before receiving the change")
+			}
+			if e.Format != clipboard.FmtHTML {
+				continue
+			}
+			if e.Kind != clipboard.EventChanged || !bytes.Equal(e.Data, want) {
+				t.Fatalf("expect a changed event with %s, got: %+v", want, e)
+			}
+			return
+		}
+	}
+}
+
+func TestClipboardWatchLazy(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	events := clipboard.WatchLazy(ctx, clipboard.FmtText)
+
+	want := []byte("golang.design/x/clipboard/lazy")
+	clipboard.Write(clipboard.FmtText, want)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("clipboard watch never receives a payload")
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("events channel is closed before receiving the change")
+			}
+			if e.Kind != clipboard.EventChanged {
+				continue
+			}
+			if e.Data != nil || e.Payload == nil {
+				t.Fatalf("expect a payload instead of data, got: %+v", e)
+			}
+			data, err := e.Payload.Fetch(ctx)
+			if errors.Is(err, clipboard.ErrUnavailable) {
+				// Writes of other tests may still arrive.
+				continue
+			}
+			if err != nil {
+				t.Fatalf("failed to fetch the payload: %v", err)
+			}
+			if bytes.Equal(data, want) {
+				return
+			}
+		}
+	}
+}
+
+func TestClipboardWatchFiles(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+	if runtime.GOOS == "ios" || runtime.GOOS == "android" {
+		t.Skip("files are not supported on mobile platforms")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	files := clipboard.WatchFiles(ctx, t.TempDir())
+
+	want, err := filepath.Abs("tests/testdata/clipboard.png")
+	if err != nil {
+		t.Fatalf("failed to resolve gold file: %v", err)
+	}
+	if _, err := clipboard.WriteFiles([]string{want}); err != nil {
+		t.Fatalf("failed to write to clipboard: %v", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("clipboard watch never receives the files")
+		case got, ok := <-files:
+			if !ok {
+				t.Fatalf("files channel is closed before receiving the files")
+			}
+			if reflect.DeepEqual(got, []string{want}) {
+				return
+			}
+		}
+	}
+}
+
+func TestClipboardWatchIgnoreSelf(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	ch := clipboard.WatchWithOptions(ctx, clipboard.FmtText, clipboard.WatchIgnoreSelf())
+
+	own := []byte("written by the watcher itself")
+	clipboard.Write(clipboard.FmtText, own)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-ch:
+			// Writes of other tests may still arrive.
+			if ok && bytes.Equal(data, own) {
+				t.Fatalf("expect to ignore the own write, got: %s", data)
+			}
+		}
+	}
+}
+
+func TestClipboardSuspendWatchers(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	ch := clipboard.Watch(ctx, clipboard.FmtText)
+
+	secret := []byte("written while the watchers are suspended")
+	clipboard.SuspendWatchers()
+	clipboard.Write(clipboard.FmtText, secret)
+	clipboard.NotifyUpdate()
+	time.Sleep(100 * time.Millisecond)
+	clipboard.ResumeWatchers()
+
+	want := []byte("written after the watchers are resumed")
+	time.Sleep(100 * time.Millisecond)
+	clipboard.Write(clipboard.FmtText, want)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("clipboard watch never receives the change after resuming")
+		case data, ok := <-ch:
+			if !ok {
+				t.Fatalf("watch channel is closed before receiving the change")
+			}
+			if bytes.Equal(data, secret) {
+				t.Fatalf("expect to skip the change while suspended, got: %s", data)
+			}
+			if bytes.Equal(data, want) {
+				return
+			}
+		}
+	}
+}
+
+func TestClipboardWatchFilter(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	ch := clipboard.WatchWithOptions(ctx, clipboard.FmtText,
+		clipboard.WatchSize(0, 64),
+		clipboard.WatchMatch(regexp.MustCompile(`^https://`)))
+
+	skipped := []byte("https://golang.design/x/clipboard/" + strings.Repeat("x", 64))
+	want := []byte("https://golang.design/x/clipboard")
+	clipboard.Write(clipboard.FmtText, skipped)
+	time.Sleep(time.Second * 2)
+	clipboard.Write(clipboard.FmtText, want)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("clipboard watch never receives the matching change")
+		case data, ok := <-ch:
+			if !ok {
+				t.Fatalf("watch channel is closed before receiving the change")
+			}
+			if len(data) > 64 || !bytes.HasPrefix(data, []byte("https://")) {
+				t.Fatalf("expect the change to be filtered, got: %s", data)
+			}
+			if bytes.Equal(data, want) {
+				return
+			}
+		}
+	}
+}
+
+func TestClipboardWatchAll(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+			t.Skip("CGO_ENABLED is set to 0")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	snaps := clipboard.WatchAll(ctx)
+
+	want := []byte("<b>golang.design/x/clipboard</b>")
+	clipboard.Write(clipboard.FmtHTML, want)
+	select {
+	case <-ctx.Done():
+		t.Fatalf("clipboard watch never receives a snapshot")
+	case snap, ok := <-snaps:
+		if !ok {
+			t.Fatalf("snapshot channel is closed before receiving the change")
+		}
+		if !bytes.Equal(snap[clipboard.FmtHTML], want) {
+			t.Fatalf("expect a snapshot with html %s, got: %v", want, snap)
+		}
+	}
+}
+
+func BenchmarkClipboard(b *testing.B) {
+	b.Run("text", func(b *testing.B) {
+		data := []byte("golang.design/x/clipboard")
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			clipboard.Write(clipboard.FmtText, data)
+			_ = clipboard.Read(clipboard.FmtText)
+		}
+	})
+}
+
+func TestClipboardNoCgo(t *testing.T) {
+	if val, ok := os.LookupEnv("CGO_ENABLED"); !ok || val != "0" {
+		t.Skip("CGO_ENABLED is set to 1")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows should always be tested")
+	}
+	if clipboard.InitDone() {
+		t.Skip("the clipboard is initialized")
+	}
+
+	// Init panics without cgo, hence the clipboard is never initialized.
+	t.Run("Read", func(t *testing.T) {
+		if _, err := clipboard.ReadErr(clipboard.FmtText); !errors.Is(err, clipboard.ErrNotInitialized) {
+			t.Fatalf("expect to fail when CGO_ENABLED=0, got: %v", err)
+		}
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		if _, err := clipboard.WriteErr(clipboard.FmtText, []byte("dummy")); !errors.Is(err, clipboard.ErrNotInitialized) {
+			t.Fatalf("expect to fail when CGO_ENABLED=0, got: %v", err)
+		}
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		if _, ok := <-clipboard.Watch(context.TODO(), clipboard.FmtText); ok {
+			t.Fatalf("expect to fail when CGO_ENABLED=0")
+		}
 	})
 }
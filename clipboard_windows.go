@@ -13,24 +13,47 @@ package clipboard
 
 import (
 	"bytes"
-	"context"
 	"encoding/binary"
-	"errors"
 	"fmt"
-	"image"
-	"image/color"
-	"image/png"
+	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
-	"time"
-	"unicode/utf16"
 	"unsafe"
 
-	"golang.org/x/image/bmp"
+	"golang.design/x/clipboard/formats"
 )
 
-func initialize() error { return nil }
+// maxTextLength is the maximum number of UTF-16 code units that a text
+// read scans for the NUL terminator, zero means unlimited.
+var maxTextLength int
+
+// listening indicates the window of WithListenerWindow receives the
+// clipboard notifications.
+var listening bool
+
+func initialize(c config) error {
+	if c.backend != BackendAuto {
+		return fmt.Errorf("%w: %v backend", ErrUnsupported, c.backend)
+	}
+	maxTextLength = c.maxTextLength
+
+	if c.listener != 0 {
+		r, _, err := addClipboardFormatListener.Call(c.listener)
+		if r == 0 {
+			return fmt.Errorf("failed to add clipboard format listener: %w", err)
+		}
+		// The host forwards WM_CLIPBOARDUPDATE using NotifyUpdate,
+		// hence the change detection does not need to poll.
+		mon.setInterval(0)
+		listening = true
+	}
+	return nil
+}
 
 // readText reads the clipboard and returns the text data if presents.
 // The caller is responsible for opening/closing the clipboard before
@@ -46,41 +69,54 @@ func readText() (buf []byte, err error) {
 	}
 	defer gUnlock.Call(hMem)
 
-	// Find NUL terminator
-	n := 0
-	for ptr := unsafe.Pointer(p); *(*uint16)(ptr) != 0; n++ {
-		ptr = unsafe.Pointer(uintptr(ptr) +
-			unsafe.Sizeof(*((*uint16)(unsafe.Pointer(p)))))
+	// Bound the scan of the NUL terminator by the size of the memory,
+	// so that a malformed producer cannot cause a read past it.
+	size, _, err := gSize.Call(hMem)
+	if size == 0 {
+		return nil, err
+	}
+	limit := int(size / unsafe.Sizeof(uint16(0)))
+	if maxTextLength > 0 && limit > maxTextLength+1 {
+		limit = maxTextLength + 1
 	}
 
 	var s []uint16
 	h := (*reflect.SliceHeader)(unsafe.Pointer(&s))
 	h.Data = p
-	h.Len = n
-	h.Cap = n
-	return []byte(string(utf16.Decode(s))), nil
+	h.Len = limit
+	h.Cap = limit
+
+	// Find NUL terminator
+	n := 0
+	for n < limit && s[n] != 0 {
+		n++
+	}
+	if n == limit {
+		return nil, ErrCorrupt
+	}
+	// Decode right from the memory of the clipboard, so that huge text
+	// is not copied in between.
+	return formats.DecodeUTF16Units(s[:n]), nil
 }
 
 // writeText writes given data to the clipboard. It is the caller's
-// responsibility for opening/closing the clipboard before calling
-// this function.
+// responsibility for opening/emptying/closing the clipboard before
+// calling this function.
 func writeText(buf []byte) error {
-	r, _, err := emptyClipboard.Call()
-	if r == 0 {
-		return fmt.Errorf("failed to clear clipboard: %w", err)
-	}
-
 	// empty text, we are done here.
 	if len(buf) == 0 {
 		return nil
 	}
 
-	s, err := syscall.UTF16FromString(string(buf))
-	if err != nil {
-		return fmt.Errorf("failed to convert given string: %w", err)
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return fmt.Errorf("failed to convert given string: %w", syscall.EINVAL)
 	}
 
-	hMem, _, err := gAlloc.Call(gmemMoveable, uintptr(len(s)*int(unsafe.Sizeof(s[0]))))
+	// The text is encoded right into the global memory, so that huge
+	// text is not copied in between. The memory holds the NUL
+	// terminator as well.
+	n := formats.UTF16Len(buf) + 1
+	hMem, _, err := gAlloc.Call(gmemMoveable, uintptr(n)*unsafe.Sizeof(uint16(0)))
 	if hMem == 0 {
 		return fmt.Errorf("failed to alloc global memory: %w", err)
 	}
@@ -91,9 +127,13 @@ func writeText(buf []byte) error {
 	}
 	defer gUnlock.Call(hMem)
 
-	// no return value
-	memMove.Call(p, uintptr(unsafe.Pointer(&s[0])),
-		uintptr(len(s)*int(unsafe.Sizeof(s[0]))))
+	var s []uint16
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&s))
+	h.Data = p
+	h.Len = n
+	h.Cap = n
+	formats.EncodeUTF16Into(s, buf)
+	s[n-1] = 0
 
 	v, _, err := setClipboardData.Call(cFmtUnicodeText, hMem)
 	if v == 0 {
@@ -105,13 +145,53 @@ func writeText(buf []byte) error {
 }
 
 // readImage reads the clipboard and returns PNG encoded image data
-// if presents. The caller is responsible for opening/closing the
-// clipboard before calling this function.
+// if presents. A PNG with 16 bits per channel that the clipboard offers
+// along with the DIB is returned as is, as DIBs only hold 8 bits per
+// channel. The caller is responsible for opening/closing the clipboard
+// before calling this function.
 func readImage() ([]byte, error) {
-	hMem, _, err := getClipboardData.Call(cFmtDIBV5)
-	if hMem == 0 {
+	if isAvailable(registerFormat(cFmtPNGName)) {
+		if buf, err := readRegistered(cFmtPNGName); err == nil {
+			if depth, _ := formats.PNGBitDepth(buf); depth == 16 {
+				return buf, nil
+			}
+		}
+	}
+	dib, err := readFormat(cFmtDIBV5)
+	if len(dib) == 0 {
 		// second chance to try FmtDIB
-		return readImageDib()
+		dib, err = readFormat(cFmtDIB)
+	}
+	if len(dib) == 0 {
+		return nil, err
+	}
+	buf, err := formats.DIBToPNG(dib)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return buf, nil
+}
+
+// readRegistered reads the clipboard data of a registered clipboard
+// format, whose name is the given MIME type in most cases. The caller
+// is responsible for opening/closing the clipboard before calling this
+// function.
+func readRegistered(mime string) ([]byte, error) {
+	name := append([]byte(mime), 0)
+	format, _, err := registerClipboardFormatA.Call(uintptr(unsafe.Pointer(&name[0])))
+	if format == 0 {
+		return nil, err
+	}
+	return readFormat(format)
+}
+
+// readFormat reads the clipboard data of a given clipboard format as is.
+// The caller is responsible for opening/closing the clipboard before
+// calling this function.
+func readFormat(format uintptr) ([]byte, error) {
+	hMem, _, err := getClipboardData.Call(format)
+	if hMem == 0 {
+		return nil, err
 	}
 	p, _, err := gLock.Call(hMem)
 	if p == 0 {
@@ -119,179 +199,127 @@ func readImage() ([]byte, error) {
 	}
 	defer gUnlock.Call(hMem)
 
-	// inspect header information
-	info := (*bitmapV5Header)(unsafe.Pointer(p))
-
-	// maybe deal with other formats?
-	if info.BitCount != 32 {
-		return nil, errUnsupported
-	}
-
-	var data []byte
-	sh := (*reflect.SliceHeader)(unsafe.Pointer(&data))
-	sh.Data = uintptr(p)
-	sh.Cap = int(info.Size + 4*uint32(info.Width)*uint32(info.Height))
-	sh.Len = int(info.Size + 4*uint32(info.Width)*uint32(info.Height))
-	img := image.NewRGBA(image.Rect(0, 0, int(info.Width), int(info.Height)))
-	offset := int(info.Size)
-	stride := int(info.Width)
-	for y := 0; y < int(info.Height); y++ {
-		for x := 0; x < int(info.Width); x++ {
-			idx := offset + 4*(y*stride+x)
-			xhat := (x + int(info.Width)) % int(info.Width)
-			yhat := int(info.Height) - 1 - y
-			r := data[idx+2]
-			g := data[idx+1]
-			b := data[idx+0]
-			a := data[idx+3]
-			img.SetRGBA(xhat, yhat, color.RGBA{r, g, b, a})
-		}
-	}
-	// always use PNG encoding.
-	var buf bytes.Buffer
-	png.Encode(&buf, img)
-	return buf.Bytes(), nil
-}
-
-func readImageDib() ([]byte, error) {
-	const (
-		fileHeaderLen = 14
-		infoHeaderLen = 40
-		cFmtDIB       = 8
-	)
+	n, _, err := gSize.Call(hMem)
+	if n == 0 {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	memMove.Call(uintptr(unsafe.Pointer(&buf[0])), p, n)
+	return buf, nil
+}
 
-	hClipDat, _, err := getClipboardData.Call(cFmtDIB)
-	if err != nil {
-		return nil, errors.New("not dib format data: " + err.Error())
+// writeRegistered writes the given data to the clipboard as a registered
+// clipboard format, whose name is the given MIME type in most cases.
+// The caller is responsible for opening/emptying/closing the clipboard
+// before calling this function.
+func writeRegistered(mime string, buf []byte) error {
+	name := append([]byte(mime), 0)
+	format, _, err := registerClipboardFormatA.Call(uintptr(unsafe.Pointer(&name[0])))
+	if format == 0 {
+		return fmt.Errorf("failed to register clipboard format: %w", err)
 	}
-	pMemBlk, _, err := gLock.Call(hClipDat)
-	if pMemBlk == 0 {
-		return nil, errors.New("failed to call global lock: " + err.Error())
+	if err := writeFormat(format, buf); err != nil {
+		return fmt.Errorf("failed to set %s to clipboard: %w", mime, err)
 	}
-	defer gUnlock.Call(hClipDat)
-
-	bmpHeader := (*bitmapHeader)(unsafe.Pointer(pMemBlk))
-	dataSize := bmpHeader.SizeImage + fileHeaderLen + infoHeaderLen
+	return nil
+}
 
-	if bmpHeader.SizeImage == 0 && bmpHeader.Compression == 0 {
-		iSizeImage := bmpHeader.Height * ((bmpHeader.Width*uint32(bmpHeader.BitCount)/8 + 3) &^ 3)
-		dataSize += iSizeImage
+// writeFormat writes the given data to the clipboard as a given clipboard
+// format as is. The caller is responsible for opening/emptying/closing
+// the clipboard before calling this function.
+func writeFormat(format uintptr, buf []byte) error {
+	// GlobalAlloc does not allow allocating zero bytes for movable
+	// memory, allocate one byte for empty data.
+	n := len(buf)
+	if n == 0 {
+		n = 1
+	}
+	hMem, _, err := gAlloc.Call(gmemMoveable, uintptr(n))
+	if hMem == 0 {
+		return fmt.Errorf("failed to alloc global memory: %w", err)
+	}
+	if len(buf) > 0 {
+		p, _, err := gLock.Call(hMem)
+		if p == 0 {
+			gFree.Call(hMem)
+			return fmt.Errorf("failed to lock global memory: %w", err)
+		}
+		memMove.Call(p, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		gUnlock.Call(hMem)
 	}
-	buf := new(bytes.Buffer)
-	binary.Write(buf, binary.LittleEndian, uint16('B')|(uint16('M')<<8))
-	binary.Write(buf, binary.LittleEndian, uint32(dataSize))
-	binary.Write(buf, binary.LittleEndian, uint32(0))
-	const sizeof_colorbar = 0
-	binary.Write(buf, binary.LittleEndian, uint32(fileHeaderLen+infoHeaderLen+sizeof_colorbar))
-	j := 0
-	for i := fileHeaderLen; i < int(dataSize); i++ {
-		binary.Write(buf, binary.BigEndian, *(*byte)(unsafe.Pointer(pMemBlk + uintptr(j))))
-		j++
+
+	v, _, err := setClipboardData.Call(format, hMem)
+	if v == 0 {
+		gFree.Call(hMem)
+		return err
 	}
-	return bmpToPng(buf)
+	return nil
 }
 
-func bmpToPng(bmpBuf *bytes.Buffer) (buf []byte, err error) {
-	var f bytes.Buffer
-	original_image, err := bmp.Decode(bmpBuf)
-	if err != nil {
-		return nil, err
+// writeImageRaw writes the given image to the clipboard without
+// transcoding. A packed DIB is written as CF_DIBV5 or CF_DIB depending
+// on its header, PNG as the registered PNG format of browsers and
+// Office. The caller is responsible for opening/emptying/closing the
+// clipboard before calling this function.
+func writeImageRaw(buf []byte) error {
+	const fileHeaderLen = 14
+
+	switch sniffImage(buf) {
+	case mimePNG:
+		return writeRegistered(cFmtPNGName, buf)
+	case mimeBMP:
+		if len(buf) < fileHeaderLen {
+			return ErrUnsupported
+		}
+		buf = buf[fileHeaderLen:]
 	}
-	err = png.Encode(&f, original_image)
-	if err != nil {
-		return nil, err
+	if len(buf) < 4 {
+		return ErrUnsupported
+	}
+	var format uintptr
+	switch n := binary.LittleEndian.Uint32(buf); n {
+	case uint32(unsafe.Sizeof(bitmapV5Header{})):
+		format = cFmtDIBV5
+	case 12, 40, 52, 56, 108: // older versions of the header
+		format = cFmtDIB
+	default:
+		return ErrUnsupported
+	}
+	if err := writeFormat(format, buf); err != nil {
+		return fmt.Errorf("failed to set image to clipboard: %w", err)
 	}
-	return f.Bytes(), nil
+	return nil
 }
 
 func writeImage(buf []byte) error {
-	r, _, err := emptyClipboard.Call()
-	if r == 0 {
-		return fmt.Errorf("failed to clear clipboard: %w", err)
-	}
-
 	// empty text, we are done here.
 	if len(buf) == 0 {
 		return nil
 	}
 
-	img, err := png.Decode(bytes.NewReader(buf))
+	dib, err := formats.PNGToDIB(buf)
 	if err != nil {
 		return fmt.Errorf("input bytes is not PNG encoded: %w", err)
 	}
-
-	offset := unsafe.Sizeof(bitmapV5Header{})
-	width := img.Bounds().Dx()
-	height := img.Bounds().Dy()
-	imageSize := 4 * width * height
-
-	data := make([]byte, int(offset)+imageSize)
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			idx := int(offset) + 4*(y*width+x)
-			r, g, b, a := img.At(x, height-1-y).RGBA()
-			data[idx+2] = uint8(r)
-			data[idx+1] = uint8(g)
-			data[idx+0] = uint8(b)
-			data[idx+3] = uint8(a)
-		}
-	}
-
-	info := bitmapV5Header{}
-	info.Size = uint32(offset)
-	info.Width = int32(width)
-	info.Height = int32(height)
-	info.Planes = 1
-	info.Compression = 0 // BI_RGB
-	info.SizeImage = uint32(4 * info.Width * info.Height)
-	info.RedMask = 0xff0000 // default mask
-	info.GreenMask = 0xff00
-	info.BlueMask = 0xff
-	info.AlphaMask = 0xff000000
-	info.BitCount = 32 // we only deal with 32 bpp at the moment.
-	// Use calibrated RGB values as Go's image/png assumes linear color space.
-	// Other options:
-	// - LCS_CALIBRATED_RGB = 0x00000000
-	// - LCS_sRGB = 0x73524742
-	// - LCS_WINDOWS_COLOR_SPACE = 0x57696E20
-	// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-wmf/eb4bbd50-b3ce-4917-895c-be31f214797f
-	info.CSType = 0x73524742
-	// Use GL_IMAGES for GamutMappingIntent
-	// Other options:
-	// - LCS_GM_ABS_COLORIMETRIC = 0x00000008
-	// - LCS_GM_BUSINESS = 0x00000001
-	// - LCS_GM_GRAPHICS = 0x00000002
-	// - LCS_GM_IMAGES = 0x00000004
-	// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-wmf/9fec0834-607d-427d-abd5-ab240fb0db38
-	info.Intent = 4 // LCS_GM_IMAGES
-
-	infob := make([]byte, int(unsafe.Sizeof(info)))
-	for i, v := range *(*[unsafe.Sizeof(info)]byte)(unsafe.Pointer(&info)) {
-		infob[i] = v
-	}
-	copy(data[:], infob[:])
-
-	hMem, _, err := gAlloc.Call(gmemMoveable,
-		uintptr(len(data)*int(unsafe.Sizeof(data[0]))))
-	if hMem == 0 {
-		return fmt.Errorf("failed to alloc global memory: %w", err)
+	if err := writeFormat(cFmtDIBV5, dib); err != nil {
+		return fmt.Errorf("failed to set image to clipboard: %w", err)
 	}
-
-	p, _, err := gLock.Call(hMem)
-	if p == 0 {
-		return fmt.Errorf("failed to lock global memory: %w", err)
+	// Windows synthesizes CF_DIB from CF_DIBV5 by dropping the alpha
+	// channel, which pastes the transparent areas as black into the
+	// applications that only read CF_DIB. Offer a matted DIB instead,
+	// composited onto the image matte or white.
+	matted, err := formats.PNGToMattedDIB(buf, imageMatte())
+	if err != nil {
+		return fmt.Errorf("input bytes is not PNG encoded: %w", err)
 	}
-	defer gUnlock.Call(hMem)
-
-	memMove.Call(p, uintptr(unsafe.Pointer(&data[0])),
-		uintptr(len(data)*int(unsafe.Sizeof(data[0]))))
-
-	v, _, err := setClipboardData.Call(cFmtDIBV5, hMem)
-	if v == 0 {
-		gFree.Call(hMem)
-		return fmt.Errorf("failed to set text to clipboard: %w", err)
+	if err := writeFormat(cFmtDIB, matted); err != nil {
+		return fmt.Errorf("failed to set image to clipboard: %w", err)
+	}
+	// The DIB only holds 8 bits per channel, offer the PNG as well, so
+	// that applications that understand PNG paste the image losslessly.
+	if depth, _ := formats.PNGBitDepth(buf); depth == 16 {
+		return writeRegistered(cFmtPNGName, buf)
 	}
-
 	return nil
 }
 
@@ -305,16 +333,54 @@ func read(t Format) (buf []byte, err error) {
 	switch t {
 	case FmtImage:
 		format = cFmtDIBV5
+	case FmtImageRaw:
+		format = cFmtDIBV5
+		if !isAvailable(cFmtDIBV5) && !isAvailable(cFmtDIB) {
+			format = registerFormat(cFmtPNGName)
+		}
+	case FmtHTML:
+		format = registerFormat(cFmtHTMLName)
+	case FmtRTF:
+		format = registerFormat(cFmtRTFName)
+	case FmtVCard, FmtICal:
+		// There is no standard format, use the format that is
+		// registered with the MIME type as WriteData does.
+		format = registerFormat(mimeOf(t))
+	case FmtFiles:
+		format = cFmtHDrop
+	case FmtURL:
+		format = registerFormat(cFmtURLName)
+		if !isAvailable(format) && isAvailable(cFmtUnicodeText) {
+			// The URL may only be offered as plain text.
+			format = cFmtUnicodeText
+		}
+	case FmtColor:
+		// There is no color format that applications agree on.
+		return nil, ErrUnsupported
 	case FmtText:
 		fallthrough
 	default:
 		format = cFmtUnicodeText
+		if !isAvailable(format) && isAvailable(cFmtText) {
+			// Legacy applications may only offer ANSI text.
+			format = cFmtText
+		}
 	}
 
 	// check if clipboard is avaliable for the requested format
 	r, _, err := isClipboardFormatAvailable.Call(format)
-	if r == 0 {
-		return nil, errUnavailable
+	if r == 0 && (format != cFmtDIBV5 || !isAvailable(cFmtDIB)) {
+		// The clipboard may only offer other representations that
+		// are registered by applications using their MIME types.
+		for {
+			r, _, _ = openClipboard.Call()
+			if r == 0 {
+				continue
+			}
+			break
+		}
+		defer closeClipboard.Call()
+		return negotiate(mimeOf(t), readRegistered)
 	}
 
 	// try again until open clipboard successed
@@ -327,49 +393,220 @@ func read(t Format) (buf []byte, err error) {
 	}
 	defer closeClipboard.Call()
 
-	switch format {
-	case cFmtDIBV5:
+	switch t {
+	case FmtImage:
 		return readImage()
-	case cFmtUnicodeText:
+	case FmtImageRaw:
+		if format != cFmtDIBV5 {
+			return readFormat(format)
+		}
+		if isAvailable(cFmtDIBV5) {
+			return readFormat(cFmtDIBV5)
+		}
+		return readFormat(cFmtDIB)
+	case FmtHTML:
+		return readHTML()
+	case FmtRTF, FmtVCard, FmtICal:
+		name := cFmtRTFName
+		if t != FmtRTF {
+			name = mimeOf(t)
+		}
+		buf, err := readRegistered(name)
+		// Producers may terminate the data with NUL.
+		if i := bytes.IndexByte(buf, 0); i >= 0 {
+			buf = buf[:i]
+		}
+		return buf, err
+	case FmtFiles:
+		buf, err := readFormat(cFmtHDrop)
+		if err != nil || len(buf) == 0 {
+			return nil, err
+		}
+		return decodeDropFiles(buf)
+	case FmtURL:
+		if format == cFmtUnicodeText {
+			buf, err := readText()
+			if err != nil {
+				return nil, err
+			}
+			return textURL(buf), nil
+		}
+		buf, err := readRegistered(cFmtURLName)
+		if err != nil || len(buf) == 0 {
+			return nil, err
+		}
+		return decodeURL16(buf), nil
+	case FmtText:
 		fallthrough
 	default:
+		if format == cFmtText {
+			return readANSIText()
+		}
 		return readText()
 	}
 }
 
+// readANSIText reads the CF_TEXT data of the clipboard, which is encoded
+// in the ANSI code page, and returns the text encoded in UTF-8. The caller
+// is responsible for opening/closing the clipboard before calling this
+// function.
+func readANSIText() ([]byte, error) {
+	buf, err := readFormat(cFmtText)
+	if err != nil || len(buf) == 0 {
+		return nil, err
+	}
+	if i := bytes.IndexByte(buf, 0); i >= 0 {
+		buf = buf[:i]
+	}
+	if len(buf) == 0 {
+		return nil, nil
+	}
+
+	const cpACP = 0
+	n, _, err := multiByteToWideChar.Call(cpACP, 0,
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, 0)
+	if n == 0 {
+		return nil, fmt.Errorf("failed to convert ANSI text: %w", err)
+	}
+	s := make([]uint16, n)
+	n, _, err = multiByteToWideChar.Call(cpACP, 0,
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)),
+		uintptr(unsafe.Pointer(&s[0])), n)
+	if n == 0 {
+		return nil, fmt.Errorf("failed to convert ANSI text: %w", err)
+	}
+	return formats.DecodeUTF16Units(s[:n]), nil
+}
+
+// readHTML reads the CF_HTML data of the clipboard and returns the HTML
+// fragment. The caller is responsible for opening/closing the clipboard
+// before calling this function.
+func readHTML() ([]byte, error) {
+	buf, err := readRegistered(cFmtHTMLName)
+	if err != nil || len(buf) == 0 {
+		return nil, err
+	}
+	frag, err := decodeCFHTML(buf)
+	if err != nil {
+		if atomic.LoadInt32(&strictRead) == 1 {
+			return nil, err
+		}
+		// Be lenient to producers that mess up the offsets.
+		return buf, nil
+	}
+	return frag, nil
+}
+
+// registerFormat returns the clipboard format of a given name, which is
+// registered if it does not exist yet. It returns 0 if the registration
+// fails.
+func registerFormat(name string) uintptr {
+	b := append([]byte(name), 0)
+	format, _, _ := registerClipboardFormatA.Call(uintptr(unsafe.Pointer(&b[0])))
+	return format
+}
+
 // write writes the given data to clipboard and
 // returns true if success or false if failed.
-func write(t Format, buf []byte) (<-chan struct{}, error) {
+func write(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	if t == FmtColor {
+		return nil, ErrUnsupported
+	}
+	return writeWith(func() error {
+		switch t {
+		case FmtImage:
+			return writeImage(buf)
+		case FmtImageRaw:
+			return writeImageRaw(buf)
+		case FmtHTML:
+			return writeRegistered(cFmtHTMLName, formats.EncodeCFHTML(buf))
+		case FmtRTF:
+			return writeRegistered(cFmtRTFName, buf)
+		case FmtVCard, FmtICal:
+			return writeRegistered(mimeOf(t), buf)
+		case FmtFiles:
+			if err := writeFormat(cFmtHDrop, formats.EncodeDropFiles(splitFiles(buf))); err != nil {
+				return fmt.Errorf("failed to set files to clipboard: %w", err)
+			}
+			return nil
+		case FmtURL:
+			err := writeRegistered(cFmtURLName, append(formats.EncodeUTF16(string(buf)), 0, 0))
+			if err == nil {
+				err = writeText(buf)
+			}
+			return err
+		case FmtText:
+			fallthrough
+		default:
+			// param = cFmtUnicodeText
+			return writeText(buf)
+		}
+	}, extra)
+}
+
+// writeOnce returns an error as the data is rendered to the clipboard
+// immediately. Telling pastes apart requires delayed rendering, which
+// needs a window to receive WM_RENDERFORMAT.
+func writeOnce(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return nil, ErrUnsupported
+}
+
+// writeData writes the given data to clipboard as the registered
+// clipboard format of a given MIME type.
+func writeData(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+	return writeWith(func() error { return writeRegistered(mime, buf) }, extra)
+}
+
+// writeWith empties the clipboard, and writes the primary data using put
+// followed by the additional representations. The returned channel
+// receives a signal if the clipboard has been overwritten from the write.
+//
+// The clipboard is owned by a message-only window of the write, which
+// receives WM_DESTROYCLIPBOARD once another write empties the clipboard,
+// see lost.
+func writeWith(put func() error, extra []representation) (<-chan struct{}, error) {
 	errch := make(chan error)
-	changed := make(chan struct{}, 1)
+	lost := make(chan struct{}, 1)
+	var cnt uintptr
 	go func() {
 		// make sure GetClipboardSequenceNumber happens with
 		// OpenClipboard on the same thread.
 		runtime.LockOSThread()
 		defer runtime.UnlockOSThread()
+
+		// The window must live on this thread, which runs its message
+		// loop. The clipboard is not owned if the window cannot be
+		// created, and the ownership is not told.
+		hwnd := ownerWindow()
+		if hwnd != 0 {
+			defer destroyWindow.Call(hwnd)
+		}
 		for {
-			r, _, _ := openClipboard.Call(0)
+			r, _, _ := openClipboard.Call(hwnd)
 			if r == 0 {
 				continue
 			}
 			break
 		}
 
-		// var param uintptr
-		switch t {
-		case FmtImage:
-			err := writeImage(buf)
-			if err != nil {
-				errch <- err
-				closeClipboard.Call()
-				return
+		r, _, err := emptyClipboard.Call()
+		if r == 0 {
+			errch <- fmt.Errorf("failed to clear clipboard: %w", err)
+			closeClipboard.Call()
+			return
+		}
+
+		if err := put(); err != nil {
+			errch <- err
+			closeClipboard.Call()
+			return
+		}
+		for _, r := range extra {
+			put := func() error { return writeRegistered(r.mime, r.data) }
+			if r.mime == mimeText {
+				put = func() error { return writeText(r.data) }
 			}
-		case FmtText:
-			fallthrough
-		default:
-			// param = cFmtUnicodeText
-			err := writeText(buf)
-			if err != nil {
+			if err := put(); err != nil {
 				errch <- err
 				closeClipboard.Call()
 				return
@@ -379,63 +616,554 @@ func write(t Format, buf []byte) (<-chan struct{}, error) {
 		// paste the data.
 		closeClipboard.Call()
 
-		cnt, _, _ := getClipboardSequenceNumber.Call()
+		cnt, _, _ = getClipboardSequenceNumber.Call()
 		errch <- nil
+		if hwnd == 0 {
+			return
+		}
+
+		// Pump the messages of the window until WM_DESTROYCLIPBOARD
+		// quits the loop.
+		var m msg
 		for {
-			time.Sleep(time.Second)
-			cur, _, _ := getClipboardSequenceNumber.Call()
-			if cur != cnt {
-				changed <- struct{}{}
-				close(changed)
-				return
+			r, _, _ := getMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				break
 			}
+			dispatchMessageW.Call(uintptr(unsafe.Pointer(&m)))
 		}
+		lost <- struct{}{}
+		close(lost)
 	}()
 	err := <-errch
 	if err != nil {
 		return nil, err
 	}
-	return changed, nil
+	lastLost = lost
+	return changedFrom(uint64(cnt)), nil
 }
 
-func watch(ctx context.Context, t Format) <-chan []byte {
-	recv := make(chan []byte, 1)
-	ready := make(chan struct{})
-	go func() {
-		// not sure if we are too slow or the user too fast :)
-		ti := time.NewTicker(time.Second)
-		cnt, _, _ := getClipboardSequenceNumber.Call()
-		ready <- struct{}{}
-		for {
-			select {
-			case <-ctx.Done():
-				close(recv)
-				return
-			case <-ti.C:
-				cur, _, _ := getClipboardSequenceNumber.Call()
-				if cnt != cur {
-					b := Read(t)
-					if b == nil {
-						continue
-					}
-					recv <- b
-					cnt = cur
-				}
-			}
+// lastLost is the channel that receives a signal once the latest write
+// loses the ownership of the clipboard. It is protected by the lock.
+var lastLost <-chan struct{}
+
+// lost returns the channel of the latest write that receives a signal
+// once another write empties the clipboard, which Windows tells the
+// clipboard owner by WM_DESTROYCLIPBOARD.
+func lost() <-chan struct{} {
+	return lastLost
+}
+
+// info returns the behavior of the Win32 clipboard, which copies the data
+// of a write. Watch polls the sequence number of the clipboard, unless
+// the host forwards the notifications of WithListenerWindow.
+func info() Info {
+	return Info{
+		Protocol:       "Win32 clipboard",
+		EventDriven:    listening,
+		SequenceNumber: true,
+	}
+}
+
+// Window messages and styles of the owner windows, see:
+// https://docs.microsoft.com/en-us/windows/win32/dataxchg/wm-destroyclipboard
+// https://docs.microsoft.com/en-us/windows/win32/winmsg/window-features#message-only-windows
+const (
+	wmDestroyClipboard = 0x0307
+	hwndMessage        = ^uintptr(2) // (HWND)-3
+)
+
+// wndClassEx is the WNDCLASSEXW structure, see:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-wndclassexw
+type wndClassEx struct {
+	size       uint32
+	style      uint32
+	wndProc    uintptr
+	clsExtra   int32
+	wndExtra   int32
+	instance   uintptr
+	icon       uintptr
+	cursor     uintptr
+	background uintptr
+	menuName   *uint16
+	className  *uint16
+	iconSm     uintptr
+}
+
+// msg is the MSG structure, see:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-msg
+type msg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	x, y    int32
+	private uint32
+}
+
+var (
+	ownerClassOnce sync.Once
+	ownerClass     *uint16
+)
+
+// ownerWindow creates a message-only window on the calling thread that
+// owns the clipboard of a write. It returns 0 if the window cannot be
+// created.
+func ownerWindow() uintptr {
+	ownerClassOnce.Do(func() {
+		name, _ := syscall.UTF16PtrFromString("golang.design/x/clipboard")
+		instance, _, _ := getModuleHandleW.Call(0)
+		wc := wndClassEx{
+			wndProc:   syscall.NewCallback(ownerProc),
+			instance:  instance,
+			className: name,
 		}
-	}()
-	<-ready
-	return recv
+		wc.size = uint32(unsafe.Sizeof(wc))
+		if r, _, _ := registerClassExW.Call(uintptr(unsafe.Pointer(&wc))); r != 0 {
+			ownerClass = name
+		}
+	})
+	if ownerClass == nil {
+		return 0
+	}
+	hwnd, _, _ := createWindowExW.Call(0, uintptr(unsafe.Pointer(ownerClass)), 0, 0,
+		0, 0, 0, 0, hwndMessage, 0, 0, 0)
+	return hwnd
+}
+
+// ownerProc is the window procedure of the owner windows, which quits the
+// message loop of the window once it loses the clipboard.
+func ownerProc(hwnd, message, wParam, lParam uintptr) uintptr {
+	if message == wmDestroyClipboard {
+		postQuitMessage.Call(0)
+		return 0
+	}
+	r, _, _ := defWindowProcW.Call(hwnd, message, wParam, lParam)
+	return r
+}
+
+// sequence returns the clipboard sequence number of the current
+// window station.
+func sequence() (uint64, bool) {
+	cnt, _, _ := getClipboardSequenceNumber.Call()
+	return uint64(cnt), true
+}
+
+// isAvailable reports whether the clipboard contains data in the
+// given clipboard format.
+func isAvailable(format uintptr) bool {
+	r, _, _ := isClipboardFormatAvailable.Call(format)
+	return r != 0
+}
+
+// readData reads the clipboard data of the registered clipboard format
+// of a given MIME type.
+func readData(mime string) ([]byte, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for {
+		r, _, _ := openClipboard.Call()
+		if r == 0 {
+			continue
+		}
+		break
+	}
+	defer closeClipboard.Call()
+
+	format := registerFormat(mime)
+	if format == 0 || !isAvailable(format) {
+		return nil, ErrUnavailable
+	}
+	return readFormat(format)
+}
+
+// imageInfo parses the header of the DIB of the clipboard, or the image
+// of a registered clipboard format if there is no DIB.
+func imageInfo() (int, int, string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for {
+		r, _, _ := openClipboard.Call()
+		if r == 0 {
+			continue
+		}
+		break
+	}
+	defer closeClipboard.Call()
+
+	for _, format := range []uintptr{cFmtDIBV5, cFmtDIB} {
+		hMem, _, _ := getClipboardData.Call(format)
+		if hMem == 0 {
+			continue
+		}
+		p, _, err := gLock.Call(hMem)
+		if p == 0 {
+			return 0, 0, "", err
+		}
+		// All versions of bitmap headers share the leading fields.
+		info := *(*bitmapHeader)(unsafe.Pointer(p))
+		gUnlock.Call(hMem)
+
+		// A negative height indicates a top-down bitmap.
+		height := int(int32(info.Height))
+		if height < 0 {
+			height = -height
+		}
+		return int(int32(info.Width)), height, mimeBMP, nil
+	}
+
+	for _, m := range append([]string{mimePNG}, convertible(mimePNG)...) {
+		buf, err := readRegistered(m)
+		if err != nil || len(buf) == 0 {
+			continue
+		}
+		return decodeImageInfo(buf)
+	}
+	return 0, 0, "", ErrUnavailable
+}
+
+func has(t Format) bool {
+	switch t {
+	case FmtText:
+		if isAvailable(cFmtUnicodeText) || isAvailable(cFmtText) {
+			return true
+		}
+	case FmtImage:
+		if isAvailable(cFmtDIBV5) || isAvailable(cFmtDIB) {
+			return true
+		}
+	case FmtImageRaw:
+		if isAvailable(cFmtDIBV5) || isAvailable(cFmtDIB) {
+			return true
+		}
+		if format := registerFormat(cFmtPNGName); format != 0 && isAvailable(format) {
+			return true
+		}
+	case FmtHTML:
+		if format := registerFormat(cFmtHTMLName); format != 0 && isAvailable(format) {
+			return true
+		}
+	case FmtRTF:
+		if format := registerFormat(cFmtRTFName); format != 0 && isAvailable(format) {
+			return true
+		}
+	case FmtVCard, FmtICal:
+		if format := registerFormat(mimeOf(t)); format != 0 && isAvailable(format) {
+			return true
+		}
+	case FmtFiles:
+		if isAvailable(cFmtHDrop) {
+			return true
+		}
+	case FmtURL:
+		if format := registerFormat(cFmtURLName); format != 0 && isAvailable(format) {
+			return true
+		}
+	default:
+		return false
+	}
+	for _, from := range convertible(mimeOf(t)) {
+		if format := registerFormat(from); format != 0 && isAvailable(format) {
+			return true
+		}
+	}
+	return false
+}
+
+// prober returns a function that reports whether the clipboard holds
+// data in a format. The availability is checked locally, hence probing
+// costs no more than calling has for each format.
+func prober() func(Format) bool { return has }
+
+func rawCall(fn func(uintptr) error) error {
+	// OpenClipboard and CloseClipboard must be executed on the same
+	// thread, so does the operations in between.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for {
+		r, _, _ := openClipboard.Call()
+		if r == 0 {
+			continue
+		}
+		break
+	}
+	defer closeClipboard.Call()
+	return fn(0)
+}
+
+// announce raises a UI Automation notification event, which is read
+// out by screen readers such as Narrator, on the foreground window.
+func announce(msg string) error {
+	if uiaRaiseNotificationEvent.Find() != nil {
+		return ErrUnsupported
+	}
+	hwnd, _, _ := getForegroundWindow.Call()
+	if hwnd == 0 {
+		return ErrUnavailable
+	}
+
+	var provider uintptr
+	hr, _, _ := uiaHostProviderFromHwnd.Call(hwnd, uintptr(unsafe.Pointer(&provider)))
+	if hr != 0 || provider == 0 {
+		return fmt.Errorf("failed to get UI Automation provider: %#x", hr)
+	}
+	// IRawElementProviderSimple inherits IUnknown, whose third method
+	// is Release.
+	vtbl := *(*[3]uintptr)(unsafe.Pointer(*(*uintptr)(unsafe.Pointer(provider))))
+	defer syscall.Syscall(vtbl[2], 1, provider, 0, 0)
+
+	m, err := syscall.UTF16PtrFromString(msg)
+	if err != nil {
+		return err
+	}
+	id, _ := syscall.UTF16PtrFromString("golang.design/x/clipboard")
+	bmsg, _, _ := sysAllocString.Call(uintptr(unsafe.Pointer(m)))
+	defer sysFreeString.Call(bmsg)
+	bid, _, _ := sysAllocString.Call(uintptr(unsafe.Pointer(id)))
+	defer sysFreeString.Call(bid)
+
+	const (
+		notificationKindActionCompleted           = 2
+		notificationProcessingImportantMostRecent = 1
+	)
+	hr, _, _ = uiaRaiseNotificationEvent.Call(provider,
+		notificationKindActionCompleted,
+		notificationProcessingImportantMostRecent, bmsg, bid)
+	if hr != 0 {
+		return fmt.Errorf("failed to raise UI Automation notification: %#x", hr)
+	}
+	return nil
+}
+
+// virtualFiles saves the virtual files of the clipboard into dir, which
+// are described by FileGroupDescriptorW and whose data is offered as the
+// FileContents of each file. Virtual files are only accessible through
+// the OLE data object of the clipboard, as FileContents is indexed.
+func virtualFiles(dir string) ([]string, error) {
+	format := registerFormat(cFmtFileGroupDescriptorName)
+	if format == 0 {
+		return nil, ErrUnavailable
+	}
+	if r, _, _ := isClipboardFormatAvailable.Call(format); r == 0 {
+		return nil, ErrUnavailable
+	}
+
+	// OLE must be initialized on the thread that accesses the data
+	// object, which must stay the same thread.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if hr, _, _ := oleInitialize.Call(0); int32(hr) < 0 {
+		return nil, fmt.Errorf("failed to initialize OLE: %#x", hr)
+	}
+	defer oleUninitialize.Call()
+
+	var obj uintptr
+	hr, _, _ := oleGetClipboard.Call(uintptr(unsafe.Pointer(&obj)))
+	if hr != 0 || obj == 0 {
+		return nil, fmt.Errorf("failed to get the clipboard data object: %#x", hr)
+	}
+	do := dataObject{obj}
+	defer do.release()
+
+	desc, err := do.read(format, -1, tymedHGlobal)
+	if err != nil {
+		return nil, err
+	}
+	files, err := formats.DecodeFileGroupDescriptor(desc)
+	if err != nil {
+		return nil, &MalformedError{Format: FmtFiles, Reason: err.Error()}
+	}
+	if len(files) == 0 {
+		return nil, ErrUnavailable
+	}
+	dir, err = virtualDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := registerFormat(cFmtFileContentsName)
+	var paths []string
+	for i, f := range files {
+		p, err := virtualPath(dir, f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if f.Dir {
+			err = os.MkdirAll(p, 0o755)
+		} else {
+			err = do.save(p, contents, i, f.Size)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Files of directories are listed along with the directories,
+		// only the top-level files are returned.
+		if !strings.ContainsAny(f.Name, `\/`) {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// Values of FORMATETC and STGMEDIUM, see:
+// https://docs.microsoft.com/en-us/windows/win32/api/objidl/ns-objidl-formatetc
+// https://docs.microsoft.com/en-us/windows/win32/api/objidl/ns-objidl-ustgmedium-r1
+const (
+	dvAspectContent = 1
+	tymedHGlobal    = 1
+	tymedIStream    = 4
+	sFalse          = 1
+)
+
+type formatEtc struct {
+	cfFormat uint16
+	ptd      uintptr
+	aspect   uint32
+	index    int32
+	tymed    uint32
+}
+
+type stgMedium struct {
+	tymed   uint32
+	handle  uintptr
+	release uintptr
+}
+
+// dataObject is an IDataObject, which inherits IUnknown, whose third
+// method is Release, and whose fourth method is GetData.
+type dataObject struct{ p uintptr }
+
+func (do dataObject) vtbl() *[4]uintptr {
+	return (*[4]uintptr)(unsafe.Pointer(*(*uintptr)(unsafe.Pointer(do.p))))
+}
+
+func (do dataObject) release() {
+	syscall.Syscall(do.vtbl()[2], 1, do.p, 0, 0)
+}
+
+// get gets the data of the given format and index as one of the given
+// storage media. The caller must release the returned medium.
+func (do dataObject) get(format uintptr, index int32, tymed uint32) (*stgMedium, error) {
+	fe := formatEtc{cfFormat: uint16(format), aspect: dvAspectContent, index: index, tymed: tymed}
+	var m stgMedium
+	hr, _, _ := syscall.Syscall(do.vtbl()[3], 3, do.p,
+		uintptr(unsafe.Pointer(&fe)), uintptr(unsafe.Pointer(&m)))
+	if hr != 0 {
+		return nil, fmt.Errorf("failed to get data of format %d: %#x", format, hr)
+	}
+	return &m, nil
+}
+
+// read reads the data of the given format and index as a global memory
+// object.
+func (do dataObject) read(format uintptr, index int32, tymed uint32) ([]byte, error) {
+	m, err := do.get(format, index, tymed)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseStgMedium.Call(uintptr(unsafe.Pointer(m)))
+
+	p, _, err := gLock.Call(m.handle)
+	if p == 0 {
+		return nil, err
+	}
+	defer gUnlock.Call(m.handle)
+	n, _, _ := gSize.Call(m.handle)
+	buf := make([]byte, n)
+	if n > 0 {
+		memMove.Call(uintptr(unsafe.Pointer(&buf[0])), p, n)
+	}
+	return buf, nil
+}
+
+// save saves the FileContents of the given index to the file of the
+// given path. The contents are either a global memory object, whose size
+// may exceed the size of the file, or a stream.
+func (do dataObject) save(path string, format uintptr, index int, size int64) error {
+	m, err := do.get(format, int32(index), tymedHGlobal|tymedIStream)
+	if err != nil {
+		return err
+	}
+	defer releaseStgMedium.Call(uintptr(unsafe.Pointer(m)))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if m.tymed == tymedHGlobal {
+		p, _, err := gLock.Call(m.handle)
+		if p == 0 {
+			return err
+		}
+		defer gUnlock.Call(m.handle)
+		n, _, _ := gSize.Call(m.handle)
+		if size >= 0 && uint64(size) < uint64(n) {
+			n = uintptr(size)
+		}
+		if n == 0 {
+			return nil
+		}
+		buf := make([]byte, n)
+		memMove.Call(uintptr(unsafe.Pointer(&buf[0])), p, n)
+		_, err = f.Write(buf)
+		return err
+	}
+
+	// IStream inherits ISequentialStream, whose first method after the
+	// methods of IUnknown is Read.
+	read := (*[4]uintptr)(unsafe.Pointer(*(*uintptr)(unsafe.Pointer(m.handle))))[3]
+	buf := make([]byte, 64<<10)
+	for {
+		var n uint32
+		hr, _, _ := syscall.Syscall6(read, 4, m.handle, uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)), uintptr(unsafe.Pointer(&n)), 0, 0)
+		if int32(hr) < 0 {
+			return fmt.Errorf("failed to read file contents: %#x", hr)
+		}
+		if _, err := f.Write(buf[:n]); err != nil {
+			return err
+		}
+		if hr == sFalse || n == 0 {
+			return nil
+		}
+	}
 }
 
 const (
+	cFmtText        = 1
 	cFmtBitmap      = 2 // Win+PrintScreen
+	cFmtDIB         = 8
 	cFmtUnicodeText = 13
+	cFmtHDrop       = 15
 	cFmtDIBV5       = 17
 	// Screenshot taken from special shortcut is in different format (why??), see:
 	// https://jpsoft.com/forums/threads/detecting-clipboard-format.5225/
 	cFmtDataObject = 49161 // Shift+Win+s, returned from enumClipboardFormats
 	gmemMoveable   = 0x0002
+
+	// cFmtHTMLName is the name of the registered CF_HTML format.
+	cFmtHTMLName = "HTML Format"
+	// cFmtRTFName is the name of the registered RTF format.
+	cFmtRTFName = "Rich Text Format"
+	// cFmtPNGName is the name of the registered PNG format.
+	cFmtPNGName = "PNG"
+	// cFmtURLName is the name of the registered URL format of browsers.
+	cFmtURLName = "UniformResourceLocatorW"
+	// cFmtFileGroupDescriptorName is the name of the registered format
+	// that describes virtual files.
+	cFmtFileGroupDescriptorName = "FileGroupDescriptorW"
+	// cFmtFileContentsName is the name of the registered format of the
+	// data of virtual files.
+	cFmtFileContentsName = "FileContents"
 )
 
 // BITMAPV5Header structure, see:
@@ -529,6 +1257,46 @@ var (
 	// a valid clipboard format.
 	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-registerclipboardformata
 	registerClipboardFormatA = user32.MustFindProc("RegisterClipboardFormatA")
+	// Retrieves a handle to the foreground window.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getforegroundwindow
+	getForegroundWindow = user32.MustFindProc("GetForegroundWindow")
+	// Places the given window in the system-maintained clipboard format
+	// listener list.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-addclipboardformatlistener
+	addClipboardFormatListener = user32.MustFindProc("AddClipboardFormatListener")
+	// Registers a window class.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-registerclassexw
+	registerClassExW = user32.MustFindProc("RegisterClassExW")
+	// Creates a window, or a message-only window if the parent is
+	// HWND_MESSAGE.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-createwindowexw
+	createWindowExW = user32.MustFindProc("CreateWindowExW")
+	// Destroys the specified window.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-destroywindow
+	destroyWindow = user32.MustFindProc("DestroyWindow")
+	// Calls the default window procedure.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-defwindowprocw
+	defWindowProcW = user32.MustFindProc("DefWindowProcW")
+	// Retrieves a message from the message queue of the calling thread,
+	// and dispatches incoming sent messages.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getmessagew
+	getMessageW = user32.MustFindProc("GetMessageW")
+	// Dispatches a message to a window procedure.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-dispatchmessagew
+	dispatchMessageW = user32.MustFindProc("DispatchMessageW")
+	// Posts WM_QUIT to the message queue of the calling thread.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-postquitmessage
+	postQuitMessage = user32.MustFindProc("PostQuitMessage")
+	// Opens the desktop that receives user input.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-openinputdesktop
+	openInputDesktop = user32.MustFindProc("OpenInputDesktop")
+	// Closes an open handle to a desktop object.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-closedesktop
+	closeDesktop = user32.MustFindProc("CloseDesktop")
+	// Retrieves information about the specified window station or
+	// desktop object, such as its name.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getuserobjectinformationw
+	getUserObjectInformationW = user32.MustFindProc("GetUserObjectInformationW")
 
 	kernel32 = syscall.NewLazyDLL("kernel32")
 
@@ -546,6 +1314,91 @@ var (
 	gAlloc = kernel32.NewProc("GlobalAlloc")
 	// Frees the specified global memory object and invalidates its handle.
 	// https://docs.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-globalfree
-	gFree   = kernel32.NewProc("GlobalFree")
+	gFree = kernel32.NewProc("GlobalFree")
+	// Retrieves the current size of the specified global memory object, in bytes.
+	// https://docs.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-globalsize
+	gSize   = kernel32.NewProc("GlobalSize")
 	memMove = kernel32.NewProc("RtlMoveMemory")
+	// Maps a character string to a UTF-16 (wide character) string.
+	// https://docs.microsoft.com/en-us/windows/win32/api/stringapiset/nf-stringapiset-multibytetowidechar
+	multiByteToWideChar = kernel32.NewProc("MultiByteToWideChar")
+	// Retrieves a module handle, of the executable if the name is NULL.
+	// https://docs.microsoft.com/en-us/windows/win32/api/libloaderapi/nf-libloaderapi-getmodulehandlew
+	getModuleHandleW = kernel32.NewProc("GetModuleHandleW")
+
+	uiautomationcore = syscall.NewLazyDLL("uiautomationcore")
+
+	// Gets the host provider of a window.
+	// https://docs.microsoft.com/en-us/windows/win32/api/uiautomationcoreapi/nf-uiautomationcoreapi-uiahostproviderfromhwnd
+	uiaHostProviderFromHwnd = uiautomationcore.NewProc("UiaHostProviderFromHwnd")
+	// Raises a notification event, available since Windows 10 1709.
+	// https://docs.microsoft.com/en-us/windows/win32/api/uiautomationcoreapi/nf-uiautomationcoreapi-uiaraisenotificationevent
+	uiaRaiseNotificationEvent = uiautomationcore.NewProc("UiaRaiseNotificationEvent")
+
+	oleaut32 = syscall.NewLazyDLL("oleaut32")
+
+	// Allocates a new string and copies the passed string into it.
+	// https://docs.microsoft.com/en-us/windows/win32/api/oleauto/nf-oleauto-sysallocstring
+	sysAllocString = oleaut32.NewProc("SysAllocString")
+	// Deallocates a string allocated previously by SysAllocString.
+	// https://docs.microsoft.com/en-us/windows/win32/api/oleauto/nf-oleauto-sysfreestring
+	sysFreeString = oleaut32.NewProc("SysFreeString")
+
+	ole32 = syscall.NewLazyDLL("ole32")
+
+	// Initializes the COM library on the current thread for OLE.
+	// https://docs.microsoft.com/en-us/windows/win32/api/ole2/nf-ole2-oleinitialize
+	oleInitialize = ole32.NewProc("OleInitialize")
+	// Closes the COM library on the current thread.
+	// https://docs.microsoft.com/en-us/windows/win32/api/ole2/nf-ole2-oleuninitialize
+	oleUninitialize = ole32.NewProc("OleUninitialize")
+	// Retrieves a data object to access the contents of the clipboard.
+	// https://docs.microsoft.com/en-us/windows/win32/api/ole2/nf-ole2-olegetclipboard
+	oleGetClipboard = ole32.NewProc("OleGetClipboard")
+	// Frees the specified storage medium.
+	// https://docs.microsoft.com/en-us/windows/win32/api/ole2/nf-ole2-releasestgmedium
+	releaseStgMedium = ole32.NewProc("ReleaseStgMedium")
+)
+
+const (
+	desktopReadObjects = 0x0001
+	uoiName            = 2
 )
+
+// locked reports whether the input desktop is not the default desktop of
+// the session, which is the case while the session is locked, or the
+// secure desktop of a UAC prompt is shown, where the clipboard calls
+// fail spuriously. The secure desktop denies opening it, which counts as
+// locked as well.
+func locked() bool {
+	desk, _, _ := openInputDesktop.Call(0, 0, desktopReadObjects)
+	if desk == 0 {
+		return true
+	}
+	defer closeDesktop.Call(desk)
+
+	name := make([]uint16, 64)
+	var n uint32
+	r, _, _ := getUserObjectInformationW.Call(desk, uoiName,
+		uintptr(unsafe.Pointer(&name[0])), uintptr(len(name)*2),
+		uintptr(unsafe.Pointer(&n)))
+	if r == 0 {
+		return false
+	}
+	return !strings.EqualFold(syscall.UTF16ToString(name), "Default")
+}
+
+// newSystem returns ErrUnsupported, as there is a single clipboard on
+// Windows.
+func newSystem(c config) (system, error) {
+	return system{}, fmt.Errorf("%w: multiple clipboards", ErrUnsupported)
+}
+
+// readSelection reads the clipboard for SelectionClipboard, as there are
+// no other selections.
+func readSelection(s Selection, t Format) ([]byte, error) {
+	if s != SelectionClipboard {
+		return nil, ErrUnsupported
+	}
+	return read(t)
+}
@@ -10,12 +10,14 @@
 // demonstrates how the golang.design/x/clipboard can interact
 // with macOS/Linux/Windows/Android/iOS system clipboard.
 //
-// The gclip GUI application writes a string to the system clipboard
-// periodically then reads it back and renders it if possible.
+// The gclip GUI application watches the system clipboard for text and
+// images, and renders the latest change, as well as the events of the
+// watcher, such as the clipboard being cleared or failing to be read.
+// Tapping the screen writes a string to the system clipboard.
 //
 // Because of the system limitation, on mobile devices, only string
-// data is supported at the moment. Hence, one must use clipboard.FmtText.
-// Other supplied formats result in a panic.
+// data is supported at the moment. The watcher reports the image format
+// as unsupported there, until the mobile backends support images.
 //
 // This example is intentded as cross platform application.
 // To build it, one must use gomobile (https://golang.org/x/mobile).
@@ -37,13 +39,16 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
+	_ "image/png"
 	"log"
 	"os"
 	"sync"
-	"time"
 
 	"golang.design/x/clipboard"
 
@@ -54,6 +59,7 @@ import (
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
 	"golang.org/x/mobile/exp/gl/glutil"
 	"golang.org/x/mobile/geom"
 	"golang.org/x/mobile/gl"
@@ -138,6 +144,79 @@ func (l *Label) Release() {
 	}
 }
 
+// Picture renders the latest image of the clipboard below the label.
+type Picture struct {
+	images *glutil.Images
+	m      *glutil.Image
+
+	mu    sync.Mutex
+	img   image.Image
+	dirty bool
+}
+
+func NewPicture(images *glutil.Images) *Picture {
+	return &Picture{images: images}
+}
+
+// SetImage sets the image to render, or clears the picture if img is nil.
+func (p *Picture) SetImage(img image.Image) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.img = img
+	p.dirty = true
+}
+
+func (p *Picture) Draw(sz size.Event) {
+	p.mu.Lock()
+	img, dirty := p.img, p.dirty
+	p.dirty = false
+	p.mu.Unlock()
+
+	if dirty {
+		if p.m != nil {
+			p.m.Release()
+			p.m = nil
+		}
+		if img != nil {
+			b := img.Bounds()
+			p.m = p.images.NewImage(b.Dx(), b.Dy())
+			draw.Draw(p.m.RGBA, p.m.RGBA.Bounds(), img, b.Min, draw.Src)
+			p.m.Upload()
+		}
+	}
+	if p.m == nil || sz.PixelsPerPt == 0 {
+		return
+	}
+
+	// Fit the image into the screen below the label.
+	const top = 80
+	b := p.m.RGBA.Bounds()
+	w := geom.Pt(float32(b.Dx()) / sz.PixelsPerPt)
+	h := geom.Pt(float32(b.Dy()) / sz.PixelsPerPt)
+	if maxW := sz.WidthPt; w > maxW {
+		w, h = maxW, h*maxW/w
+	}
+	if maxH := sz.HeightPt - top; maxH > 0 && h > maxH {
+		w, h = w*maxH/h, maxH
+	}
+	p.m.Draw(
+		sz,
+		geom.Point{X: 0, Y: top},
+		geom.Point{X: w, Y: top},
+		geom.Point{X: 0, Y: top + h},
+		b,
+	)
+}
+
+func (p *Picture) Release() {
+	if p.m != nil {
+		p.m.Release()
+		p.m = nil
+		p.images = nil
+	}
+}
+
 // GclipApp is the application instance.
 type GclipApp struct {
 	app app.App
@@ -147,44 +226,70 @@ type GclipApp struct {
 
 	images *glutil.Images
 	l      *Label
+	p      *Picture
 
 	counter int
 }
 
-// WatchClipboard watches the system clipboard every seconds.
-func (g *GclipApp) WatchClipboard() {
+// WatchClipboard watches the system clipboard for text and images, and
+// renders the changes on the screen.
+func (g *GclipApp) WatchClipboard(ctx context.Context) {
+	events := clipboard.WatchEvents(ctx, clipboard.FmtText, clipboard.FmtImage)
 	go func() {
-		tk := time.NewTicker(time.Second)
-		for range tk.C {
-			// Write something to the clipboard
-			w := fmt.Sprintf("(gclip: %d)", g.counter)
-			clipboard.Write(clipboard.FmtText, []byte(w))
-			g.counter++
-			log.Println(w)
-
-			// Read it back and render it, if possible.
-			data := clipboard.Read(clipboard.FmtText)
-			if len(data) == 0 {
-				continue
+		for e := range events {
+			var r string
+			switch e.Kind {
+			case clipboard.EventChanged:
+				if e.Format == clipboard.FmtImage {
+					img, _, err := image.Decode(bytes.NewReader(e.Data))
+					if err != nil {
+						r = fmt.Sprintf("image: %v", err)
+						break
+					}
+					g.p.SetImage(img)
+					r = fmt.Sprintf("image: %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+					break
+				}
+				g.p.SetImage(nil)
+				r = fmt.Sprintf("clipboard: %s", string(e.Data))
+			case clipboard.EventCleared:
+				if e.Format == clipboard.FmtImage {
+					g.p.SetImage(nil)
+				}
+				r = fmt.Sprintf("%v: cleared", e.Format)
+			case clipboard.EventError:
+				r = fmt.Sprintf("%v: %v", e.Format, e.Err)
+			case clipboard.EventReconnected:
+				r = fmt.Sprintf("%v: reconnected", e.Format)
 			}
+			log.Println(r)
 
-			// Set the current clipboard data as label content and render on the screen.
-			r := fmt.Sprintf("clipboard: %s", string(data))
+			// Set the event as label content and render on the screen.
 			g.l.SetLabel(r)
 			g.app.Send(paint.Event{})
 		}
 	}()
 }
 
+// WriteClipboard writes a string to the system clipboard.
+func (g *GclipApp) WriteClipboard() {
+	w := fmt.Sprintf("(gclip: %d)", g.counter)
+	clipboard.Write(clipboard.FmtText, []byte(w))
+	g.counter++
+	log.Println(w)
+}
+
 func (g *GclipApp) OnStart(e lifecycle.Event) {
 	g.ctx, _ = e.DrawContext.(gl.Context)
 	g.images = glutil.NewImages(g.ctx)
 	g.l = NewLabel(g.images)
+	g.p = NewPicture(g.images)
 	g.app.Send(paint.Event{})
 }
 
 func (g *GclipApp) OnStop() {
 	g.l.Release()
+	g.p.Release()
 	g.images.Release()
 	g.ctx = nil
 }
@@ -202,6 +307,7 @@ func (g *GclipApp) OnDraw() {
 	g.ctx.ClearColor(0, 0, 0, 1)
 	g.ctx.Clear(gl.COLOR_BUFFER_BIT)
 	g.l.Draw(g.siz)
+	g.p.Draw(g.siz)
 }
 
 func init() {
@@ -215,7 +321,8 @@ func main() {
 	app.Main(func(a app.App) {
 		gclip := GclipApp{app: a}
 		gclip.app.Send(size.Event{WidthPx: 800, HeightPx: 500})
-		gclip.WatchClipboard()
+		gclip.WatchClipboard(context.Background())
+		gclip.WriteClipboard()
 		for e := range gclip.app.Events() {
 			switch e := gclip.app.Filter(e).(type) {
 			case lifecycle.Event:
@@ -230,6 +337,10 @@ func main() {
 				gclip.OnSize(e)
 			case paint.Event:
 				gclip.OnDraw()
+			case touch.Event:
+				if e.Type == touch.TypeBegin {
+					gclip.WriteClipboard()
+				}
 			}
 		}
 	})
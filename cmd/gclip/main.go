@@ -7,19 +7,28 @@
 package main // go install golang.design/x/clipboard/cmd/gclip@latest
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"golang.design/x/clipboard"
+	"golang.design/x/clipboard/formats"
 )
 
 func usage() {
 	fmt.Fprintf(os.Stderr, `gclip is a command that provides clipboard interaction.
 
-usage: gclip [-copy|-paste] [-f <file>]
+usage: gclip [-copy|-paste|-watch] [-f <file>] [-every <duration> [-jitter <duration>]] [-exec <command>] [-ocr <command>] [-html text|markdown] [-profile <name>] [-osascript-compat]
 
 options:
 `)
@@ -29,30 +38,58 @@ examples:
 gclip -paste                    paste from clipboard and prints the content
 gclip -paste -f x.txt           paste from clipboard and save as text to x.txt
 gclip -paste -f x.png           paste from clipboard and save as image to x.png
+gclip -paste -ocr 'ocr {}'      paste text from clipboard, or the text that ocr recognizes from the image
+gclip -paste -html markdown     paste copied rich content as markdown
 
 cat x.txt | gclip -copy         copy content from x.txt to clipboard
 gclip -copy -f x.txt            copy content from x.txt to clipboard
 gclip -copy -f x.png            copy x.png as image data to clipboard
+gclip -copy -every 30s -f x.txt copy content from x.txt to clipboard every 30 seconds
+gclip -profile remote -paste    paste using the options of profile "remote"
+
+gclip -watch                    print the content whenever the clipboard text changes
+gclip -watch -exec 'ocr {}'     run ocr with a file of the content whenever the clipboard changes
+
+gclip -copy -osascript-compat -f x.rtf  copy x.rtf as styled text like AppleScript (macOS)
+gclip -copy -osascript-compat -f x.pdf  copy x.pdf as a file reference like AppleScript (macOS)
 `)
 	os.Exit(2)
 }
 
 var (
-	in   = flag.Bool("copy", false, "copy data to clipboard")
-	out  = flag.Bool("paste", false, "paste data from clipboard")
-	file = flag.String("f", "", "source or destination to a given file path")
+	in     = flag.Bool("copy", false, "copy data to clipboard")
+	out    = flag.Bool("paste", false, "paste data from clipboard")
+	watch  = flag.Bool("watch", false, "watch text and image changes of clipboard")
+	file   = flag.String("f", "", "source or destination to a given file path")
+	script = flag.String("exec", "", "run the given command for each change of -watch, {} is replaced by the path of a file of the data,\nand GCLIP_FORMAT and GCLIP_MIME environment variables indicate the data format")
+	ocrCmd = flag.String("ocr", "", "paste the text that the given command prints for a copied image if there is no text,\n{} is replaced by the path of a file of the PNG image")
+	prof   = flag.String("profile", "", "use the options of the named profile in the gclip/profiles.json file of the user config directory")
+	htmlAs = flag.String("html", "", "paste copied HTML rendered as \"text\" or \"markdown\" instead of the plain text")
+	osa    = flag.Bool("osascript-compat", false, "copy data the way AppleScript's \"set the clipboard to\" does (macOS only)")
+	every  = flag.Duration("every", 0, "copy data to clipboard repeatedly at the given interval, the file is read again for every copy")
+	jitter = flag.Duration("jitter", 0, "add a random delay up to the given duration to the interval of -every")
 )
 
-func init() {
-	err := clipboard.Init()
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	var opts []clipboard.Option
+	if *prof != "" {
+		if err := loadProfiles(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load profiles: %v\n", err)
+			os.Exit(2)
+		}
+		opts = append(opts, clipboard.WithProfile(*prof))
+	}
+	if *ocrCmd != "" {
+		opts = append(opts, clipboard.WithOCR(ocr))
+	}
+	err := clipboard.Init(opts...)
 	if err != nil {
 		panic(err)
 	}
-}
 
-func main() {
-	flag.Usage = usage
-	flag.Parse()
 	if *out {
 		if err := pst(); err != nil {
 			usage()
@@ -65,10 +102,20 @@ func main() {
 		}
 		return
 	}
+	if *watch {
+		if err := wtch(); err != nil {
+			usage()
+		}
+		return
+	}
 	usage()
 }
 
 func cpy() error {
+	if *osa {
+		return cpyOSAScript()
+	}
+
 	t := clipboard.FmtText
 	ext := filepath.Ext(*file)
 
@@ -99,17 +146,133 @@ func cpy() error {
 		}
 	}
 
+	if *every > 0 {
+		return cpyEvery(t, b)
+	}
+
+	changed, err := clipboard.WriteErr(t, b)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write to clipboard: %v", err)
+		return err
+	}
+
 	// Wait until clipboard content has been changed.
-	<-clipboard.Write(t, b)
+	<-changed
+	return nil
+}
+
+// cpyEvery copies the given data to clipboard repeatedly at the interval
+// of -every, until the command is terminated. If the data is from a file,
+// the file is read again for every copy, so that changes of the file are
+// picked up. All copies share the clipboard that is initialized once.
+func cpyEvery(t clipboard.Format, b []byte) error {
+	if *jitter < 0 {
+		err := errors.New("-jitter must not be negative")
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		if _, err := clipboard.WriteErr(t, b); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write to clipboard: %v", err)
+			return err
+		}
+
+		d := *every
+		if *jitter > 0 {
+			d += time.Duration(r.Int63n(int64(*jitter)))
+		}
+		time.Sleep(d)
+
+		if *file != "" {
+			nb, err := os.ReadFile(*file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to read given file: %v", err)
+				return err
+			}
+			b = nb
+		}
+	}
+}
+
+// cpyOSAScript copies data the same way as AppleScript: RTF files are
+// copied as styled text, text files or stdin as plain text, and other
+// files as file references.
+func cpyOSAScript() error {
+	if runtime.GOOS != "darwin" {
+		err := errors.New("-osascript-compat is only supported on macOS")
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	kind := clipboard.OSAScriptText
+	switch filepath.Ext(*file) {
+	case ".rtf":
+		kind = clipboard.OSAScriptStyledText
+	case ".txt", "":
+		kind = clipboard.OSAScriptText
+	default:
+		kind = clipboard.OSAScriptFiles
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	switch {
+	case kind == clipboard.OSAScriptFiles:
+		var p string
+		p, err = filepath.Abs(*file)
+		b = []byte(p)
+	case *file != "":
+		b, err = os.ReadFile(*file)
+	default:
+		b, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read data: %v", err)
+		return err
+	}
+
+	changed, err := clipboard.WriteOSAScriptErr(kind, b)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write to clipboard: %v", err)
+		return err
+	}
+
+	// Wait until clipboard content has been changed.
+	<-changed
 	return nil
 }
 
 func pst() (err error) {
 	var b []byte
 
-	b = clipboard.Read(clipboard.FmtText)
-	if b == nil {
-		b = clipboard.Read(clipboard.FmtImage)
+	switch *htmlAs {
+	case "", "text", "markdown":
+	default:
+		err := fmt.Errorf("unknown -html rendering: %s", *htmlAs)
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	if *htmlAs != "" {
+		if h := clipboard.Read(clipboard.FmtHTML); h != nil {
+			b = []byte(renderHTML(h))
+		}
+	}
+
+	switch {
+	case b != nil:
+		// Rendered from HTML.
+	case *ocrCmd != "":
+		s, err := clipboard.ReadTextOrOCR()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read text: %v\n", err)
+			return err
+		}
+		b = []byte(s)
+	default:
+		b, _, _ = clipboard.ReadAny(clipboard.FmtText, clipboard.FmtImage)
 	}
 
 	if *file != "" && b != nil {
@@ -129,3 +292,117 @@ func pst() (err error) {
 	}
 	return nil
 }
+
+// renderHTML renders the given HTML as text or markdown according to
+// -html.
+func renderHTML(h []byte) string {
+	if *htmlAs == "markdown" {
+		return formats.HTMLToMarkdown(h)
+	}
+	return formats.HTMLToText(h)
+}
+
+// watched are the formats that -watch watches, and their MIME types and
+// file extensions.
+var watched = []struct {
+	t    clipboard.Format
+	mime string
+	ext  string
+}{
+	{clipboard.FmtText, "text/plain;charset=utf-8", ".txt"},
+	{clipboard.FmtImage, "image/png", ".png"},
+}
+
+// wtch watches the clipboard until the command is terminated. For each
+// change, the command of -exec runs with the data, or the text is printed
+// if there is no command.
+func wtch() error {
+	type change struct {
+		i    int
+		data []byte
+	}
+	changes := make(chan change)
+	for i, w := range watched {
+		go func(i int, ch <-chan []byte) {
+			for b := range ch {
+				changes <- change{i, b}
+			}
+		}(i, clipboard.Watch(context.Background(), w.t))
+	}
+
+	for c := range changes {
+		w := watched[c.i]
+		if *script == "" {
+			if w.t == clipboard.FmtText {
+				fmt.Println(string(c.data))
+			}
+			continue
+		}
+		if err := run(*script, w.t, w.mime, w.ext, c.data); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to run command: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// run runs the given command line using the shell, where {} is replaced
+// by the path of a temporary file that holds the given data. The data is
+// also available from the standard input of the command.
+func run(line string, t clipboard.Format, mime, ext string, data []byte) error {
+	c, cleanup, err := shell(line, ext, data)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	c.Env = append(os.Environ(), "GCLIP_FORMAT="+t.String(), "GCLIP_MIME="+mime)
+	c.Stdout = os.Stdout
+	return c.Run()
+}
+
+// ocr recognizes the text of the given PNG image using the command of
+// -ocr, which prints the text to its standard output.
+func ocr(png []byte) (string, error) {
+	c, cleanup, err := shell(*ocrCmd, ".png", png)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	out, err := c.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// shell returns the command that runs the given command line using the
+// shell, where {} is replaced by the path of a temporary file with the
+// given extension that holds the given data. The data is also the
+// standard input of the command. The caller must call cleanup to remove
+// the file after the command finishes.
+func shell(line, ext string, data []byte) (c *exec.Cmd, cleanup func(), err error) {
+	f, err := os.CreateTemp("", "gclip-*"+ext)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() { os.Remove(f.Name()) }
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	line = strings.ReplaceAll(line, "{}", f.Name())
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", line)
+	} else {
+		c = exec.Command("sh", "-c", line)
+	}
+	c.Stdin = bytes.NewReader(data)
+	c.Stderr = os.Stderr
+	return c, cleanup, nil
+}
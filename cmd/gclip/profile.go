@@ -0,0 +1,112 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.design/x/clipboard"
+)
+
+// profile is a profile of the configuration file of gclip, for instance:
+//
+//	{
+//		"remote-server": {"display": ":1", "read-timeout": "5s"},
+//		"windows-paste": {"write-line-ending": "crlf"}
+//	}
+type profile struct {
+	Display         string `json:"display"`
+	ReadTimeout     string `json:"read-timeout"`
+	PollInterval    string `json:"poll-interval"`
+	ReadLineEnding  string `json:"read-line-ending"`
+	WriteLineEnding string `json:"write-line-ending"`
+}
+
+// profilesPath returns the path of the configuration file of profiles.
+func profilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gclip", "profiles.json"), nil
+}
+
+// loadProfiles registers the profiles of the configuration file.
+func loadProfiles() error {
+	path, err := profilesPath()
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var ps map[string]profile
+	if err := json.Unmarshal(b, &ps); err != nil {
+		return fmt.Errorf("invalid profiles %s: %w", path, err)
+	}
+	for name, p := range ps {
+		opts, err := p.options()
+		if err != nil {
+			return fmt.Errorf("invalid profile %s: %w", name, err)
+		}
+		clipboard.RegisterProfile(name, opts...)
+	}
+	return nil
+}
+
+// options returns the clipboard options of the profile.
+func (p profile) options() ([]clipboard.Option, error) {
+	// An empty profile still selects the defaults.
+	opts := []clipboard.Option{clipboard.WithBackend(clipboard.BackendAuto)}
+	if p.Display != "" {
+		opts = append(opts, clipboard.WithDisplay(p.Display))
+	}
+	if p.ReadTimeout != "" {
+		d, err := time.ParseDuration(p.ReadTimeout)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, clipboard.WithReadTimeout(d))
+	}
+	if p.PollInterval != "" {
+		d, err := time.ParseDuration(p.PollInterval)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, clipboard.WithPollInterval(d))
+	}
+	if p.ReadLineEnding != "" {
+		e, err := lineEnding(p.ReadLineEnding)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, clipboard.WithReadLineEnding(e))
+	}
+	if p.WriteLineEnding != "" {
+		e, err := lineEnding(p.WriteLineEnding)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, clipboard.WithWriteLineEnding(e))
+	}
+	return opts, nil
+}
+
+// lineEnding returns the line ending of the given name.
+func lineEnding(name string) (clipboard.LineEnding, error) {
+	for _, e := range []clipboard.LineEnding{clipboard.LineEndingKeep, clipboard.LineEndingLF, clipboard.LineEndingCRLF} {
+		if e.String() == name {
+			return e, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown line ending: %s", name)
+}
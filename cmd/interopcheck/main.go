@@ -0,0 +1,265 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+// Command interopcheck exercises clipboard round-trips between this
+// package and the Windows clipboard consumers, i.e. PowerShell's
+// Get-Clipboard/Set-Clipboard and the WinRT DataPackage APIs, and reports
+// mismatches regarding encodings and formats. It helps to diagnose the
+// class of discrepancies where a check in a shell reports a mismatch
+// but pasting works fine.
+package main // go install golang.design/x/clipboard/cmd/interopcheck@latest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.design/x/clipboard"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `interopcheck checks clipboard interoperability with PowerShell and WinRT.
+
+usage: interopcheck [-v] [-shell <powershell>]
+
+options:
+`)
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+var (
+	verbose = flag.Bool("v", false, "print the details of every check")
+	shell   = flag.String("shell", "powershell", "the PowerShell executable, e.g. powershell or pwsh")
+)
+
+// cases are the text samples that commonly reveal encoding issues.
+var cases = []struct {
+	name string
+	text string
+}{
+	{"ascii", "golang.design/x/clipboard"},
+	{"unicode", "Hello, 世界, 你好，world"},
+	{"emoji", "clipboard 📋 ✂️"},
+	{"crlf", "line one\r\nline two\r\n"},
+	{"lf", "line one\nline two"},
+	{"trailing-newline", "text\n"},
+	{"empty", ""},
+}
+
+// awaitPrelude defines Await to block on WinRT async operations, and
+// loads the WinRT Clipboard type into the PowerShell session.
+const awaitPrelude = `
+Add-Type -AssemblyName System.Runtime.WindowsRuntime
+$asTask = ([System.WindowsRuntimeSystemExtensions].GetMethods() | Where-Object {
+	$_.Name -eq 'AsTask' -and $_.GetParameters().Count -eq 1 -and
+	$_.GetParameters()[0].ParameterType.Name -eq 'IAsyncOperation` + "`" + `1' })[0]
+function Await($op, [Type]$t) {
+	$task = $asTask.MakeGenericMethod($t).Invoke($null, @($op))
+	$task.Wait(-1) | Out-Null
+	$task.Result
+}
+$null = [Windows.ApplicationModel.DataTransfer.Clipboard, Windows.ApplicationModel.DataTransfer, ContentType = WindowsRuntime]
+$null = [Windows.ApplicationModel.DataTransfer.DataPackage, Windows.ApplicationModel.DataTransfer, ContentType = WindowsRuntime]
+`
+
+// Scripts exchange data in base64 encoded UTF-8 to avoid being affected
+// by the console code page.
+const (
+	psGet = `$t = Get-Clipboard -Raw
+if ($null -eq $t) { $t = '' }
+[Convert]::ToBase64String([Text.Encoding]::UTF8.GetBytes($t))`
+	psSet = `$t = [Text.Encoding]::UTF8.GetString([Convert]::FromBase64String('%s'))
+Set-Clipboard -Value $t`
+	psFormats = `Add-Type -AssemblyName System.Windows.Forms
+$o = [System.Windows.Forms.Clipboard]::GetDataObject()
+if ($null -ne $o) { $o.GetFormats() -join ',' }`
+	psImage = `Add-Type -AssemblyName System.Windows.Forms
+$img = [System.Windows.Forms.Clipboard]::GetImage()
+if ($null -ne $img) { '{0}x{1}' -f $img.Width, $img.Height }`
+	winrtGet = awaitPrelude + `
+$c = [Windows.ApplicationModel.DataTransfer.Clipboard]::GetContent()
+$t = Await ($c.GetTextAsync()) ([string])
+[Convert]::ToBase64String([Text.Encoding]::UTF8.GetBytes($t))`
+	winrtSet = awaitPrelude + `
+$t = [Text.Encoding]::UTF8.GetString([Convert]::FromBase64String('%s'))
+$p = New-Object Windows.ApplicationModel.DataTransfer.DataPackage
+$p.SetText($t)
+[Windows.ApplicationModel.DataTransfer.Clipboard]::SetContent($p)
+[Windows.ApplicationModel.DataTransfer.Clipboard]::Flush()`
+	winrtImage = awaitPrelude + `
+$c = [Windows.ApplicationModel.DataTransfer.Clipboard]::GetContent()
+$c.Contains([Windows.ApplicationModel.DataTransfer.StandardDataFormats]::Bitmap)`
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if runtime.GOOS != "windows" {
+		fmt.Fprintln(os.Stderr, "interopcheck: only supported on Windows")
+		os.Exit(1)
+	}
+	if err := clipboard.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "interopcheck: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	check := func(name string, err error) {
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("FAIL  %s: %v\n", name, err)
+		case *verbose:
+			fmt.Printf("ok    %s\n", name)
+		}
+	}
+
+	for _, c := range cases {
+		check("go -> Get-Clipboard/"+c.name, goToShell(c.text, psGet))
+		check("Set-Clipboard -> go/"+c.name, shellToGo(c.text, psSet))
+		check("go -> WinRT/"+c.name, goToShell(c.text, winrtGet))
+		check("WinRT -> go/"+c.name, shellToGo(c.text, winrtSet))
+	}
+	check("go -> formats/text", formats())
+	check("go -> Get-Clipboard/image", imageToShell(psImage, "2x3"))
+	check("go -> WinRT/image", imageToShell(winrtImage, "True"))
+
+	if failed > 0 {
+		fmt.Printf("%d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("all checks passed")
+}
+
+// goToShell writes text using this package and reads it back using the
+// given script that prints the base64 encoded clipboard text.
+func goToShell(text, script string) error {
+	clipboard.Write(clipboard.FmtText, []byte(text))
+	out, err := run(script)
+	if err != nil {
+		return err
+	}
+	got, err := base64.StdEncoding.DecodeString(out)
+	if err != nil {
+		return fmt.Errorf("unexpected output %q: %w", out, err)
+	}
+	return compare(text, string(got))
+}
+
+// shellToGo writes text using the given script that accepts the base64
+// encoded text and reads it back using this package.
+func shellToGo(text, script string) error {
+	_, err := run(fmt.Sprintf(script, base64.StdEncoding.EncodeToString([]byte(text))))
+	if err != nil {
+		return err
+	}
+	return compare(text, string(clipboard.Read(clipboard.FmtText)))
+}
+
+// formats reports the clipboard formats that are visible to .NET after
+// writing text using this package.
+func formats() error {
+	clipboard.Write(clipboard.FmtText, []byte("golang.design/x/clipboard"))
+	out, err := run(psFormats)
+	if err != nil {
+		return err
+	}
+	if *verbose {
+		fmt.Printf("      formats: %s\n", out)
+	}
+	for _, f := range []string{"UnicodeText", "Text"} {
+		if !strings.Contains(out, f) {
+			return fmt.Errorf("format %s is missing, got: %s", f, out)
+		}
+	}
+	return nil
+}
+
+// imageToShell writes a PNG image using this package and checks the
+// output of the given script.
+func imageToShell(script, want string) error {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 3))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(0, 0, color.NRGBA{R: 0xff, A: 0x80})
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return err
+	}
+	clipboard.Write(clipboard.FmtImage, b.Bytes())
+
+	out, err := run(script)
+	if err != nil {
+		return err
+	}
+	if out != want {
+		return fmt.Errorf("want %q, got %q", want, out)
+	}
+	return nil
+}
+
+func compare(want, got string) error {
+	if want == got {
+		return nil
+	}
+	hint := ""
+	switch {
+	case strings.ReplaceAll(want, "\r\n", "\n") == strings.ReplaceAll(got, "\r\n", "\n"):
+		hint = " (line endings differ)"
+	case strings.TrimRight(want, "\r\n") == strings.TrimRight(got, "\r\n"):
+		hint = " (trailing newlines differ)"
+	}
+	return fmt.Errorf("mismatch%s, want %q, got %q", hint, want, got)
+}
+
+// run runs the given PowerShell script and returns its trimmed output.
+func run(script string) (string, error) {
+	// -EncodedCommand accepts base64 encoded UTF-16LE scripts.
+	u := utf16.Encode([]rune(script))
+	b := make([]byte, 2*len(u))
+	for i, v := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], v)
+	}
+	cmd := exec.Command(*shell, "-NoProfile", "-NonInteractive", "-STA",
+		"-EncodedCommand", base64.StdEncoding.EncodeToString(b))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	done := make(chan error, 1)
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	case <-time.After(30 * time.Second):
+		cmd.Process.Kill()
+		return "", errors.New("script timed out")
+	}
+	if s := strings.TrimSpace(stderr.String()); s != "" {
+		return "", errors.New(s)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
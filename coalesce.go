@@ -0,0 +1,101 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"sync"
+	"time"
+)
+
+// CoalescingWriter coalesces rapid successive writes, for instance, of
+// applications that copy the text on every keystroke, so that only the
+// final data is written to the clipboard once the writes have settled.
+// This saves the clipboard managers and watchers of other applications
+// from every intermediate state. It is safe for concurrent use.
+type CoalescingWriter struct {
+	d time.Duration
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending bool
+	t       Format
+	buf     []byte
+	changed <-chan struct{}
+	err     error
+
+	// committing serializes the writes of the pending data, so that an
+	// older data never overwrites a newer one.
+	committing sync.Mutex
+}
+
+// NewCoalescingWriter returns a writer that writes the data of its
+// latest Write to the clipboard once no other Write follows within d.
+func NewCoalescingWriter(d time.Duration) *CoalescingWriter {
+	return &CoalescingWriter{d: d}
+}
+
+// Write schedules the given data in format t to be written to the
+// clipboard, which replaces the data of the previous Write that is not
+// yet written. The data is copied, hence the caller may reuse buf.
+func (w *CoalescingWriter) Write(t Format, buf []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.t, w.buf, w.pending = t, append([]byte(nil), buf...), true
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.d, func() { w.commit() })
+		return
+	}
+	w.timer.Reset(w.d)
+}
+
+// Flush writes the pending data to the clipboard immediately, for
+// instance, before the application exits. Like WriteErr, it returns the
+// channel that receives a signal if the clipboard has been overwritten
+// from the latest write of w, and the error of the latest write.
+func (w *CoalescingWriter) Flush() (<-chan struct{}, error) {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+
+	w.commit()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.changed, w.err
+}
+
+// Err returns the error of the latest write to the clipboard, which
+// happens in the background after the writes have settled.
+func (w *CoalescingWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// commit writes the pending data, if any, to the clipboard.
+func (w *CoalescingWriter) commit() {
+	w.committing.Lock()
+	defer w.committing.Unlock()
+
+	w.mu.Lock()
+	if !w.pending {
+		w.mu.Unlock()
+		return
+	}
+	t, buf := w.t, w.buf
+	w.pending, w.buf = false, nil
+	w.mu.Unlock()
+
+	changed, err := WriteErr(t, buf)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.changed, w.err = changed, err
+}
@@ -0,0 +1,39 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"encoding/binary"
+	"image/color"
+)
+
+// colorSize is the size of the data of FmtColor.
+const colorSize = 8
+
+// encodeColor returns the data of FmtColor of the given color.
+func encodeColor(c color.Color) []byte {
+	n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+	buf := make([]byte, colorSize)
+	binary.BigEndian.PutUint16(buf[0:], n.R)
+	binary.BigEndian.PutUint16(buf[2:], n.G)
+	binary.BigEndian.PutUint16(buf[4:], n.B)
+	binary.BigEndian.PutUint16(buf[6:], n.A)
+	return buf
+}
+
+// decodeColor returns the color of the given data of FmtColor.
+func decodeColor(buf []byte) (color.NRGBA64, error) {
+	if len(buf) != colorSize {
+		return color.NRGBA64{}, &MalformedError{Format: FmtColor, Reason: "invalid color size"}
+	}
+	return color.NRGBA64{
+		R: binary.BigEndian.Uint16(buf[0:]),
+		G: binary.BigEndian.Uint16(buf[2:]),
+		B: binary.BigEndian.Uint16(buf[4:]),
+		A: binary.BigEndian.Uint16(buf[6:]),
+	}, nil
+}
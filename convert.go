@@ -0,0 +1,236 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sync"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+// MIME types of the data represented by the supported formats.
+const (
+	mimeText  = "text/plain;charset=utf-8"
+	mimeHTML  = "text/html"
+	mimeRTF   = "text/rtf"
+	mimeURIs  = "text/uri-list"
+	mimeURL   = "text/x-moz-url"
+	mimeColor = "application/x-color"
+	mimeVCard = "text/vcard"
+	mimeICal  = "text/calendar"
+	mimePNG   = "image/png"
+	mimeBMP   = "image/bmp"
+	mimeTIFF  = "image/tiff"
+	mimeJPEG  = "image/jpeg"
+	mimeGIF   = "image/gif"
+	mimeWebP  = "image/webp"
+)
+
+// mimeOf returns the MIME type of the data of a given format.
+func mimeOf(t Format) string {
+	switch t {
+	case FmtText:
+		return mimeText
+	case FmtImage:
+		return mimePNG
+	case FmtHTML:
+		return mimeHTML
+	case FmtRTF:
+		return mimeRTF
+	case FmtFiles:
+		return mimeURIs
+	case FmtURL:
+		return mimeURL
+	case FmtColor:
+		return mimeColor
+	case FmtVCard:
+		return mimeVCard
+	case FmtICal:
+		return mimeICal
+	}
+	return ""
+}
+
+// sniffImage returns the MIME type of the given encoded image, or an
+// empty string if the encoding is not known.
+func sniffImage(buf []byte) string {
+	switch {
+	case bytes.HasPrefix(buf, pngSignature):
+		return mimePNG
+	case bytes.HasPrefix(buf, []byte("II*\x00")), bytes.HasPrefix(buf, []byte("MM\x00*")):
+		return mimeTIFF
+	case bytes.HasPrefix(buf, []byte("BM")):
+		return mimeBMP
+	case bytes.HasPrefix(buf, []byte("\xff\xd8\xff")):
+		return mimeJPEG
+	case bytes.HasPrefix(buf, []byte("GIF8")):
+		return mimeGIF
+	case len(buf) >= 12 && string(buf[:4]) == "RIFF" && string(buf[8:12]) == "WEBP":
+		return mimeWebP
+	}
+	return ""
+}
+
+// representation is clipboard data encoded in a MIME type. A write may
+// offer additional representations of its data, so that the reading
+// application picks the one it understands.
+type representation struct {
+	mime string
+	data []byte
+	// render renders the data on demand if it is not nil, which the
+	// X11 backend uses to offer conversions that are only paid for if
+	// a requestor asks for them.
+	render func() ([]byte, error)
+}
+
+// Converter converts clipboard data that is encoded in one MIME type
+// to another MIME type, for instance, from image/bmp to image/png.
+type Converter func(src []byte) ([]byte, error)
+
+type converter struct {
+	from, to string
+	fn       Converter
+}
+
+var (
+	convMu     sync.RWMutex
+	converters []converter
+
+	errNoConverter = errors.New("no converter available")
+)
+
+func init() {
+	RegisterConverter(mimeBMP, mimePNG, transcoder(bmp.Decode, png.Encode))
+	RegisterConverter(mimeTIFF, mimePNG, transcoder(tiff.Decode, png.Encode))
+	RegisterConverter(mimeJPEG, mimePNG, jpegToPNG)
+	RegisterConverter(mimeGIF, mimePNG, transcoder(gif.Decode, png.Encode))
+	RegisterConverter(mimeWebP, mimePNG, transcoder(webp.Decode, png.Encode))
+
+	RegisterConverter(mimePNG, mimeJPEG, transcoder(png.Decode,
+		func(w io.Writer, m image.Image) error { return jpeg.Encode(w, m, &jpeg.Options{Quality: 95}) }))
+	RegisterConverter(mimePNG, mimeGIF, transcoder(png.Decode,
+		func(w io.Writer, m image.Image) error { return gif.Encode(w, m, nil) }))
+	RegisterConverter(mimePNG, mimeBMP, transcoder(png.Decode, bmp.Encode))
+	RegisterConverter(mimePNG, mimeTIFF, transcoder(png.Decode,
+		func(w io.Writer, m image.Image) error { return tiff.Encode(w, m, nil) }))
+}
+
+// RegisterConverter registers a converter that converts data from
+// MIME type from to MIME type to. Registering a converter for a pair
+// of MIME types that already has a converter replaces the existing one.
+//
+// When the clipboard does not offer a requested format, Read consults
+// the registered converters and tries to satisfy the request using
+// other available representations. Converters registered earlier are
+// preferred. For example, to read HEIC images copied from Apple apps:
+//
+//	clipboard.RegisterConverter("image/heic", "image/png", heicToPNG)
+//
+// A nil converter unregisters the converter for the given pair.
+func RegisterConverter(from, to string, c Converter) {
+	convMu.Lock()
+	defer convMu.Unlock()
+
+	for i := range converters {
+		if converters[i].from != from || converters[i].to != to {
+			continue
+		}
+		if c == nil {
+			converters = append(converters[:i], converters[i+1:]...)
+		} else {
+			converters[i].fn = c
+		}
+		return
+	}
+	if c == nil {
+		return
+	}
+	converters = append(converters, converter{from: from, to: to, fn: c})
+}
+
+// Convert converts the given buf from MIME type from to MIME type to
+// using the registered converters. It returns an error if there is
+// no converter registered for the given pair.
+func Convert(from, to string, buf []byte) ([]byte, error) {
+	if from == to {
+		return buf, nil
+	}
+
+	convMu.RLock()
+	var fn Converter
+	for _, c := range converters {
+		if c.from == from && c.to == to {
+			fn = c.fn
+			break
+		}
+	}
+	convMu.RUnlock()
+
+	if fn == nil {
+		return nil, fmt.Errorf("%w: %s to %s", errNoConverter, from, to)
+	}
+	return fn(buf)
+}
+
+// convertible returns all MIME types that can be converted to the
+// given MIME type, in the order of preference.
+func convertible(to string) []string {
+	convMu.RLock()
+	defer convMu.RUnlock()
+
+	var from []string
+	for _, c := range converters {
+		if c.to == to {
+			from = append(from, c.from)
+		}
+	}
+	return from
+}
+
+// negotiate tries to satisfy a read of MIME type to by reading other
+// available representations using readFn and converting them.
+func negotiate(to string, readFn func(mime string) ([]byte, error)) ([]byte, error) {
+	for _, from := range convertible(to) {
+		buf, err := readFn(from)
+		if err != nil || len(buf) == 0 {
+			continue
+		}
+		buf, err = Convert(from, to, buf)
+		if err != nil {
+			continue
+		}
+		return buf, nil
+	}
+	return nil, ErrUnavailable
+}
+
+// transcoder returns a converter that decodes an image using decode
+// and encodes it using encode.
+func transcoder(decode func(io.Reader) (image.Image, error),
+	encode func(io.Writer, image.Image) error) Converter {
+	return func(src []byte) ([]byte, error) {
+		img, err := decode(bytes.NewReader(src))
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
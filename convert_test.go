@@ -0,0 +1,86 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.design/x/clipboard"
+	"golang.org/x/image/bmp"
+)
+
+func TestConvert(t *testing.T) {
+	t.Run("builtin", func(t *testing.T) {
+		data, err := os.ReadFile("tests/testdata/clipboard.png")
+		if err != nil {
+			t.Fatalf("failed to read gold file: %v", err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to decode gold file: %v", err)
+		}
+		var b bytes.Buffer
+		if err := bmp.Encode(&b, img); err != nil {
+			t.Fatalf("failed to encode bmp: %v", err)
+		}
+
+		out, err := clipboard.Convert("image/bmp", "image/png", b.Bytes())
+		if err != nil {
+			t.Fatalf("failed to convert bmp to png: %v", err)
+		}
+		got, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("converted data is not png encoded: %v", err)
+		}
+		if got.Bounds() != img.Bounds() {
+			t.Fatalf("converted image has different bounds, got: %v, want: %v", got.Bounds(), img.Bounds())
+		}
+	})
+
+	t.Run("encodings", func(t *testing.T) {
+		data, err := os.ReadFile("tests/testdata/clipboard.png")
+		if err != nil {
+			t.Fatalf("failed to read gold file: %v", err)
+		}
+		for _, mime := range []string{"image/jpeg", "image/gif", "image/bmp", "image/tiff"} {
+			enc, err := clipboard.Convert("image/png", mime, data)
+			if err != nil {
+				t.Fatalf("failed to convert png to %s: %v", mime, err)
+			}
+			out, err := clipboard.Convert(mime, "image/png", enc)
+			if err != nil {
+				t.Fatalf("failed to convert %s to png: %v", mime, err)
+			}
+			if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+				t.Fatalf("converted data from %s is not png encoded: %v", mime, err)
+			}
+		}
+	})
+
+	t.Run("register", func(t *testing.T) {
+		const from, to = "text/x-golang-design-upper", "text/plain;charset=utf-8"
+		defer clipboard.RegisterConverter(from, to, nil)
+
+		if _, err := clipboard.Convert(from, to, []byte("x")); err == nil {
+			t.Fatalf("expect to fail without registered converter")
+		}
+		clipboard.RegisterConverter(from, to, func(src []byte) ([]byte, error) {
+			return []byte(strings.ToLower(string(src))), nil
+		})
+		out, err := clipboard.Convert(from, to, []byte("GOLANG.DESIGN"))
+		if err != nil {
+			t.Fatalf("failed to convert: %v", err)
+		}
+		if string(out) != "golang.design" {
+			t.Fatalf("converted data mismatch, got: %s, want: golang.design", out)
+		}
+	})
+}
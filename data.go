@@ -0,0 +1,60 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+// ReadData reads the clipboard data of the given MIME type as is, for
+// instance, "audio/mpeg", without any conversion. It returns
+// ErrUnavailable if the clipboard holds no data of the MIME type. On
+// macOS, a uniform type identifier, such as "public.mp3", is accepted
+// as well. Reading data of arbitrary MIME types is not supported on iOS
+// and Android.
+func ReadData(mime string) ([]byte, error) {
+	if err := ready(); err != nil {
+		return nil, err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	buf, err := sys.readData(mime)
+	if err != nil {
+		return nil, err
+	}
+	if buf == nil {
+		return nil, ErrUnavailable
+	}
+	return buf, nil
+}
+
+// WriteData writes the given data to the clipboard as the given MIME
+// type, so that binary payloads, such as audio snippets or proprietary
+// data of an application, can be put on the clipboard without pretending
+// to be text. On macOS, a uniform type identifier is accepted as well,
+// and on Windows, the data is written as the clipboard format that is
+// registered with the MIME type as its name.
+//
+// Like WriteErr, the returned channel receives a signal if the clipboard
+// has been overwritten from this write.
+func WriteData(mime string, buf []byte) (<-chan struct{}, error) {
+	if err := ready(); err != nil {
+		return nil, err
+	}
+	if mime == "" {
+		return nil, ErrUnsupported
+	}
+	extra := []representation{origin(false, sideRef{})}
+
+	release, err := arbitrate()
+	if err != nil {
+		return nil, err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	changed, err := sys.writeData(mime, buf, extra)
+	release()
+	return changed, err
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"math"
+
+	"golang.design/x/clipboard/formats"
+)
+
+// inchesPerMeter converts pixels per meter to dots per inch.
+const inchesPerMeter = 39.37007874015748
+
+// ImageDPI returns the resolution of PNG encoded image data in dots per
+// inch, and reports whether the image specifies its resolution.
+//
+// On Windows, the resolution is retained when the image is written to
+// or read from the clipboard, so that, for instance, a screenshot taken
+// on a scaled monitor pastes at its physical size in other applications.
+func ImageDPI(buf []byte) (x, y float64, ok bool) {
+	xppm, yppm, ok := formats.PNGResolution(buf)
+	if !ok {
+		return 0, 0, false
+	}
+	return float64(xppm) / inchesPerMeter, float64(yppm) / inchesPerMeter, true
+}
+
+// ImageWithDPI returns a copy of PNG encoded image data that specifies
+// the given resolution in dots per inch. It returns an error if buf is
+// not PNG encoded.
+func ImageWithDPI(buf []byte, x, y float64) ([]byte, error) {
+	return formats.WithPNGResolution(buf,
+		uint32(math.Round(x*inchesPerMeter)), uint32(math.Round(y*inchesPerMeter)))
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"os"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestImageDPI(t *testing.T) {
+	data, err := os.ReadFile("tests/testdata/clipboard.png")
+	if err != nil {
+		t.Fatalf("failed to read gold file: %v", err)
+	}
+
+	out, err := clipboard.ImageWithDPI(data, 192, 96)
+	if err != nil {
+		t.Fatalf("failed to set image dpi: %v", err)
+	}
+	x, y, ok := clipboard.ImageDPI(out)
+	if !ok {
+		t.Fatalf("image dpi is not set")
+	}
+	if math.Abs(x-192) > 0.1 || math.Abs(y-96) > 0.1 {
+		t.Fatalf("image dpi mismatch, got: %vx%v, want: 192x96", x, y)
+	}
+
+	// Setting the resolution again replaces the existing one.
+	out, err = clipboard.ImageWithDPI(out, 144, 144)
+	if err != nil {
+		t.Fatalf("failed to set image dpi: %v", err)
+	}
+	if x, y, _ := clipboard.ImageDPI(out); math.Abs(x-144) > 0.1 || math.Abs(y-144) > 0.1 {
+		t.Fatalf("image dpi mismatch, got: %vx%v, want: 144x144", x, y)
+	}
+
+	img1, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode gold file: %v", err)
+	}
+	img2, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("image with dpi is not PNG encoded: %v", err)
+	}
+	if img1.Bounds() != img2.Bounds() {
+		t.Fatalf("image with dpi has different bounds, got: %v, want: %v", img2.Bounds(), img1.Bounds())
+	}
+
+	if _, err := clipboard.ImageWithDPI([]byte("not png"), 96, 96); err == nil {
+		t.Fatalf("expect to fail for data that is not PNG encoded")
+	}
+}
@@ -54,3 +54,21 @@ func ExampleWatch() {
 	// Output:
 	// 你好，world
 }
+
+func ExampleWatchFiles() {
+	err := clipboard.Init()
+	if err != nil {
+		panic(err)
+	}
+
+	// Open the copied files in the window until the window is closed,
+	// which cancels the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for paths := range clipboard.WatchFiles(ctx, "") {
+		for _, p := range paths {
+			fmt.Println("open", p)
+		}
+	}
+}
@@ -6,8 +6,157 @@
 
 package clipboard
 
+import (
+	"time"
+)
+
 // for debugging errors
 var (
-	Debug          = debug
-	ErrUnavailable = errUnavailable
+	Debug = debug
+)
+
+// for testing internal logics
+var (
+	TuneFor      = tuneFor
+	Announcement = announcement
+	Validate     = validate
+	DecodeCFHTML = decodeCFHTML
+	DecodeDrop   = decodeDropFiles
+	VirtualPath  = virtualPath
+	DecodeURL16  = decodeURL16
+	TextURL      = textURL
+	ConvertLines = convertLineEndings
+	DecodeLatin1 = decodeLatin1
+	EncodeLatin1 = encodeLatin1
+	PutToken     = tokens.put
+	TakeToken    = tokens.take
 )
+
+// Arbitrate acquires the arbitration lock the way another cooperating
+// process does, and returns the function that releases it.
+func Arbitrate() (release func(), err error) {
+	f, err := acquireArbitration()
+	if err != nil {
+		return nil, err
+	}
+	return func() { releaseArbitration(f) }, nil
+}
+
+// CachedRead returns the cached data of format t at the current sequence
+// number of the clipboard, see SetReadCache.
+func CachedRead(t Format) ([]byte, bool) {
+	seq, ok := sys.sequence()
+	if !ok {
+		return nil, false
+	}
+	return cache.get(seq, t)
+}
+
+// SetLocked replaces the detection of a locked session with fn, and
+// returns the function that restores it.
+func SetLocked(fn func() bool) (restore func()) {
+	mon.polling.Lock()
+	defer mon.polling.Unlock()
+
+	prev := sys.locked
+	sys.locked = fn
+	return func() {
+		mon.polling.Lock()
+		defer mon.polling.Unlock()
+		sys.locked = prev
+	}
+}
+
+// SetOCR sets the optical character recognition engine of WithOCR.
+func SetOCR(fn func(png []byte) (string, error)) { ocr = fn }
+
+// ProfileDisplay returns the display and the error that the given
+// options configure.
+func ProfileDisplay(opts ...Option) (string, error) {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c.display, c.err
+}
+
+// MattedImage returns the data of the matted variant of the given image.
+func MattedImage(buf []byte) []byte {
+	r, _ := matted(FmtImage, buf)
+	return r.data
+}
+
+// WatchTick returns the interval that the change detection loop ticks at
+// for watchers of the given intervals, if the interval of the monitor is
+// interval.
+func WatchTick(interval time.Duration, watchers ...time.Duration) time.Duration {
+	m := &monitor{interval: interval, subs: map[*subscriber]struct{}{}}
+	for _, d := range watchers {
+		m.subs[&subscriber{interval: d}] = struct{}{}
+	}
+	return m.shortest()
+}
+
+// Backpressured delivers n changes to a watcher of the given policy and
+// buffer, and returns the changes that the watcher receives.
+func Backpressured(p Backpressure, buffer, n int) []int {
+	s := &subscriber{mail: make(chan Event, buffer), policy: p, done: make(chan struct{})}
+	delivered := make(chan struct{})
+	go func() {
+		for i := 0; i < n; i++ {
+			s.deliver(Event{Size: i})
+		}
+		close(delivered)
+	}()
+
+	var got []int
+	if p == Block {
+		for i := 0; i < n; i++ {
+			got = append(got, (<-s.mail).Size)
+		}
+		return got
+	}
+	<-delivered
+	for len(s.mail) > 0 {
+		got = append(got, (<-s.mail).Size)
+	}
+	return got
+}
+
+// SideFetch offers the given representations through the side channel,
+// and fetches the data of the given MIME type through the socket using
+// the reference of the offer if token is empty, or the given token
+// otherwise.
+func SideFetch(reps map[string][]byte, mime, token string) ([]byte, bool) {
+	ref, err := side.offer(reps)
+	if err != nil {
+		return nil, false
+	}
+	defer side.withdraw(ref.Token)
+	if token != "" {
+		ref.Token = token
+	}
+	return ref.fetch(mime)
+}
+
+// Tracker tracks change channels of writes for the given sequence
+// numbers, see NewTracker.
+type Tracker = tracker
+
+// NewTracker returns a tracker of change channels that checks the given
+// sequence number every interval.
+func NewTracker(sequence func() (uint64, bool), interval time.Duration) *Tracker {
+	return newTracker(sequence, func() time.Duration { return interval })
+}
+
+func (tr *tracker) Track(seq uint64) <-chan struct{} { return tr.track(seq) }
+func (tr *tracker) Untrack(changed <-chan struct{})  { tr.untrack(changed) }
+func (tr *tracker) Notify()                          { tr.notify() }
+func (tr *tracker) Pending() int                     { return tr.pending() }
+
+// Running reports whether the tracking loop is running.
+func (tr *tracker) Running() bool {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.running
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.design/x/clipboard/formats"
+)
+
+// splitFiles splits the data of FmtFiles into file paths.
+func splitFiles(buf []byte) []string {
+	var paths []string
+	for _, p := range strings.Split(string(buf), "\n") {
+		if p = strings.TrimSuffix(p, "\r"); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// joinFiles joins file paths into the data of FmtFiles.
+func joinFiles(paths []string) []byte {
+	return []byte(strings.Join(paths, "\n"))
+}
+
+// decodeDropFiles decodes the data of CF_HDROP to the data of FmtFiles.
+// It returns a *MalformedError if the DROPFILES structure is truncated.
+func decodeDropFiles(buf []byte) ([]byte, error) {
+	paths, err := formats.DecodeDropFiles(buf)
+	var ferr *formats.Error
+	if errors.As(err, &ferr) {
+		return nil, &MalformedError{Format: FmtFiles, Reason: ferr.Reason}
+	}
+	return joinFiles(paths), err
+}
+
+// virtualPath returns the path in dir to save a virtual file of the given
+// name to, which may be a relative path using slashes or backslashes to
+// place the file in a subdirectory. It returns an error if the name is
+// absolute or escapes dir, which a malicious clipboard owner could offer
+// to overwrite other files.
+func virtualPath(dir, name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	clean := path.Clean(name)
+	// A colon indicates a drive or an alternate data stream on Windows.
+	if name == "" || clean == "." || path.IsAbs(clean) || strings.Contains(clean, ":") ||
+		clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid virtual file name: %q", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+// virtualDir returns the directory to save virtual files into, which is
+// dir, or a new temporary directory if dir is empty. Platforms call it
+// once they find virtual files, so that no directory is created for a
+// clipboard without files.
+func virtualDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+	return os.MkdirTemp("", "clipboard-files-")
+}
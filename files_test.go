@@ -0,0 +1,67 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.design/x/clipboard"
+	"golang.design/x/clipboard/formats"
+)
+
+func TestDropFiles(t *testing.T) {
+	files := []string{`C:\Users\gopher\a b.txt`, `D:\数据`}
+
+	got, err := clipboard.DecodeDrop(formats.EncodeDropFiles(files))
+	if err != nil {
+		t.Fatalf("failed to decode DROPFILES: %v", err)
+	}
+	if want := files[0] + "\n" + files[1]; string(got) != want {
+		t.Fatalf("decoded files mismatch, got: %q, want: %q", got, want)
+	}
+
+	// ANSI paths, as written by legacy applications.
+	ansi := append(make([]byte, 20), "C:\\a\x00C:\\b\x00\x00"...)
+	ansi[0] = 20
+	got, err = clipboard.DecodeDrop(ansi)
+	if err != nil {
+		t.Fatalf("failed to decode DROPFILES: %v", err)
+	}
+	if string(got) != "C:\\a\nC:\\b" {
+		t.Fatalf("decoded files mismatch, got: %q", got)
+	}
+
+	for _, m := range [][]byte{{20, 0}, append([]byte{99}, make([]byte, 19)...)} {
+		_, err := clipboard.DecodeDrop(m)
+		var merr *clipboard.MalformedError
+		if !errors.As(err, &merr) {
+			t.Fatalf("expect a MalformedError for %v, got: %v", m, err)
+		}
+	}
+}
+
+func TestVirtualPath(t *testing.T) {
+	dir := filepath.FromSlash("/tmp/paste")
+	for _, name := range []string{"a.txt", `archive\b.txt`, "archive/c/../d.txt"} {
+		p, err := clipboard.VirtualPath(dir, name)
+		if err != nil {
+			t.Fatalf("failed to resolve %q: %v", name, err)
+		}
+		if rel, err := filepath.Rel(dir, p); err != nil || strings.HasPrefix(rel, "..") {
+			t.Fatalf("resolved %q out of the directory: %v", name, p)
+		}
+	}
+	// Malicious names must not escape the directory.
+	for _, name := range []string{"", `..\evil.exe`, "a/../../evil", "/etc/passwd", `C:\evil.exe`, "a.txt:stream"} {
+		if p, err := clipboard.VirtualPath(dir, name); err == nil {
+			t.Fatalf("expect an error for %q, got: %v", name, p)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// srgbGamma is the decoding gamma that approximates the transfer function
+// of sRGB, which PNG specifies as a gAMA of 45455.
+const srgbGamma = 2.2
+
+// PNGGamma returns the decoding gamma of PNG encoded data, which is 2.2
+// if the data is tagged as sRGB by its sRGB chunk, or the reciprocal of
+// the gAMA chunk otherwise. It reports false if the data has no color
+// space information, in which case sRGB is assumed by convention.
+func PNGGamma(buf []byte) (gamma float64, ok bool) {
+	pngChunks(buf, func(typ string, data []byte, off int) bool {
+		switch typ {
+		case "IDAT":
+			// The color space chunks must precede the image data.
+			return false
+		case "sRGB":
+			// sRGB overrides gAMA.
+			gamma, ok = srgbGamma, true
+			return false
+		case "gAMA":
+			if len(data) == 4 {
+				if g := binary.BigEndian.Uint32(data); g > 0 {
+					gamma, ok = 100000/float64(g), true
+				}
+			}
+		}
+		return true
+	})
+	return
+}
+
+// WithPNGSRGB returns a copy of PNG encoded data that is explicitly
+// tagged as sRGB using the sRGB chunk and the gAMA chunk for decoders
+// that only understand the latter, so that color managed applications
+// do not guess the color space. Existing color space chunks are replaced,
+// the pixels are not converted. It returns an *Error if buf is not PNG
+// encoded.
+func WithPNGSRGB(buf []byte) ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	err := pngChunks(buf, func(typ string, data []byte, off int) bool {
+		switch typ {
+		case "sRGB", "gAMA", "iCCP", "cHRM":
+			return true
+		}
+		out.Write(buf[off : off+12+len(data)])
+		if typ == "IHDR" {
+			writePNGChunk(&out, "sRGB", []byte{0}) // perceptual intent
+			gama := make([]byte, 4)
+			binary.BigEndian.PutUint32(gama, 45455)
+			writePNGChunk(&out, "gAMA", gama)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// isSRGBGamma reports whether the given decoding gamma is close enough to
+// sRGB to skip the conversion.
+func isSRGBGamma(g float64) bool {
+	return math.Abs(g-srgbGamma) < 0.05
+}
+
+// toSRGB converts the pixels of img that are encoded using the given
+// decoding gammas of the red, green and blue channels to sRGB.
+func toSRGB(img image.Image, gamma [3]float64) *image.NRGBA {
+	b := img.Bounds()
+	m := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(m, m.Bounds(), img, b.Min, draw.Src)
+
+	var luts [3][256]uint8
+	for c := range luts {
+		for i := range luts[c] {
+			linear := math.Pow(float64(i)/255, gamma[c])
+			luts[c][i] = uint8(math.Round(255 * encodeSRGB(linear)))
+		}
+	}
+	for i := 0; i < len(m.Pix); i += 4 {
+		m.Pix[i+0] = luts[0][m.Pix[i+0]]
+		m.Pix[i+1] = luts[1][m.Pix[i+1]]
+		m.Pix[i+2] = luts[2][m.Pix[i+2]]
+	}
+	return m
+}
+
+// encodeSRGB applies the transfer function of sRGB to a linear intensity.
+func encodeSRGB(l float64) float64 {
+	if l <= 0.0031308 {
+		return 12.92 * l
+	}
+	return 1.055*math.Pow(l, 1/2.4) - 0.055
+}
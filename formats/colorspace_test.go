@@ -0,0 +1,144 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"golang.design/x/clipboard/formats"
+)
+
+// grayPNG returns a PNG encoded 2x2 image of the given gray level, which
+// is tagged with the given gAMA if it is not zero.
+func grayPNG(t *testing.T, level uint8, gama uint32) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = level, level, level, 0xff
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	buf := b.Bytes()
+	if gama == 0 {
+		return buf
+	}
+
+	// Insert the gAMA chunk after the IHDR chunk, which ends at 33.
+	chunk := make([]byte, 16)
+	binary.BigEndian.PutUint32(chunk[0:], 4)
+	copy(chunk[4:], "gAMA")
+	binary.BigEndian.PutUint32(chunk[8:], gama)
+	binary.BigEndian.PutUint32(chunk[12:], crc32.ChecksumIEEE(chunk[4:12]))
+	return append(append(append([]byte{}, buf[:33]...), chunk...), buf[33:]...)
+}
+
+func TestPNGGamma(t *testing.T) {
+	if _, ok := formats.PNGGamma(grayPNG(t, 128, 0)); ok {
+		t.Fatalf("untagged png should not report a gamma")
+	}
+	if g, ok := formats.PNGGamma(grayPNG(t, 128, 100000)); !ok || g != 1 {
+		t.Fatalf("expect the linear gamma of gAMA, got: %v %v", g, ok)
+	}
+
+	tagged, err := formats.WithPNGSRGB(grayPNG(t, 128, 100000))
+	if err != nil {
+		t.Fatalf("failed to tag png as srgb: %v", err)
+	}
+	if g, ok := formats.PNGGamma(tagged); !ok || g != 2.2 {
+		t.Fatalf("expect the gamma of sRGB, got: %v %v", g, ok)
+	}
+	img, err := png.Decode(bytes.NewReader(tagged))
+	if err != nil {
+		t.Fatalf("tagged png is not decodable: %v", err)
+	}
+	if c := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); c.R != 128 {
+		t.Fatalf("tagging should not change pixels, got: %v", c)
+	}
+}
+
+func TestPNGBitDepth(t *testing.T) {
+	if d, ok := formats.PNGBitDepth(grayPNG(t, 128, 0)); !ok || d != 8 {
+		t.Fatalf("expect 8 bits per sample, got: %v %v", d, ok)
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewNRGBA64(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	if d, ok := formats.PNGBitDepth(b.Bytes()); !ok || d != 16 {
+		t.Fatalf("expect 16 bits per sample, got: %v %v", d, ok)
+	}
+	if _, ok := formats.PNGBitDepth([]byte("not png")); ok {
+		t.Fatalf("expect no bit depth of malformed data")
+	}
+}
+
+func TestDIBColorSpace(t *testing.T) {
+	// The golden level of linear 128/255 in sRGB.
+	const golden = 188
+
+	t.Run("srgb", func(t *testing.T) {
+		dib, err := formats.PNGToDIB(grayPNG(t, 128, 0))
+		if err != nil {
+			t.Fatalf("failed to convert png to dib: %v", err)
+		}
+		if cs := binary.LittleEndian.Uint32(dib[56:]); cs != 0x73524742 {
+			t.Fatalf("dib is not tagged as sRGB, got: %#x", cs)
+		}
+		out, err := formats.DIBToPNG(dib)
+		if err != nil {
+			t.Fatalf("failed to convert dib to png: %v", err)
+		}
+		if g, ok := formats.PNGGamma(out); !ok || g != 2.2 {
+			t.Fatalf("png of an sRGB dib is not tagged as sRGB, got: %v %v", g, ok)
+		}
+	})
+
+	t.Run("linear-png", func(t *testing.T) {
+		dib, err := formats.PNGToDIB(grayPNG(t, 128, 100000))
+		if err != nil {
+			t.Fatalf("failed to convert png to dib: %v", err)
+		}
+		// The pixels follow the header in BGRA order.
+		if b := dib[124]; b != golden {
+			t.Fatalf("linear png is not converted to sRGB, got: %d, want: %d", b, golden)
+		}
+	})
+
+	t.Run("calibrated-dib", func(t *testing.T) {
+		dib, err := formats.PNGToDIB(grayPNG(t, 128, 0))
+		if err != nil {
+			t.Fatalf("failed to convert png to dib: %v", err)
+		}
+		// Calibrate the DIB with linear gammas of 1.0 in 16.16.
+		binary.LittleEndian.PutUint32(dib[56:], 0)
+		for i := 0; i < 3; i++ {
+			binary.LittleEndian.PutUint32(dib[96+4*i:], 1<<16)
+		}
+		out, err := formats.DIBToPNG(dib)
+		if err != nil {
+			t.Fatalf("failed to convert dib to png: %v", err)
+		}
+		img, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("converted data is not png encoded: %v", err)
+		}
+		if c := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); c.R != golden {
+			t.Fatalf("calibrated dib is not converted to sRGB, got: %v, want: %d", c, golden)
+		}
+		if g, ok := formats.PNGGamma(out); !ok || g != 2.2 {
+			t.Fatalf("converted png is not tagged as sRGB, got: %v %v", g, ok)
+		}
+	})
+}
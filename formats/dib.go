@@ -0,0 +1,266 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"golang.org/x/image/bmp"
+)
+
+// Sizes of the headers of a DIB, see:
+// https://docs.microsoft.com/en-us/windows/win32/api/wingdi/ns-wingdi-bitmapinfoheader
+// https://docs.microsoft.com/en-us/windows/win32/api/wingdi/ns-wingdi-bitmapv5header
+const (
+	bmpFileHeaderSize = 14
+	infoHeaderSize    = 40
+	v4HeaderSize      = 108
+	v5HeaderSize      = 124
+)
+
+// Color spaces of the CSType of a BITMAPV4HEADER or BITMAPV5HEADER, see:
+// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-wmf/eb4bbd50-b3ce-4917-895c-be31f214797f
+const (
+	lcsCalibratedRGB     = 0
+	lcsSRGB              = 0x73524742 // 'sRGB'
+	lcsWindowsColorSpace = 0x57696e20 // 'Win '
+)
+
+// DIBToPNG converts a packed DIB, which is the data of CF_DIB or CF_DIBV5
+// on Windows, to PNG encoded data. The resolution of the DIB is retained
+// in the pHYs chunk of the PNG. The PNG is tagged as sRGB if the DIB is
+// in sRGB, and the pixels of a DIB that is calibrated with the gammas of
+// its channels are converted to sRGB. It returns an *Error if the DIB is
+// malformed, or an error if it is not supported.
+func DIBToPNG(dib []byte) ([]byte, error) {
+	if len(dib) < infoHeaderSize {
+		return nil, &Error{Format: "DIB", Reason: "truncated header"}
+	}
+	size := binary.LittleEndian.Uint32(dib[0:])
+	if size < infoHeaderSize || uint64(size) > uint64(len(dib)) {
+		return nil, &Error{Format: "DIB", Reason: "header size out of range"}
+	}
+	bitCount := binary.LittleEndian.Uint16(dib[14:])
+	compression := binary.LittleEndian.Uint32(dib[16:])
+	xppm := int32(binary.LittleEndian.Uint32(dib[24:]))
+	yppm := int32(binary.LittleEndian.Uint32(dib[28:]))
+	clrUsed := binary.LittleEndian.Uint32(dib[32:])
+
+	// The color masks of BI_BITFIELDS follow a BITMAPINFOHEADER. The
+	// decoder only handles the default masks that are equivalent to
+	// BI_RGB, hence drop them.
+	const biRGB, biBitfields = 0, 3
+	if size == infoHeaderSize && compression == biBitfields && len(dib) >= infoHeaderSize+12 {
+		masks := dib[infoHeaderSize : infoHeaderSize+12]
+		if binary.LittleEndian.Uint32(masks[0:]) == 0xff0000 &&
+			binary.LittleEndian.Uint32(masks[4:]) == 0xff00 &&
+			binary.LittleEndian.Uint32(masks[8:]) == 0xff {
+			fixed := make([]byte, 0, len(dib)-12)
+			fixed = append(fixed, dib[:infoHeaderSize]...)
+			fixed = append(fixed, dib[infoHeaderSize+12:]...)
+			binary.LittleEndian.PutUint32(fixed[16:], biRGB)
+			dib = fixed
+		}
+	}
+
+	// Prepend the file header to decode it as a BMP file.
+	offset := bmpFileHeaderSize + int(size)
+	if bitCount <= 8 {
+		n := int(clrUsed)
+		if n == 0 {
+			n = 1 << bitCount
+		}
+		offset += 4 * n
+	}
+	file := make([]byte, bmpFileHeaderSize, bmpFileHeaderSize+len(dib))
+	file[0], file[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(file[2:], uint32(bmpFileHeaderSize+len(dib)))
+	binary.LittleEndian.PutUint32(file[10:], uint32(offset))
+	file = append(file, dib...)
+
+	img, err := bmp.Decode(bytes.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+	// Windows stores premultiplied alpha in 32-bit DIBs with an alpha
+	// channel, which the decoder reports as non-premultiplied.
+	if m, ok := img.(*image.NRGBA); ok && bitCount == 32 && size > infoHeaderSize {
+		img = &image.RGBA{Pix: m.Pix, Stride: m.Stride, Rect: m.Rect}
+	}
+
+	srgb := false
+	if size >= v4HeaderSize {
+		switch binary.LittleEndian.Uint32(dib[56:]) {
+		case lcsSRGB, lcsWindowsColorSpace:
+			srgb = true
+		case lcsCalibratedRGB:
+			// The gammas are unsigned 16.16 fixed point numbers, zero
+			// if they are not specified.
+			var gamma [3]float64
+			for i := range gamma {
+				gamma[i] = float64(binary.LittleEndian.Uint32(dib[96+4*i:])) / 65536
+			}
+			if gamma[0] > 0 && gamma[1] > 0 && gamma[2] > 0 {
+				if !isSRGBGamma(gamma[0]) || !isSRGBGamma(gamma[1]) || !isSRGBGamma(gamma[2]) {
+					img = toSRGB(img, gamma)
+				}
+				srgb = true
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	out := buf.Bytes()
+	if srgb {
+		if out, err = WithPNGSRGB(out); err != nil {
+			return nil, err
+		}
+	}
+	if xppm <= 0 || yppm <= 0 {
+		return out, nil
+	}
+	return WithPNGResolution(out, uint32(xppm), uint32(yppm))
+}
+
+// PNGToDIB converts PNG encoded data to a packed DIB with a BITMAPV5HEADER
+// and 32-bit premultiplied pixels, which is the data of CF_DIBV5 on
+// Windows. The resolution of the PNG is retained, so that the image
+// pastes at its physical size in applications such as Word. The DIB is
+// tagged as sRGB, and the pixels of a PNG whose gAMA chunk specifies
+// another gamma, such as linear data, are converted to sRGB.
+func PNGToDIB(buf []byte) ([]byte, error) {
+	img, err := decodePNGSRGB(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	dib := make([]byte, v5HeaderSize+4*width*height)
+
+	le := binary.LittleEndian
+	le.PutUint32(dib[0:], v5HeaderSize)
+	le.PutUint32(dib[4:], uint32(width))
+	le.PutUint32(dib[8:], uint32(height))
+	le.PutUint16(dib[12:], 1)  // planes
+	le.PutUint16(dib[14:], 32) // bits per pixel
+	le.PutUint32(dib[16:], 0)  // BI_RGB
+	le.PutUint32(dib[20:], uint32(4*width*height))
+	if x, y, ok := PNGResolution(buf); ok {
+		le.PutUint32(dib[24:], x)
+		le.PutUint32(dib[28:], y)
+	}
+	le.PutUint32(dib[40:], 0xff0000) // red mask
+	le.PutUint32(dib[44:], 0xff00)   // green mask
+	le.PutUint32(dib[48:], 0xff)     // blue mask
+	le.PutUint32(dib[52:], 0xff000000)
+	// Use LCS_sRGB as the color space, see:
+	// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-wmf/eb4bbd50-b3ce-4917-895c-be31f214797f
+	le.PutUint32(dib[56:], lcsSRGB)
+	// Use LCS_GM_IMAGES as the gamut mapping intent, see:
+	// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-wmf/9fec0834-607d-427d-abd5-ab240fb0db38
+	le.PutUint32(dib[108:], 4)
+
+	// The rows of the DIB are stored bottom-up.
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			idx := v5HeaderSize + 4*(y*width+x)
+			r, g, bl, a := img.At(b.Min.X+x, b.Max.Y-1-y).RGBA()
+			dib[idx+2] = uint8(r >> 8)
+			dib[idx+1] = uint8(g >> 8)
+			dib[idx+0] = uint8(bl >> 8)
+			dib[idx+3] = uint8(a >> 8)
+		}
+	}
+	return dib, nil
+}
+
+// PNGToMattedDIB converts PNG encoded data to a packed DIB with a
+// BITMAPINFOHEADER and 24-bit pixels, which is the data of CF_DIB on
+// Windows. The DIB has no alpha channel, thus the image is composited
+// onto the given background, or white if bg is nil, so that applications
+// that only read CF_DIB paste the transparent areas as the background
+// rather than black. The resolution and the color space are handled as
+// PNGToDIB does.
+func PNGToMattedDIB(buf []byte, bg color.Color) ([]byte, error) {
+	img, err := decodePNGSRGB(buf)
+	if err != nil {
+		return nil, err
+	}
+	if bg == nil {
+		bg = color.White
+	}
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	m := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(m, m.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(m, m.Bounds(), img, b.Min, draw.Over)
+
+	// The rows of a 24-bit DIB are padded to multiples of 4 bytes.
+	stride := (3*width + 3) &^ 3
+	dib := make([]byte, infoHeaderSize+stride*height)
+
+	le := binary.LittleEndian
+	le.PutUint32(dib[0:], infoHeaderSize)
+	le.PutUint32(dib[4:], uint32(width))
+	le.PutUint32(dib[8:], uint32(height))
+	le.PutUint16(dib[12:], 1)  // planes
+	le.PutUint16(dib[14:], 24) // bits per pixel
+	le.PutUint32(dib[16:], 0)  // BI_RGB
+	le.PutUint32(dib[20:], uint32(stride*height))
+	if x, y, ok := PNGResolution(buf); ok {
+		le.PutUint32(dib[24:], x)
+		le.PutUint32(dib[28:], y)
+	}
+
+	// The rows of the DIB are stored bottom-up.
+	for y := 0; y < height; y++ {
+		row := dib[infoHeaderSize+y*stride:]
+		for x := 0; x < width; x++ {
+			p := m.Pix[m.PixOffset(x, height-1-y):]
+			row[3*x+2] = p[0]
+			row[3*x+1] = p[1]
+			row[3*x+0] = p[2]
+		}
+	}
+	return dib, nil
+}
+
+// DIBHasAlpha reports whether the given packed DIB carries an alpha
+// channel, which is the case for the 32-bit DIBs of PNGToDIB, but not
+// for the matted DIBs of PNGToMattedDIB. Windows only honors the alpha
+// channel of DIBs with a BITMAPV4HEADER or BITMAPV5HEADER.
+func DIBHasAlpha(dib []byte) bool {
+	if len(dib) < v4HeaderSize {
+		return false
+	}
+	le := binary.LittleEndian
+	return le.Uint32(dib[0:]) >= v4HeaderSize &&
+		le.Uint16(dib[14:]) == 32 &&
+		le.Uint32(dib[52:]) != 0
+}
+
+// decodePNGSRGB decodes PNG encoded data, and converts the pixels of a
+// PNG whose gAMA chunk specifies another gamma than sRGB to sRGB.
+func decodePNGSRGB(buf []byte) (image.Image, error) {
+	img, err := png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	if g, ok := PNGGamma(buf); ok && !isSRGBGamma(g) {
+		img = toSRGB(img, [3]float64{g, g, g})
+	}
+	return img, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+
+	"golang.design/x/clipboard/formats"
+)
+
+func TestDIB(t *testing.T) {
+	data, err := os.ReadFile("../tests/testdata/clipboard.png")
+	if err != nil {
+		t.Fatalf("failed to read gold file: %v", err)
+	}
+	want, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode gold file: %v", err)
+	}
+	data, err = formats.WithPNGResolution(data, 5906, 5906)
+	if err != nil {
+		t.Fatalf("failed to set resolution: %v", err)
+	}
+
+	dib, err := formats.PNGToDIB(data)
+	if err != nil {
+		t.Fatalf("failed to convert png to dib: %v", err)
+	}
+	out, err := formats.DIBToPNG(dib)
+	if err != nil {
+		t.Fatalf("failed to convert dib to png: %v", err)
+	}
+	got, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("converted data is not png encoded: %v", err)
+	}
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("converted image has different bounds, got: %v, want: %v", got.Bounds(), want.Bounds())
+	}
+	if x, y, ok := formats.PNGResolution(out); !ok || x != 5906 || y != 5906 {
+		t.Fatalf("resolution is not retained, got: %v %v %v", x, y, ok)
+	}
+
+	if _, err := formats.DIBToPNG([]byte{1, 2, 3}); err == nil {
+		t.Fatalf("expect to fail on truncated dib")
+	}
+}
+
+func TestDIBAlpha(t *testing.T) {
+	// A red gradient of alpha, whose first pixel is fully transparent.
+	want := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			want.SetNRGBA(x, y, color.NRGBA{R: 255, G: 0, B: 0, A: uint8(x * 255 / 4)})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, want); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+
+	t.Run("dibv5", func(t *testing.T) {
+		dib, err := formats.PNGToDIB(b.Bytes())
+		if err != nil {
+			t.Fatalf("failed to convert png to dib: %v", err)
+		}
+		if !formats.DIBHasAlpha(dib) {
+			t.Fatalf("dib has no alpha channel")
+		}
+		out, err := formats.DIBToPNG(dib)
+		if err != nil {
+			t.Fatalf("failed to convert dib to png: %v", err)
+		}
+		got, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("converted data is not png encoded: %v", err)
+		}
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 5; x++ {
+				g := color.NRGBAModel.Convert(got.At(x, y)).(color.NRGBA)
+				w := want.NRGBAAt(x, y)
+				if g.A != w.A {
+					t.Fatalf("alpha is not retained at (%d, %d), got: %d, want: %d", x, y, g.A, w.A)
+				}
+				if w.A != 0 && g.R != w.R {
+					t.Fatalf("color is not retained at (%d, %d), got: %v, want: %v", x, y, g, w)
+				}
+			}
+		}
+	})
+
+	t.Run("matted", func(t *testing.T) {
+		dib, err := formats.PNGToMattedDIB(b.Bytes(), color.NRGBA{B: 255, A: 255})
+		if err != nil {
+			t.Fatalf("failed to convert png to matted dib: %v", err)
+		}
+		if formats.DIBHasAlpha(dib) {
+			t.Fatalf("matted dib has an alpha channel")
+		}
+		out, err := formats.DIBToPNG(dib)
+		if err != nil {
+			t.Fatalf("failed to convert matted dib to png: %v", err)
+		}
+		got, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("converted data is not png encoded: %v", err)
+		}
+		for y := 0; y < 3; y++ {
+			if g := color.NRGBAModel.Convert(got.At(0, y)); g != (color.NRGBA{B: 255, A: 255}) {
+				t.Fatalf("transparent pixel is not the background, got: %v", g)
+			}
+			if g := color.NRGBAModel.Convert(got.At(4, y)); g != (color.NRGBA{R: 255, A: 255}) {
+				t.Fatalf("opaque pixel is not retained, got: %v", g)
+			}
+		}
+
+		white, err := formats.PNGToMattedDIB(b.Bytes(), nil)
+		if err != nil {
+			t.Fatalf("failed to convert png to matted dib: %v", err)
+		}
+		// The pixels of the first row follow the header in BGR order.
+		if p := white[40:43]; !bytes.Equal(p, []byte{255, 255, 255}) {
+			t.Fatalf("default background is not white, got: %v", p)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/url"
+	"strings"
+)
+
+// EncodeURIList encodes the given absolute file paths as a text/uri-list,
+// see RFC 2483, section 5.
+func EncodeURIList(paths []string) []byte {
+	var b bytes.Buffer
+	for _, p := range paths {
+		u := url.URL{Scheme: "file", Path: p}
+		b.WriteString(u.String())
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
+
+// DecodeURIList decodes a text/uri-list. File URIs are converted to
+// paths, other URIs are kept as they are, and comments are skipped.
+func DecodeURIList(buf []byte) []string {
+	var paths []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err == nil && u.Scheme == "file" && u.Path != "" &&
+			(u.Host == "" || u.Host == "localhost") {
+			line = u.Path
+		}
+		paths = append(paths, line)
+	}
+	return paths
+}
+
+// EncodeGNOMEFiles encodes the given absolute file paths as the file
+// list that is copied by GNOME Files, which is the operation followed
+// by file URIs.
+func EncodeGNOMEFiles(paths []string) []byte {
+	uris := bytes.Split(bytes.TrimSuffix(EncodeURIList(paths), []byte("\r\n")), []byte("\r\n"))
+	return append([]byte("copy\n"), bytes.Join(uris, []byte("\n"))...)
+}
+
+// dropFilesSize is the size of the DROPFILES structure, see:
+// https://docs.microsoft.com/en-us/windows/win32/api/shlobj_core/ns-shlobj_core-dropfiles
+const dropFilesSize = 20
+
+// EncodeDropFiles encodes the given file paths as the data of CF_HDROP,
+// which is a DROPFILES structure that is followed by the NUL-terminated
+// UTF-16 paths and a final NUL.
+func EncodeDropFiles(paths []string) []byte {
+	var b bytes.Buffer
+	for _, p := range paths {
+		b.Write(EncodeUTF16(p))
+		b.Write([]byte{0, 0})
+	}
+	b.Write([]byte{0, 0})
+
+	hdr := make([]byte, dropFilesSize)
+	binary.LittleEndian.PutUint32(hdr[0:], dropFilesSize) // pFiles
+	binary.LittleEndian.PutUint32(hdr[16:], 1)            // fWide
+	return append(hdr, b.Bytes()...)
+}
+
+// DecodeDropFiles decodes the data of CF_HDROP to file paths. Both wide
+// and ANSI paths are supported. It returns an *Error if the DROPFILES
+// structure is truncated.
+func DecodeDropFiles(buf []byte) ([]string, error) {
+	if len(buf) < dropFilesSize {
+		return nil, &Error{Format: "DROPFILES", Reason: "truncated DROPFILES header"}
+	}
+	off := binary.LittleEndian.Uint32(buf[0:])
+	wide := binary.LittleEndian.Uint32(buf[16:]) != 0
+	if off < dropFilesSize || uint64(off) > uint64(len(buf)) {
+		return nil, &Error{Format: "DROPFILES", Reason: "file list offset out of range"}
+	}
+	buf = buf[off:]
+
+	var paths []string
+	if wide {
+		for {
+			n := 0
+			for n+1 < len(buf) && (buf[n] != 0 || buf[n+1] != 0) {
+				n += 2
+			}
+			if n == 0 {
+				break
+			}
+			paths = append(paths, decodeUTF16(buf[:n], binary.LittleEndian))
+			if n+2 > len(buf) {
+				break
+			}
+			buf = buf[n+2:]
+		}
+		return paths, nil
+	}
+	for len(buf) > 0 && buf[0] != 0 {
+		n := bytes.IndexByte(buf, 0)
+		if n < 0 {
+			n = len(buf)
+		}
+		paths = append(paths, string(buf[:n]))
+		if n == len(buf) {
+			break
+		}
+		buf = buf[n+1:]
+	}
+	return paths, nil
+}
+
+// Sizes of the FILEGROUPDESCRIPTORW header and its FILEDESCRIPTORW items,
+// see:
+// https://docs.microsoft.com/en-us/windows/win32/api/shlobj_core/ns-shlobj_core-filegroupdescriptorw
+// https://docs.microsoft.com/en-us/windows/win32/api/shlobj_core/ns-shlobj_core-filedescriptorw
+const (
+	fileGroupHeaderSize = 4
+	fileDescriptorSize  = 592
+	fileNameOffset      = 72
+	fileNameSize        = 520 // MAX_PATH UTF-16 code units
+)
+
+// Flags of the valid fields of a FILEDESCRIPTORW, and the attribute of
+// directories.
+const (
+	fdAttributes           = 0x04
+	fdFileSize             = 0x40
+	fileAttributeDirectory = 0x10
+)
+
+// FileDescriptor describes a virtual file of FileGroupDescriptorW on
+// Windows, whose data is the FileContents of the same index.
+type FileDescriptor struct {
+	// Name is the path of the file relative to the pasted location,
+	// which uses backslashes to separate directories.
+	Name string
+	// Dir indicates the file is a directory.
+	Dir bool
+	// Size is the size of the file, or -1 if it is not given.
+	Size int64
+}
+
+// EncodeFileGroupDescriptor encodes the given virtual files as the data
+// of FileGroupDescriptorW, which is a FILEGROUPDESCRIPTORW structure.
+// Names are truncated to MAX_PATH.
+func EncodeFileGroupDescriptor(files []FileDescriptor) []byte {
+	buf := make([]byte, fileGroupHeaderSize+fileDescriptorSize*len(files))
+	le := binary.LittleEndian
+	le.PutUint32(buf[0:], uint32(len(files)))
+	for i, f := range files {
+		d := buf[fileGroupHeaderSize+fileDescriptorSize*i:]
+		var flags uint32
+		if f.Dir {
+			flags |= fdAttributes
+			le.PutUint32(d[36:], fileAttributeDirectory)
+		}
+		if f.Size >= 0 {
+			flags |= fdFileSize
+			le.PutUint32(d[64:], uint32(f.Size>>32))
+			le.PutUint32(d[68:], uint32(f.Size))
+		}
+		le.PutUint32(d[0:], flags)
+		name := EncodeUTF16(f.Name)
+		if len(name) > fileNameSize-2 {
+			name = name[:fileNameSize-2]
+		}
+		copy(d[fileNameOffset:], name)
+	}
+	return buf
+}
+
+// DecodeFileGroupDescriptor decodes the data of FileGroupDescriptorW on
+// Windows, as copied from attachments of Outlook or files in archives.
+// It returns an *Error if the FILEGROUPDESCRIPTORW structure is
+// truncated.
+func DecodeFileGroupDescriptor(buf []byte) ([]FileDescriptor, error) {
+	if len(buf) < fileGroupHeaderSize {
+		return nil, &Error{Format: "FILEGROUPDESCRIPTORW", Reason: "truncated header"}
+	}
+	le := binary.LittleEndian
+	n := le.Uint32(buf[0:])
+	if uint64(n)*fileDescriptorSize > uint64(len(buf)-fileGroupHeaderSize) {
+		return nil, &Error{Format: "FILEGROUPDESCRIPTORW", Reason: "truncated file descriptors"}
+	}
+	files := make([]FileDescriptor, n)
+	for i := range files {
+		d := buf[fileGroupHeaderSize+fileDescriptorSize*i:]
+		flags := le.Uint32(d[0:])
+		name := d[fileNameOffset : fileNameOffset+fileNameSize]
+		end := 0
+		for end+1 < len(name) && (name[end] != 0 || name[end+1] != 0) {
+			end += 2
+		}
+		files[i] = FileDescriptor{
+			Name: decodeUTF16(name[:end], le),
+			Dir:  flags&fdAttributes != 0 && le.Uint32(d[36:])&fileAttributeDirectory != 0,
+			Size: -1,
+		}
+		if flags&fdFileSize != 0 {
+			files[i].Size = int64(le.Uint32(d[64:]))<<32 | int64(le.Uint32(d[68:]))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"golang.design/x/clipboard/formats"
+)
+
+func TestURIList(t *testing.T) {
+	files := []string{"/home/gopher/a b.txt", "/tmp/数据"}
+
+	uris := formats.EncodeURIList(files)
+	want := "file:///home/gopher/a%20b.txt\r\nfile:///tmp/%E6%95%B0%E6%8D%AE\r\n"
+	if string(uris) != want {
+		t.Fatalf("encoded uri list mismatch, got: %q, want: %q", uris, want)
+	}
+	if got := formats.DecodeURIList(uris); !reflect.DeepEqual(got, files) {
+		t.Fatalf("decoded files mismatch, got: %q, want: %q", got, files)
+	}
+
+	in := []byte("# comment\r\nfile://localhost/etc/hosts\r\nhttps://golang.design\r\n")
+	want2 := []string{"/etc/hosts", "https://golang.design"}
+	if got := formats.DecodeURIList(in); !reflect.DeepEqual(got, want2) {
+		t.Fatalf("decoded files mismatch, got: %q, want: %q", got, want2)
+	}
+}
+
+func TestGNOMEFiles(t *testing.T) {
+	got := formats.EncodeGNOMEFiles([]string{"/tmp/a b"})
+	want := "copy\nfile:///tmp/a%20b"
+	if string(got) != want {
+		t.Fatalf("encoded gnome files mismatch, got: %q, want: %q", got, want)
+	}
+}
+
+func TestDropFiles(t *testing.T) {
+	files := []string{`C:\Users\gopher\a b.txt`, `D:\数据`}
+
+	got, err := formats.DecodeDropFiles(formats.EncodeDropFiles(files))
+	if err != nil {
+		t.Fatalf("failed to decode DROPFILES: %v", err)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Fatalf("decoded files mismatch, got: %q, want: %q", got, files)
+	}
+
+	// ANSI paths, as offered by legacy applications.
+	ansi := append([]byte{20, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		"C:\\a.txt\x00C:\\b.txt\x00\x00"...)
+	got, err = formats.DecodeDropFiles(ansi)
+	if err != nil {
+		t.Fatalf("failed to decode ANSI DROPFILES: %v", err)
+	}
+	if want := []string{`C:\a.txt`, `C:\b.txt`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("decoded files mismatch, got: %q, want: %q", got, want)
+	}
+
+	_, err = formats.DecodeDropFiles([]byte{1, 2, 3})
+	var e *formats.Error
+	if !errors.As(err, &e) || e.Format != "DROPFILES" {
+		t.Fatalf("expect a DROPFILES error for truncated data, got: %v", err)
+	}
+}
+
+func TestFileGroupDescriptor(t *testing.T) {
+	files := []formats.FileDescriptor{
+		{Name: "attachment.pdf", Size: 1 << 33},
+		{Name: "archive", Dir: true, Size: -1},
+		{Name: `archive\数据.txt`, Size: 0},
+	}
+
+	got, err := formats.DecodeFileGroupDescriptor(formats.EncodeFileGroupDescriptor(files))
+	if err != nil {
+		t.Fatalf("failed to decode FILEGROUPDESCRIPTORW: %v", err)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Fatalf("decoded files mismatch, got: %+v, want: %+v", got, files)
+	}
+
+	buf := formats.EncodeFileGroupDescriptor(files)
+	_, err = formats.DecodeFileGroupDescriptor(buf[:len(buf)-1])
+	var e *formats.Error
+	if !errors.As(err, &e) || e.Format != "FILEGROUPDESCRIPTORW" {
+		t.Fatalf("expect a FILEGROUPDESCRIPTORW error for truncated data, got: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+// Package formats implements the encoders and decoders of the native
+// clipboard formats that package clipboard uses, such as CF_HTML,
+// DROPFILES and DIB on Windows, or text/uri-list on X11. The functions
+// do not access the clipboard, hence they can be used without
+// initializing package clipboard, for instance, to process clipboard
+// dumps offline. It also renders HTML as plain text or Markdown for
+// tools that paste rich content into a terminal.
+package formats
+
+// Error reports data that is not well-formed in its format.
+type Error struct {
+	// Format is the name of the format, such as "CF_HTML".
+	Format string
+	// Reason describes what is malformed.
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return "formats: malformed " + e.Format + ": " + e.Reason
+}
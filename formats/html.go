@@ -0,0 +1,107 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// cfHTMLHeader is the header of the CF_HTML clipboard format on Windows,
+// the offsets are padded to a fixed width so that the length of the
+// header does not depend on them. See:
+// https://docs.microsoft.com/en-us/windows/win32/dataxchg/html-clipboard-format
+const cfHTMLHeader = "Version:0.9\r\n" +
+	"StartHTML:%010d\r\n" +
+	"EndHTML:%010d\r\n" +
+	"StartFragment:%010d\r\n" +
+	"EndFragment:%010d\r\n"
+
+const (
+	cfHTMLPrefix = "<html><body>\r\n<!--StartFragment-->"
+	cfHTMLSuffix = "<!--EndFragment-->\r\n</body></html>"
+)
+
+// EncodeCFHTML wraps the given HTML fragment into the CF_HTML envelope.
+func EncodeCFHTML(frag []byte) []byte {
+	n := len(fmt.Sprintf(cfHTMLHeader, 0, 0, 0, 0))
+	startFrag := n + len(cfHTMLPrefix)
+	endFrag := startFrag + len(frag)
+	endHTML := endFrag + len(cfHTMLSuffix)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, cfHTMLHeader, n, endHTML, startFrag, endFrag)
+	b.WriteString(cfHTMLPrefix)
+	b.Write(frag)
+	b.WriteString(cfHTMLSuffix)
+	return b.Bytes()
+}
+
+// DecodeCFHTML returns the HTML fragment of the given CF_HTML data. It
+// returns an *Error if the header is missing or the offsets of the
+// header are inconsistent with the data.
+func DecodeCFHTML(buf []byte) ([]byte, error) {
+	malformed := func(reason string) error {
+		return &Error{Format: "CF_HTML", Reason: reason}
+	}
+
+	// Producers may terminate the data with NUL.
+	if i := bytes.IndexByte(buf, 0); i >= 0 {
+		buf = buf[:i]
+	}
+
+	offsets := map[string]int{}
+	for rest := buf; len(rest) > 0 && rest[0] != '<'; {
+		i := bytes.IndexAny(rest, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := rest[:i]
+		rest = bytes.TrimLeft(rest[i:], "\r\n")
+
+		kv := bytes.SplitN(line, []byte(":"), 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch key := string(kv[0]); key {
+		case "StartHTML", "EndHTML", "StartFragment", "EndFragment":
+			v, err := strconv.Atoi(string(bytes.TrimSpace(kv[1])))
+			if err != nil {
+				return nil, malformed("invalid " + key + " offset")
+			}
+			offsets[key] = v
+		}
+	}
+
+	start, ok1 := offsets["StartFragment"]
+	end, ok2 := offsets["EndFragment"]
+	if !ok1 || !ok2 {
+		return nil, malformed("missing CF_HTML fragment offsets")
+	}
+	if start < 0 || start > end || end > len(buf) {
+		return nil, malformed("inconsistent CF_HTML offsets")
+	}
+	return buf[start:end], nil
+}
+
+// DecodeHTML decodes HTML data that some X11 applications, such as
+// Firefox, offer in UTF-16 with a byte order mark, and returns UTF-8
+// encoded HTML. Other data is returned as is.
+func DecodeHTML(buf []byte) []byte {
+	if len(buf) < 2 {
+		return buf
+	}
+	switch {
+	case buf[0] == 0xff && buf[1] == 0xfe:
+		return []byte(decodeUTF16(buf[2:], binary.LittleEndian))
+	case buf[0] == 0xfe && buf[1] == 0xff:
+		return []byte(decodeUTF16(buf[2:], binary.BigEndian))
+	}
+	return buf
+}
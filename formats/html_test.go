@@ -0,0 +1,42 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.design/x/clipboard/formats"
+)
+
+func TestCFHTML(t *testing.T) {
+	frag := []byte("<b>golang.design</b>")
+	got, err := formats.DecodeCFHTML(formats.EncodeCFHTML(frag))
+	if err != nil {
+		t.Fatalf("failed to decode CF_HTML: %v", err)
+	}
+	if string(got) != string(frag) {
+		t.Fatalf("decoded fragment mismatch, got: %q, want: %q", got, frag)
+	}
+
+	_, err = formats.DecodeCFHTML([]byte("Version:0.9\r\nStartHTML:-1\r\n"))
+	var e *formats.Error
+	if !errors.As(err, &e) || e.Format != "CF_HTML" {
+		t.Fatalf("expect a CF_HTML error for malformed data, got: %v", err)
+	}
+}
+
+func TestDecodeHTML(t *testing.T) {
+	// UTF-16LE with byte order mark, as Firefox offers on X11.
+	in := []byte{0xff, 0xfe, '<', 0, 'b', 0, '>', 0}
+	if got := string(formats.DecodeHTML(in)); got != "<b>" {
+		t.Fatalf("decoded html mismatch, got: %q, want: %q", got, "<b>")
+	}
+	if got := string(formats.DecodeHTML([]byte("<b>"))); got != "<b>" {
+		t.Fatalf("utf-8 html should be kept, got: %q", got)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// pngSignature is the leading bytes of every PNG encoded data.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngChunks calls fn for each chunk of PNG encoded data with the offset
+// of the chunk, until fn returns false. It returns an error if buf is
+// not PNG encoded.
+func pngChunks(buf []byte, fn func(typ string, data []byte, off int) bool) error {
+	notPNG := &Error{Format: "PNG", Reason: "invalid chunk layout"}
+	if !bytes.HasPrefix(buf, pngSignature) {
+		return &Error{Format: "PNG", Reason: "missing PNG signature"}
+	}
+	for off := len(pngSignature); off < len(buf); {
+		if len(buf)-off < 12 {
+			return notPNG
+		}
+		n := int(binary.BigEndian.Uint32(buf[off:]))
+		if n < 0 || len(buf)-off-12 < n {
+			return notPNG
+		}
+		if !fn(string(buf[off+4:off+8]), buf[off+8:off+8+n], off) {
+			return nil
+		}
+		off += 12 + n
+	}
+	return nil
+}
+
+// PNGResolution returns the pixels per meter of PNG encoded data that
+// is specified by its pHYs chunk, and reports whether the resolution
+// is specified.
+func PNGResolution(buf []byte) (x, y uint32, ok bool) {
+	pngChunks(buf, func(typ string, data []byte, off int) bool {
+		if typ == "IDAT" {
+			// pHYs must precede the image data.
+			return false
+		}
+		// A unit of 1 indicates meter, otherwise only the aspect
+		// ratio is specified.
+		if typ == "pHYs" && len(data) == 9 && data[8] == 1 {
+			x = binary.BigEndian.Uint32(data[0:])
+			y = binary.BigEndian.Uint32(data[4:])
+			ok = x > 0 && y > 0
+			return false
+		}
+		return true
+	})
+	return
+}
+
+// PNGBitDepth returns the number of bits per sample of PNG encoded data,
+// such as 8 or 16, which is specified by its IHDR chunk, and reports
+// whether the data has an IHDR chunk.
+func PNGBitDepth(buf []byte) (depth int, ok bool) {
+	pngChunks(buf, func(typ string, data []byte, off int) bool {
+		// IHDR must be the first chunk.
+		if typ == "IHDR" && len(data) == 13 {
+			depth, ok = int(data[8]), true
+		}
+		return false
+	})
+	return
+}
+
+// WithPNGResolution returns a copy of PNG encoded data whose pHYs chunk
+// specifies the given pixels per meter. An existing pHYs chunk is
+// replaced. It returns an *Error if buf is not PNG encoded.
+func WithPNGResolution(buf []byte, x, y uint32) ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	err := pngChunks(buf, func(typ string, data []byte, off int) bool {
+		if typ == "pHYs" {
+			return true
+		}
+		out.Write(buf[off : off+12+len(data)])
+		if typ == "IHDR" {
+			phys := make([]byte, 9)
+			binary.BigEndian.PutUint32(phys[0:], x)
+			binary.BigEndian.PutUint32(phys[4:], y)
+			phys[8] = 1 // meter
+			writePNGChunk(&out, "pHYs", phys)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// writePNGChunk writes a PNG chunk of the given type and data to w.
+func writePNGChunk(w *bytes.Buffer, typ string, data []byte) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(len(data)))
+	w.Write(b[:])
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	w.WriteString(typ)
+	w.Write(data)
+	binary.BigEndian.PutUint32(b[:], crc.Sum32())
+	w.Write(b[:])
+}
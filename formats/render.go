@@ -0,0 +1,479 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// HTMLToText renders the given HTML fragment as plain text, for instance,
+// to paste rich content that is copied from browsers into a terminal.
+// Block elements start new lines, list items are bulleted, and scripts,
+// styles and the document head are dropped.
+func HTMLToText(frag []byte) string {
+	return render(frag, false)
+}
+
+// HTMLToMarkdown renders the given HTML fragment as Markdown, which keeps
+// headings, emphasis, links, images, lists, quotes and code of the HTML.
+// Elements that Markdown cannot express, such as tables, are rendered as
+// text.
+func HTMLToMarkdown(frag []byte) string {
+	return render(frag, true)
+}
+
+func render(frag []byte, md bool) string {
+	r := &renderer{md: md}
+	for _, t := range tokenize(string(frag)) {
+		switch t.kind {
+		case textToken:
+			r.text(t.data)
+		case startToken:
+			r.start(t)
+		case endToken:
+			r.end(t)
+		}
+	}
+	return strings.TrimSpace(r.b.String())
+}
+
+// list is the state of a list that is being rendered.
+type list struct {
+	ordered bool
+	n       int
+}
+
+// renderer renders HTML tokens as text or Markdown. Line breaks and
+// spaces are pending until the next content is written, so that runs of
+// block elements and whitespace collapse.
+type renderer struct {
+	b  strings.Builder
+	md bool
+
+	newlines  int  // pending line breaks
+	space     bool // pending space
+	linestart bool
+
+	pre   int      // depth of pre elements
+	quote int      // depth of blockquote elements
+	lists []list   // enclosing lists
+	links []string // targets of enclosing links
+	cells int      // cells of the current table row
+}
+
+// block requests n line breaks before the next content.
+func (r *renderer) block(n int) {
+	if r.b.Len() > 0 && r.newlines < n {
+		r.newlines = n
+	}
+	r.space = false
+}
+
+// flush writes the pending line breaks or space.
+func (r *renderer) flush() {
+	if r.newlines > 0 {
+		for i := 0; i < r.newlines; i++ {
+			r.b.WriteByte('\n')
+			if r.md && r.quote > 0 && i == r.newlines-1 {
+				r.b.WriteString(strings.Repeat("> ", r.quote))
+			}
+		}
+		r.newlines = 0
+		r.space = false
+		r.linestart = true
+		return
+	}
+	if r.b.Len() == 0 && r.md && r.quote > 0 && !r.linestart {
+		r.b.WriteString(strings.Repeat("> ", r.quote))
+		r.linestart = true
+	}
+	if r.space && !r.linestart {
+		r.b.WriteByte(' ')
+	}
+	r.space = false
+}
+
+// write writes the given content after the pending breaks.
+func (r *renderer) write(s string) {
+	r.flush()
+	r.b.WriteString(s)
+	r.linestart = false
+}
+
+func (r *renderer) text(s string) {
+	if r.pre > 0 {
+		lines := strings.Split(s, "\n")
+		for i, l := range lines {
+			if i > 0 {
+				r.newlines++
+			}
+			if l != "" {
+				r.write(l)
+			}
+		}
+		return
+	}
+
+	if strings.TrimSpace(s) == "" {
+		if s != "" {
+			r.space = true
+		}
+		return
+	}
+	if isSpace(s[0]) {
+		r.space = true
+	}
+	for i, f := range strings.Fields(s) {
+		if i > 0 {
+			r.space = true
+		}
+		if r.md {
+			f = escapeMarkdown(f)
+		}
+		r.write(f)
+	}
+	if isSpace(s[len(s)-1]) {
+		r.space = true
+	}
+}
+
+func (r *renderer) start(t token) {
+	switch t.data {
+	case "p", "div", "section", "article", "header", "footer", "nav",
+		"main", "aside", "figure", "table", "dl":
+		r.block(2)
+	case "tr", "dt", "dd":
+		r.block(1)
+		r.cells = 0
+	case "td", "th":
+		if r.cells > 0 {
+			if r.md {
+				r.write(" | ")
+			} else {
+				r.write("\t")
+			}
+		}
+		r.cells++
+	case "br":
+		if r.md && r.pre == 0 {
+			r.b.WriteString("  ")
+		}
+		r.newlines++
+	case "hr":
+		r.block(2)
+		if r.md {
+			r.write("---")
+		}
+		r.block(2)
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.block(2)
+		if r.md {
+			r.write(strings.Repeat("#", int(t.data[1]-'0')) + " ")
+			r.linestart = true
+		}
+	case "ul", "ol":
+		if len(r.lists) == 0 {
+			r.block(2)
+		} else {
+			r.block(1)
+		}
+		n := 1
+		if s, err := strconv.Atoi(t.attrs["start"]); err == nil {
+			n = s
+		}
+		r.lists = append(r.lists, list{ordered: t.data == "ol", n: n})
+	case "li":
+		r.block(1)
+		bullet := "- "
+		if len(r.lists) > 0 {
+			l := &r.lists[len(r.lists)-1]
+			if l.ordered {
+				bullet = strconv.Itoa(l.n) + ". "
+				l.n++
+			}
+			bullet = strings.Repeat("  ", len(r.lists)-1) + bullet
+		}
+		r.write(bullet)
+		r.linestart = true
+	case "blockquote":
+		r.block(2)
+		r.quote++
+	case "pre":
+		r.block(2)
+		if r.md {
+			r.write("```")
+			r.newlines = 1
+		}
+		r.pre++
+	case "b", "strong":
+		r.inline("**")
+	case "i", "em":
+		r.inline("_")
+	case "s", "del", "strike":
+		r.inline("~~")
+	case "code":
+		if r.pre == 0 {
+			r.inline("`")
+		}
+	case "a":
+		r.links = append(r.links, t.attrs["href"])
+		r.inline("[")
+	case "img":
+		alt := t.attrs["alt"]
+		if r.md {
+			r.write("![" + escapeMarkdown(alt) + "](" + t.attrs["src"] + ")")
+		} else if alt != "" {
+			r.write(alt)
+		}
+	}
+}
+
+func (r *renderer) end(t token) {
+	switch t.data {
+	case "p", "div", "section", "article", "header", "footer", "nav",
+		"main", "aside", "figure", "table", "dl",
+		"h1", "h2", "h3", "h4", "h5", "h6":
+		r.block(2)
+	case "tr", "dt", "dd", "li":
+		r.block(1)
+	case "ul", "ol":
+		if len(r.lists) > 0 {
+			r.lists = r.lists[:len(r.lists)-1]
+		}
+		if len(r.lists) == 0 {
+			r.block(2)
+		} else {
+			r.block(1)
+		}
+	case "blockquote":
+		if r.quote > 0 {
+			r.quote--
+		}
+		r.block(2)
+	case "pre":
+		if r.pre > 0 {
+			r.pre--
+		}
+		if r.md {
+			r.newlines = 1
+			r.write("```")
+		}
+		r.block(2)
+	case "b", "strong":
+		r.closeInline("**")
+	case "i", "em":
+		r.closeInline("_")
+	case "s", "del", "strike":
+		r.closeInline("~~")
+	case "code":
+		if r.pre == 0 {
+			r.closeInline("`")
+		}
+	case "a":
+		href := ""
+		if len(r.links) > 0 {
+			href = r.links[len(r.links)-1]
+			r.links = r.links[:len(r.links)-1]
+		}
+		if r.md {
+			r.b.WriteString("](" + href + ")")
+		}
+	}
+}
+
+// inline writes the opening marker of an inline Markdown element.
+func (r *renderer) inline(marker string) {
+	if r.md {
+		r.write(marker)
+		r.linestart = true // no space after the marker
+	}
+}
+
+// closeInline writes the closing marker of an inline Markdown element,
+// which sticks to the preceding content.
+func (r *renderer) closeInline(marker string) {
+	if r.md {
+		r.b.WriteString(marker)
+	}
+}
+
+// escapeMarkdown escapes the characters of text that Markdown would
+// interpret as formatting.
+func escapeMarkdown(s string) string {
+	if !strings.ContainsAny(s, "\\*_`[]") {
+		return s
+	}
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune("\\*_`[]", c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// Kinds of HTML tokens.
+const (
+	textToken = iota
+	startToken
+	endToken
+)
+
+// token is an HTML token. The data is the unescaped text of a text token,
+// or the lower case name of a tag.
+type token struct {
+	kind  int
+	data  string
+	attrs map[string]string
+}
+
+// tokenize splits the given HTML into tokens. It is lenient to malformed
+// HTML, as clipboard data is produced by all sorts of applications, and
+// skips comments, declarations, and the content of elements that are not
+// rendered, such as scripts.
+func tokenize(s string) []token {
+	var (
+		toks []token
+		text strings.Builder
+	)
+	flushText := func() {
+		if text.Len() > 0 {
+			toks = append(toks, token{kind: textToken, data: html.UnescapeString(text.String())})
+			text.Reset()
+		}
+	}
+
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			text.WriteString(s)
+			break
+		}
+		text.WriteString(s[:i])
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				return append(toks, token{kind: textToken, data: html.UnescapeString(text.String())})
+			}
+			s = s[end+3:]
+			continue
+		case strings.HasPrefix(s, "<!"), strings.HasPrefix(s, "<?"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				s = ""
+			} else {
+				s = s[end+1:]
+			}
+			continue
+		}
+
+		t, n, ok := parseTag(s)
+		if !ok {
+			text.WriteByte('<')
+			s = s[1:]
+			continue
+		}
+		s = s[n:]
+		flushText()
+		switch t.data {
+		case "script", "style", "head", "title", "template", "noscript":
+			if t.kind == startToken {
+				// Skip the content of the element altogether.
+				end := strings.Index(strings.ToLower(s), "</"+t.data)
+				if end < 0 {
+					s = ""
+					continue
+				}
+				s = s[end:]
+				if gt := strings.IndexByte(s, '>'); gt >= 0 {
+					s = s[gt+1:]
+				} else {
+					s = ""
+				}
+			}
+			continue
+		}
+		toks = append(toks, t)
+	}
+	flushText()
+	return toks
+}
+
+// parseTag parses the tag at the beginning of s, and returns the tag and
+// its length. Self-closing tags are returned as start tags.
+func parseTag(s string) (t token, n int, ok bool) {
+	i := 1
+	t.kind = startToken
+	if i < len(s) && s[i] == '/' {
+		t.kind = endToken
+		i++
+	}
+	start := i
+	for i < len(s) && (isLetter(s[i]) || (i > start && s[i] >= '0' && s[i] <= '9')) {
+		i++
+	}
+	if i == start {
+		return t, 0, false
+	}
+	t.data = strings.ToLower(s[start:i])
+
+	for i < len(s) && s[i] != '>' {
+		if isSpace(s[i]) || s[i] == '/' {
+			i++
+			continue
+		}
+		// Attribute name, and an optional value that may be quoted.
+		ns := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[ns:i])
+		val := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return t, 0, false
+				}
+				val = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				vs := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				val = s[vs:i]
+			}
+		}
+		if t.kind == startToken && name != "" {
+			if t.attrs == nil {
+				t.attrs = map[string]string{}
+			}
+			t.attrs[name] = html.UnescapeString(val)
+		}
+	}
+	if i >= len(s) {
+		return t, 0, false
+	}
+	return t, i + 1, true
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats_test
+
+import (
+	"testing"
+
+	"golang.design/x/clipboard/formats"
+)
+
+// page is HTML as browsers put on the clipboard.
+const page = `<html><head><title>x</title><style>p{}</style></head><body>
+<!--StartFragment--><h2>Clipboard</h2>
+<p>Cross-platform  <b>clipboard</b> package in <a href="https://go.dev">Go</a>.</p>
+<ul><li>text</li><li>image<ol start="3"><li>png</li><li>bmp</li></ol></li></ul>
+<pre>go get golang.design/x/clipboard
+  done</pre>
+<blockquote><p>a &amp; b_c</p></blockquote>
+<p>x<br>y <img src="a.png" alt="logo"></p><!--EndFragment-->
+<script>alert(1)</script></body></html>`
+
+func TestHTMLToText(t *testing.T) {
+	want := `Clipboard
+
+Cross-platform clipboard package in Go.
+
+- text
+- image
+  3. png
+  4. bmp
+
+go get golang.design/x/clipboard
+  done
+
+a & b_c
+
+x
+y logo`
+	if got := formats.HTMLToText([]byte(page)); got != want {
+		t.Fatalf("rendered text mismatch, got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	want := "## Clipboard\n\n" +
+		"Cross-platform **clipboard** package in [Go](https://go.dev).\n\n" +
+		"- text\n- image\n  3. png\n  4. bmp\n\n" +
+		"```\ngo get golang.design/x/clipboard\n  done\n```\n\n" +
+		"> a & b\\_c\n\n" +
+		"x  \ny ![logo](a.png)"
+	if got := formats.HTMLToMarkdown([]byte(page)); got != want {
+		t.Fatalf("rendered markdown mismatch, got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Malformed HTML is rendered as far as possible.
+	if got := formats.HTMLToMarkdown([]byte("<b>1 < 2</b><i")); got != "**1 < 2**<i" {
+		t.Fatalf("rendered malformed markdown mismatch, got: %q", got)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// EncodeUTF16 encodes the given string in UTF-16LE without a byte order
+// mark and terminator, as the native text formats of Windows and some
+// X11 targets, such as text/x-moz-url, expect.
+func EncodeUTF16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+// DecodeUTF16 decodes UTF-16 encoded data, whose byte order is indicated
+// by a leading byte order mark, or little endian without it. A trailing
+// odd byte is ignored.
+func DecodeUTF16(buf []byte) string {
+	if len(buf) >= 2 && buf[0] == 0xfe && buf[1] == 0xff {
+		return decodeUTF16(buf[2:], binary.BigEndian)
+	}
+	if len(buf) >= 2 && buf[0] == 0xff && buf[1] == 0xfe {
+		buf = buf[2:]
+	}
+	return decodeUTF16(buf, binary.LittleEndian)
+}
+
+func decodeUTF16(buf []byte, order binary.ByteOrder) string {
+	u := make([]uint16, len(buf)/2)
+	for i := range u {
+		u[i] = order.Uint16(buf[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// UTF16Len returns the number of UTF-16 code units that EncodeUTF16Into
+// encodes the given UTF-8 encoded text in.
+func UTF16Len(src []byte) int {
+	n := 0
+	for i := 0; i < len(src); {
+		if src[i] < utf8.RuneSelf {
+			n++
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(src[i:])
+		i += size
+		n += len16(r)
+	}
+	return n
+}
+
+// EncodeUTF16Into encodes the given UTF-8 encoded text in UTF-16 into
+// dst, which must hold at least UTF16Len(src) code units, and returns the
+// number of written code units. Invalid UTF-8 is encoded as U+FFFD. Unlike
+// EncodeUTF16, it needs no intermediate copies of the text, so that huge
+// text can be encoded right into the memory of the clipboard.
+func EncodeUTF16Into(dst []uint16, src []byte) int {
+	n := 0
+	for i := 0; i < len(src); {
+		if c := src[i]; c < utf8.RuneSelf {
+			dst[n] = uint16(c)
+			n++
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(src[i:])
+		i += size
+		if len16(r) == 2 {
+			r1, r2 := utf16.EncodeRune(r)
+			dst[n], dst[n+1] = uint16(r1), uint16(r2)
+			n += 2
+			continue
+		}
+		dst[n] = uint16(r)
+		n++
+	}
+	return n
+}
+
+// DecodeUTF16Units decodes the given UTF-16 code units to UTF-8, where
+// unpaired surrogates are decoded as U+FFFD. The result is allocated once
+// in its exact size, which keeps the memory of decoding huge text close
+// to the size of the text.
+func DecodeUTF16Units(src []uint16) []byte {
+	n := 0
+	for i := 0; i < len(src); {
+		if src[i] < utf8.RuneSelf {
+			n++
+			i++
+			continue
+		}
+		r, size := nextRune(src, i)
+		i += size
+		n += utf8.RuneLen(r)
+	}
+	dst := make([]byte, n)
+	n = 0
+	for i := 0; i < len(src); {
+		if c := src[i]; c < utf8.RuneSelf {
+			dst[n] = byte(c)
+			n++
+			i++
+			continue
+		}
+		r, size := nextRune(src, i)
+		i += size
+		n += utf8.EncodeRune(dst[n:], r)
+	}
+	return dst
+}
+
+// len16 returns the number of UTF-16 code units of the given rune.
+func len16(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+// nextRune decodes the rune at src[i], and returns the rune and the
+// number of code units it takes.
+func nextRune(src []uint16, i int) (rune, int) {
+	c := rune(src[i])
+	switch {
+	case c < 0xd800 || c >= 0xe000:
+		return c, 1
+	case c < 0xdc00 && i+1 < len(src):
+		if r := utf16.DecodeRune(c, rune(src[i+1])); r != utf8.RuneError {
+			return r, 2
+		}
+	}
+	return utf8.RuneError, 1
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package formats_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"golang.design/x/clipboard/formats"
+)
+
+func TestUTF16(t *testing.T) {
+	s := "https://golang.design/数据"
+	if got := formats.DecodeUTF16(formats.EncodeUTF16(s)); got != s {
+		t.Fatalf("decoded string mismatch, got: %q, want: %q", got, s)
+	}
+	be := []byte{0xfe, 0xff, 0, 'g', 0, 'o'}
+	if got := formats.DecodeUTF16(be); got != "go" {
+		t.Fatalf("decoded big endian string mismatch, got: %q, want: %q", got, "go")
+	}
+}
+
+func TestUTF16Units(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"golang.design",
+		"https://golang.design/数据 🦫",
+		"invalid \xff utf-8 \xed\xa0\x80",
+	} {
+		want := utf16.Encode([]rune(s))
+		if n := formats.UTF16Len([]byte(s)); n != len(want) {
+			t.Fatalf("length of %q mismatch, got: %d, want: %d", s, n, len(want))
+		}
+		got := make([]uint16, len(want))
+		if n := formats.EncodeUTF16Into(got, []byte(s)); n != len(want) {
+			t.Fatalf("encoded length of %q mismatch, got: %d, want: %d", s, n, len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("encoded %q mismatch, got: %v, want: %v", s, got, want)
+			}
+		}
+		if b := formats.DecodeUTF16Units(got); string(b) != string([]rune(s)) {
+			t.Fatalf("decoded %q mismatch, got: %q", s, b)
+		}
+	}
+
+	// Unpaired surrogates decode as U+FFFD, as utf16.Decode does.
+	units := []uint16{'a', 0xd800, 'b', 0xdc00, 0xd83e}
+	if got, want := string(formats.DecodeUTF16Units(units)), string(utf16.Decode(units)); got != want {
+		t.Fatalf("decoded unpaired surrogates mismatch, got: %q, want: %q", got, want)
+	}
+}
+
+func TestUTF16UnitsMemory(t *testing.T) {
+	text := []byte(strings.Repeat("golang.design/x/clipboard 数据\n", 1<<14))
+	units := make([]uint16, formats.UTF16Len(text))
+
+	// Encoding into the given memory allocates nothing, and decoding
+	// allocates the text once.
+	if n := testing.AllocsPerRun(10, func() { formats.EncodeUTF16Into(units, text) }); n != 0 {
+		t.Fatalf("encoding allocates %v times", n)
+	}
+	var got []byte
+	if n := testing.AllocsPerRun(10, func() { got = formats.DecodeUTF16Units(units) }); n != 1 {
+		t.Fatalf("decoding allocates %v times", n)
+	}
+	if !bytes.Equal(got, text) {
+		t.Fatalf("decoded text mismatch")
+	}
+}
+
+func BenchmarkEncodeUTF16Into(b *testing.B) {
+	text := []byte(strings.Repeat("golang.design/x/clipboard\n", 1<<16))
+	units := make([]uint16, formats.UTF16Len(text))
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		formats.EncodeUTF16Into(units, text)
+	}
+}
+
+func BenchmarkDecodeUTF16Units(b *testing.B) {
+	text := []byte(strings.Repeat("golang.design/x/clipboard\n", 1<<16))
+	units := make([]uint16, formats.UTF16Len(text))
+	formats.EncodeUTF16Into(units, text)
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		formats.DecodeUTF16Units(units)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build heif && ((darwin && !ios) || (linux && !android)) && cgo
+
+package clipboard
+
+// HEIC/AVIF images are not supported by the standard library. When the
+// package is built with the heif build tag, the platform decoders
+// (ImageIO on macOS, libheif on Linux) are registered as converters so
+// that reading FmtImage transcodes these images to PNG:
+//
+//	go build -tags heif
+
+const (
+	mimeHEIC = "image/heic"
+	mimeHEIF = "image/heif"
+	mimeAVIF = "image/avif"
+)
+
+func init() {
+	for _, mime := range []string{mimeHEIC, mimeHEIF, mimeAVIF} {
+		RegisterConverter(mime, mimePNG, heifToPNG)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build darwin && !ios && heif && cgo
+
+package clipboard
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation -framework Cocoa
+#import <Foundation/Foundation.h>
+
+unsigned int clipboard_heif_to_png(const void *bytes, NSInteger n, void **out);
+*/
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// heifToPNG transcodes HEIC/AVIF images to PNG using ImageIO, which
+// supports HEIC since macOS 10.13 and AVIF since macOS 13.
+func heifToPNG(src []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errors.New("empty image data")
+	}
+
+	var data unsafe.Pointer
+	n := C.clipboard_heif_to_png(unsafe.Pointer(&src[0]), C.NSInteger(len(src)), &data)
+	if data == nil {
+		return nil, errors.New("failed to decode image")
+	}
+	defer C.free(data)
+	return C.GoBytes(data, C.int(n)), nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build darwin && !ios && heif
+
+#import <Foundation/Foundation.h>
+#import <Cocoa/Cocoa.h>
+
+// clipboard_heif_to_png decodes the given image data using the image
+// decoders of the system and encodes the image as PNG.
+unsigned int clipboard_heif_to_png(const void *bytes, NSInteger n, void **out) {
+	NSData *data = [NSData dataWithBytes: bytes length: n];
+	NSBitmapImageRep *rep = [NSBitmapImageRep imageRepWithData: data];
+	if (rep == nil) {
+		return 0;
+	}
+	NSData *png = [rep representationUsingType: NSBitmapImageFileTypePNG
+		properties: @{}];
+	if (png == nil) {
+		return 0;
+	}
+	NSUInteger siz = [png length];
+	*out = malloc(siz);
+	[png getBytes: *out length: siz];
+	return siz;
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build linux && !android && heif
+
+#include <stdlib.h>
+#include <stdint.h>
+#include <string.h>
+#include <dlfcn.h>
+
+// Declarations of the used libheif API, see:
+// https://github.com/strukturag/libheif/blob/master/libheif/heif.h
+struct heif_context;
+struct heif_image_handle;
+struct heif_image;
+struct heif_error {
+	int code;
+	int subcode;
+	const char *message;
+};
+
+enum {
+	heif_colorspace_RGB          = 1,
+	heif_chroma_interleaved_RGBA = 11,
+	heif_channel_interleaved     = 10,
+};
+
+void *libheif;
+
+struct heif_context* (*P_heif_context_alloc)(void);
+void (*P_heif_context_free)(struct heif_context*);
+struct heif_error (*P_heif_context_read_from_memory_without_copy)(struct heif_context*, const void*, size_t, const void*);
+struct heif_error (*P_heif_context_get_primary_image_handle)(struct heif_context*, struct heif_image_handle**);
+void (*P_heif_image_handle_release)(const struct heif_image_handle*);
+struct heif_error (*P_heif_decode_image)(const struct heif_image_handle*, struct heif_image**, int, int, const void*);
+void (*P_heif_image_release)(const struct heif_image*);
+int (*P_heif_image_get_width)(const struct heif_image*, int);
+int (*P_heif_image_get_height)(const struct heif_image*, int);
+const uint8_t* (*P_heif_image_get_plane_readonly)(const struct heif_image*, int, int*);
+
+int initHeif() {
+	if (libheif) {
+		return 1;
+	}
+	libheif = dlopen("libheif.so.1", RTLD_LAZY);
+	if (!libheif) {
+		libheif = dlopen("libheif.so", RTLD_LAZY);
+	}
+	if (!libheif) {
+		return 0;
+	}
+	P_heif_context_alloc = (struct heif_context* (*)(void)) dlsym(libheif, "heif_context_alloc");
+	P_heif_context_free = (void (*)(struct heif_context*)) dlsym(libheif, "heif_context_free");
+	P_heif_context_read_from_memory_without_copy = (struct heif_error (*)(struct heif_context*, const void*, size_t, const void*)) dlsym(libheif, "heif_context_read_from_memory_without_copy");
+	if (!P_heif_context_read_from_memory_without_copy) {
+		// Older libheif only reads a copy of the data.
+		P_heif_context_read_from_memory_without_copy = (struct heif_error (*)(struct heif_context*, const void*, size_t, const void*)) dlsym(libheif, "heif_context_read_from_memory");
+	}
+	P_heif_context_get_primary_image_handle = (struct heif_error (*)(struct heif_context*, struct heif_image_handle**)) dlsym(libheif, "heif_context_get_primary_image_handle");
+	P_heif_image_handle_release = (void (*)(const struct heif_image_handle*)) dlsym(libheif, "heif_image_handle_release");
+	P_heif_decode_image = (struct heif_error (*)(const struct heif_image_handle*, struct heif_image**, int, int, const void*)) dlsym(libheif, "heif_decode_image");
+	P_heif_image_release = (void (*)(const struct heif_image*)) dlsym(libheif, "heif_image_release");
+	P_heif_image_get_width = (int (*)(const struct heif_image*, int)) dlsym(libheif, "heif_image_get_width");
+	P_heif_image_get_height = (int (*)(const struct heif_image*, int)) dlsym(libheif, "heif_image_get_height");
+	P_heif_image_get_plane_readonly = (const uint8_t* (*)(const struct heif_image*, int, int*)) dlsym(libheif, "heif_image_get_plane_readonly");
+	if (!P_heif_context_alloc || !P_heif_context_free ||
+		!P_heif_context_read_from_memory_without_copy ||
+		!P_heif_context_get_primary_image_handle || !P_heif_image_handle_release ||
+		!P_heif_decode_image || !P_heif_image_release ||
+		!P_heif_image_get_width || !P_heif_image_get_height ||
+		!P_heif_image_get_plane_readonly) {
+		dlclose(libheif);
+		libheif = NULL;
+		return 0;
+	}
+	return 1;
+}
+
+// heif_decode_rgba decodes the primary image of the given HEIF/AVIF data
+// as non-premultiplied RGBA pixels. The pixels are tightly packed into
+// out, and the caller is responsible for the free of the out buffer.
+// It returns -1 if libheif is not available, -2 if the data cannot be
+// parsed, -3 if the image cannot be decoded, and -4 if the decoded
+// pixels cannot be taken.
+int heif_decode_rgba(const void *buf, size_t n, unsigned char **out, int *w, int *h) {
+	if (!initHeif()) {
+		return -1;
+	}
+
+	struct heif_context *ctx = (*P_heif_context_alloc)();
+	if (ctx == NULL) {
+		return -1;
+	}
+	struct heif_error err = (*P_heif_context_read_from_memory_without_copy)(ctx, buf, n, NULL);
+	if (err.code != 0) {
+		(*P_heif_context_free)(ctx);
+		return -2;
+	}
+	struct heif_image_handle *handle = NULL;
+	err = (*P_heif_context_get_primary_image_handle)(ctx, &handle);
+	if (err.code != 0) {
+		(*P_heif_context_free)(ctx);
+		return -2;
+	}
+	struct heif_image *img = NULL;
+	err = (*P_heif_decode_image)(handle, &img,
+		heif_colorspace_RGB, heif_chroma_interleaved_RGBA, NULL);
+	if (err.code != 0) {
+		(*P_heif_image_handle_release)(handle);
+		(*P_heif_context_free)(ctx);
+		return -3;
+	}
+
+	int ret = 0;
+	int stride = 0;
+	const uint8_t *data = (*P_heif_image_get_plane_readonly)(img, heif_channel_interleaved, &stride);
+	*w = (*P_heif_image_get_width)(img, heif_channel_interleaved);
+	*h = (*P_heif_image_get_height)(img, heif_channel_interleaved);
+	if (data == NULL || *w <= 0 || *h <= 0 || stride < *w * 4) {
+		ret = -4;
+	} else if ((*out = (unsigned char *)malloc((size_t)(*w) * (*h) * 4)) == NULL) {
+		ret = -4;
+	} else {
+		for (int y = 0; y < *h; y++) {
+			memcpy(*out + (size_t)y * (*w) * 4, data + (size_t)y * stride, (size_t)(*w) * 4);
+		}
+	}
+
+	(*P_heif_image_release)(img);
+	(*P_heif_image_handle_release)(handle);
+	(*P_heif_context_free)(ctx);
+	return ret;
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build linux && !android && heif && cgo
+
+package clipboard
+
+/*
+#cgo LDFLAGS: -ldl
+#include <stdlib.h>
+
+int heif_decode_rgba(const void *buf, size_t n, unsigned char **out, int *w, int *h);
+*/
+import "C"
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"unsafe"
+)
+
+// heifToPNG transcodes HEIC/AVIF images to PNG using libheif, which
+// is loaded at runtime. Install libheif1 to enable the decoding.
+func heifToPNG(src []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errors.New("empty image data")
+	}
+
+	var (
+		data *C.uchar
+		w, h C.int
+	)
+	switch C.heif_decode_rgba(unsafe.Pointer(&src[0]), C.size_t(len(src)), &data, &w, &h) {
+	case 0:
+	case -1:
+		return nil, errors.New("failed to load libheif, is libheif installed?")
+	default:
+		return nil, errors.New("failed to decode image")
+	}
+	defer C.free(unsafe.Pointer(data))
+
+	img := image.NewNRGBA(image.Rect(0, 0, int(w), int(h)))
+	copy(img.Pix, C.GoBytes(unsafe.Pointer(data), C.int(len(img.Pix))))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
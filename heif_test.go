@@ -0,0 +1,41 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build heif
+
+package clipboard_test
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestHEIFDecodeFailure(t *testing.T) {
+	if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+		t.Skip("CGO_ENABLED is set to 0")
+	}
+	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
+		t.Skip("HEIC/AVIF images are only supported on macOS and Linux")
+	}
+
+	tests := map[string][]byte{
+		"empty":     nil,
+		"garbage":   []byte("golang.design/x/clipboard"),
+		"truncated": []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, mime := range []string{"image/heic", "image/heif", "image/avif"} {
+				if b, err := clipboard.Convert(mime, "image/png", data); err == nil {
+					t.Fatalf("expect an error from decoding %s, got %d bytes", mime, len(b))
+				}
+			}
+		})
+	}
+}
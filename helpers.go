@@ -0,0 +1,174 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+)
+
+// ReadString returns the text of the clipboard. It returns an empty
+// string if the clipboard holds no text.
+func ReadString() string {
+	return string(Read(FmtText))
+}
+
+// WriteString writes the given text to the clipboard. Like Write, the
+// returned channel receives a signal if the clipboard has been
+// overwritten from this write.
+func WriteString(s string) <-chan struct{} {
+	return Write(FmtText, []byte(s))
+}
+
+// ReadImage returns the decoded image of the clipboard. It returns
+// ErrUnavailable if the clipboard holds no image.
+func ReadImage() (image.Image, error) {
+	buf, err := ReadErr(FmtImage)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) == 0 {
+		return nil, ErrUnavailable
+	}
+	return png.Decode(bytes.NewReader(buf))
+}
+
+// ReadImageAs returns the image of the clipboard encoded in the given
+// MIME type, such as "image/jpeg". Besides PNG, JPEG, GIF, BMP and TIFF
+// are supported, as well as the MIME types of registered converters from
+// PNG. It returns ErrUnavailable if the clipboard holds no image.
+func ReadImageAs(mime string) ([]byte, error) {
+	buf, err := ReadErr(FmtImage)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) == 0 {
+		return nil, ErrUnavailable
+	}
+	return Convert(mimePNG, mime, buf)
+}
+
+// WriteImage writes the given image to the clipboard. Like WriteErr,
+// the returned channel receives a signal if the clipboard has been
+// overwritten from this write.
+func WriteImage(img image.Image) (<-chan struct{}, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return WriteErr(FmtImage, buf.Bytes())
+}
+
+// ReadFiles returns the absolute paths of the files that are copied to
+// the clipboard, for instance, from a file manager. It returns
+// ErrUnavailable if the clipboard holds no files.
+func ReadFiles() ([]string, error) {
+	buf, err := ReadErr(FmtFiles)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) == 0 {
+		return nil, ErrUnavailable
+	}
+	return splitFiles(buf), nil
+}
+
+// WriteFiles writes the given file paths to the clipboard, so that the
+// files can be pasted into a file manager. Relative paths are resolved
+// against the working directory. Like WriteErr, the returned channel
+// receives a signal if the clipboard has been overwritten from this write.
+func WriteFiles(paths []string) (<-chan struct{}, error) {
+	abs := make([]string, len(paths))
+	for i, p := range paths {
+		a, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		abs[i] = a
+	}
+	return WriteErr(FmtFiles, joinFiles(abs))
+}
+
+// PasteFiles returns the paths of the files on the clipboard, for
+// instance, to implement pasting files into a window of an application.
+// Files that are copied from a file manager are returned as ReadFiles
+// does. Files that only exist as clipboard data are saved into dir, or a
+// new temporary directory if dir is empty, and the paths of the saved
+// files are returned. Such files are the file promises of macOS, as
+// copied from Photos or Mail, and the virtual files of Windows, as copied
+// from attachments of Outlook or files in archives. It returns
+// ErrUnavailable if the clipboard holds no files.
+//
+// The caller owns the saved files, and is responsible for removing them
+// once they are no longer needed. See WatchFiles to paste files whenever
+// they are copied.
+func PasteFiles(dir string) ([]string, error) {
+	paths, err := ReadFiles()
+	if err == nil || !errors.Is(err, ErrUnavailable) {
+		return paths, err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	paths, err = sys.virtualFiles(dir)
+	if err == nil && len(paths) == 0 {
+		err = ErrUnavailable
+	}
+	return paths, err
+}
+
+// ReadColor returns the color of the clipboard, as copied from the color
+// picker of a design tool. It returns ErrUnavailable if the clipboard
+// holds no color.
+func ReadColor() (color.NRGBA64, error) {
+	buf, err := ReadErr(FmtColor)
+	if err != nil {
+		return color.NRGBA64{}, err
+	}
+	if len(buf) == 0 {
+		return color.NRGBA64{}, ErrUnavailable
+	}
+	return decodeColor(buf)
+}
+
+// WriteColor writes the given color to the clipboard, so that it can be
+// pasted into design tools. Like WriteErr, the returned channel receives
+// a signal if the clipboard has been overwritten from this write.
+func WriteColor(c color.Color) (<-chan struct{}, error) {
+	return WriteErr(FmtColor, encodeColor(c))
+}
+
+// ocr is the optical character recognition engine of WithOCR.
+var ocr func(png []byte) (string, error)
+
+// ReadTextOrOCR returns the text of the clipboard. If the clipboard holds
+// no text but an image, it returns the text that the engine of WithOCR
+// recognizes from the image. It returns ErrUnavailable if the clipboard
+// holds neither text nor an image, and ErrUnsupported if there is no
+// engine to recognize an image.
+func ReadTextOrOCR() (string, error) {
+	buf, err := ReadErr(FmtText)
+	if err == nil && len(buf) != 0 {
+		return string(buf), nil
+	}
+	img, ierr := ReadErr(FmtImage)
+	if ierr != nil || len(img) == 0 {
+		if err != nil {
+			return "", err
+		}
+		return "", ErrUnavailable
+	}
+	if ocr == nil {
+		return "", fmt.Errorf("%w: no OCR engine, see WithOCR", ErrUnsupported)
+	}
+	return ocr(img)
+}
@@ -0,0 +1,25 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"errors"
+
+	"golang.design/x/clipboard/formats"
+)
+
+// decodeCFHTML returns the HTML fragment of the given CF_HTML data. It
+// returns a *MalformedError if the header is missing or the offsets of
+// the header are inconsistent with the data.
+func decodeCFHTML(buf []byte) ([]byte, error) {
+	frag, err := formats.DecodeCFHTML(buf)
+	var ferr *formats.Error
+	if errors.As(err, &ferr) {
+		return nil, &MalformedError{Format: FmtHTML, Reason: ferr.Reason}
+	}
+	return frag, err
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.design/x/clipboard"
+	"golang.design/x/clipboard/formats"
+)
+
+func TestCFHTML(t *testing.T) {
+	frag := []byte("<b>golang.design</b>/x/clipboard")
+
+	got, err := clipboard.DecodeCFHTML(formats.EncodeCFHTML(frag))
+	if err != nil {
+		t.Fatalf("failed to decode CF_HTML: %v", err)
+	}
+	if string(got) != string(frag) {
+		t.Fatalf("decoded fragment mismatch, got: %s, want: %s", got, frag)
+	}
+
+	malformed := []string{
+		"<b>no header</b>",
+		"Version:0.9\r\nStartFragment:10\r\nEndFragment:99999\r\n<b>x</b>",
+		"Version:0.9\r\nStartFragment:x\r\nEndFragment:10\r\n<b>x</b>",
+	}
+	for _, m := range malformed {
+		_, err := clipboard.DecodeCFHTML([]byte(m))
+		var merr *clipboard.MalformedError
+		if !errors.As(err, &merr) {
+			t.Fatalf("expect a MalformedError for %q, got: %v", m, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"image"
+)
+
+// ImageInfo returns the dimensions and the MIME type of the image that
+// the clipboard holds, which is the native representation of the image
+// before it is transcoded to PNG by Read. Only the header of the image
+// is parsed without decoding the pixels, so that, for instance, image
+// pickers can show the dimensions of huge images instantly.
+//
+// ImageInfo returns ErrUnavailable if the clipboard holds no image.
+func ImageInfo() (width, height int, mime string, err error) {
+	if err := ready(); err != nil {
+		return 0, 0, "", err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	return sys.imageInfo()
+}
+
+// readImageInfo reads the image data of the clipboard in its native
+// representation using readData, and parses the header of the image.
+func readImageInfo(readData func(mime string) ([]byte, error)) (width, height int, mime string, err error) {
+	for _, m := range append([]string{mimePNG}, convertible(mimePNG)...) {
+		buf, err := readData(m)
+		if err != nil || len(buf) == 0 {
+			continue
+		}
+		return decodeImageInfo(buf)
+	}
+	return 0, 0, "", ErrUnavailable
+}
+
+// decodeImageInfo parses the header of the given image data.
+func decodeImageInfo(buf []byte) (width, height int, mime string, err error) {
+	cfg, name, err := image.DecodeConfig(bytes.NewReader(buf))
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return cfg.Width, cfg.Height, "image/" + name, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+// Info describes the behavior of the backend that Init selected, which
+// differs between the platforms, so that tests and applications can
+// check the facts rather than the platform.
+type Info struct {
+	// Backend is the backend that Init selected, which is BackendAuto
+	// for the only backend of the platforms other than Linux.
+	Backend Backend
+	// Protocol is the name and version of the clipboard facility of the
+	// platform that the backend uses, such as "X11 ICCCM 2.0".
+	Protocol string
+	// EventDriven reports whether the change detection of Watch is
+	// notified of changes, rather than polling the clipboard at the poll
+	// interval, see WithPollInterval.
+	EventDriven bool
+	// SequenceNumber reports whether the clipboard has a sequence number
+	// that tells its changes, see Sequence. Without it, the change
+	// detection compares the data of the clipboard.
+	SequenceNumber bool
+	// DelayedRendering reports whether the data of a write is served by
+	// the writing process on demand when it is pasted, rather than
+	// copied to the clipboard at the time of the write. The data then
+	// vanishes once the process exits, unless a clipboard manager saved
+	// it, see WithPersistence.
+	DelayedRendering bool
+	// MaxPayload is the maximum number of bytes of the data of a write,
+	// or zero if the size is only limited by the available memory.
+	MaxPayload int
+}
+
+// BackendInfo returns the behavior of the backend that Init selected. It
+// returns the zero Info if Init has not been called or failed.
+func BackendInfo() Info {
+	if err := ready(); err != nil {
+		return Info{}
+	}
+	return sys.info()
+}
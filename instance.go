@@ -0,0 +1,104 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+// Clipboard is a clipboard that is independent of the clipboard of Init,
+// for instance, the clipboard of another X display in multi-seat setups
+// or nested X servers, such as Xephyr:
+//
+//	seat, err := clipboard.New(clipboard.WithDisplay(":1"))
+//	if err != nil {
+//		// ...
+//	}
+//	buf, err := seat.Read(clipboard.FmtText)
+//
+// A Clipboard only reads and writes data. Watching, the change channels
+// of writes and the other functions of the package work with the
+// clipboard of Init. It is safe for concurrent use.
+type Clipboard struct {
+	sys system
+}
+
+// New returns a clipboard that is configured by the given options, such
+// as WithDisplay to connect to the given X display rather than the one
+// of the DISPLAY environment variable. Options that configure the package
+// as a whole, such as WithPollInterval, have no effect, and the package
+// settings, such as the line endings of WithReadLineEnding, apply once
+// Init is called.
+//
+// Multiple clipboards are only supported by the X11 backend, and by
+// BackendMemory on all platforms, where every call of New returns a new
+// in-memory clipboard. New returns an error that wraps ErrUnsupported
+// elsewhere, and ErrUnavailable if the display cannot be connected.
+func New(opts ...Option) (*Clipboard, error) {
+	c := config{readTimeout: -1}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.backend == BackendMemory {
+		return &Clipboard{sys: (&memory{}).system()}, nil
+	}
+	s, err := newSystem(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Clipboard{sys: s}, nil
+}
+
+// Read is like ReadErr but reads the clipboard c.
+func (c *Clipboard) Read(t Format) ([]byte, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	buf, err := c.sys.read(t)
+	if err != nil {
+		return nil, err
+	}
+	return checked(t, buf)
+}
+
+// ReadData is like the package function ReadData but reads the
+// clipboard c.
+func (c *Clipboard) ReadData(mime string) ([]byte, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return c.sys.readData(mime)
+}
+
+// Write is like WriteErr but writes the clipboard c. The returned channel
+// receives a signal once the data is no longer served, for instance, the
+// clipboard has been overwritten.
+func (c *Clipboard) Write(t Format, buf []byte) (<-chan struct{}, error) {
+	buf, err := prepared(t, buf)
+	if err != nil {
+		return nil, err
+	}
+	extra := []representation{origin(false, sideRef{})}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	return c.sys.write(t, buf, extra)
+}
+
+// WriteData is like the package function WriteData but writes the
+// clipboard c.
+func (c *Clipboard) WriteData(mime string, buf []byte) (<-chan struct{}, error) {
+	if mime == "" {
+		return nil, ErrUnsupported
+	}
+	extra := []representation{origin(false, sideRef{})}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	return c.sys.writeData(mime, buf, extra)
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestNew(t *testing.T) {
+	a, err := clipboard.New(clipboard.WithBackend(clipboard.BackendMemory))
+	if err != nil {
+		t.Fatalf("failed to create a clipboard: %v", err)
+	}
+	b, err := clipboard.New(clipboard.WithBackend(clipboard.BackendMemory))
+	if err != nil {
+		t.Fatalf("failed to create a clipboard: %v", err)
+	}
+
+	want := []byte("golang.design/x/clipboard")
+	changed, err := a.Write(clipboard.FmtText, want)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if got, err := a.Read(clipboard.FmtText); err != nil || string(got) != string(want) {
+		t.Fatalf("read mismatch, got: %q, %v, want: %q", got, err, want)
+	}
+	if got, _ := b.Read(clipboard.FmtText); got != nil {
+		t.Fatalf("the clipboards are not independent, got: %q", got)
+	}
+
+	if _, err := a.WriteData("text/html", []byte("<b>golang.design</b>")); err != nil {
+		t.Fatalf("failed to write data: %v", err)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatalf("the write is not told that the clipboard has been overwritten")
+	}
+	if got, err := a.ReadData("text/html"); err != nil || string(got) != "<b>golang.design</b>" {
+		t.Fatalf("read data mismatch, got: %q, %v", got, err)
+	}
+}
+
+func TestNewDisplay(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("displays are specific to X11")
+	}
+	if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+		t.Skip("CGO_ENABLED is set to 0")
+	}
+
+	_, err := clipboard.New(clipboard.WithDisplay(":4242"))
+	if !errors.Is(err, clipboard.ErrUnavailable) {
+		t.Fatalf("expect ErrUnavailable for a display that does not exist, got: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"sync"
+)
+
+var (
+	matteMu sync.Mutex
+	matte   color.Color
+)
+
+// SetImageMatte sets the background color of the matted variant that
+// image writes offer in addition to the PNG image. A nil color, which
+// is the default, disables the matted variant.
+//
+// Some applications render pasted transparent images poorly, for
+// instance, on dark backgrounds. The matted variant composites the
+// image onto the given background, and it is offered as a JPEG image
+// without transparency, so that receiving applications can choose the
+// representation they render best. For example:
+//
+//	clipboard.SetImageMatte(color.White)
+//
+// On Windows, the color is also the background of the CF_DIB that is
+// always offered without transparency, which defaults to white.
+func SetImageMatte(c color.Color) {
+	matteMu.Lock()
+	defer matteMu.Unlock()
+
+	matte = c
+}
+
+// imageMatte returns the background color that is set by SetImageMatte.
+func imageMatte() color.Color {
+	matteMu.Lock()
+	defer matteMu.Unlock()
+
+	return matte
+}
+
+// matted returns the matted variant of the given image data if the
+// image matte is set and t indicates an image.
+func matted(t Format, buf []byte) (representation, bool) {
+	bg := imageMatte()
+	if t != FmtImage || bg == nil || len(buf) == 0 {
+		return representation{}, false
+	}
+	img, err := png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return representation{}, false
+	}
+
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, dst, &jpeg.Options{Quality: 95}); err != nil {
+		return representation{}, false
+	}
+	return representation{mime: mimeJPEG, data: b.Bytes()}, true
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestImageMatte(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+
+	if got := clipboard.MattedImage(b.Bytes()); got != nil {
+		t.Fatalf("expect no matted variant by default")
+	}
+
+	clipboard.SetImageMatte(color.White)
+	defer clipboard.SetImageMatte(nil)
+
+	got, err := jpeg.Decode(bytes.NewReader(clipboard.MattedImage(b.Bytes())))
+	if err != nil {
+		t.Fatalf("matted variant is not JPEG encoded: %v", err)
+	}
+	if got.Bounds() != img.Bounds() {
+		t.Fatalf("matted image has different bounds, got: %v, want: %v", got.Bounds(), img.Bounds())
+	}
+	// Transparent pixels become the background color, allowing a
+	// small error of the lossy encoding.
+	r, g, bl, _ := got.At(15, 15).RGBA()
+	if r>>8 < 250 || g>>8 < 250 || bl>>8 < 250 {
+		t.Fatalf("transparent pixel is not matted, got: %v", got.At(15, 15))
+	}
+}
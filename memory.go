@@ -0,0 +1,247 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"strings"
+	"sync"
+)
+
+// memory is the in-memory clipboard of BackendMemory. It is the reference
+// implementation of the semantics that the platform clipboards share:
+//
+//   - The clipboard holds a single item, which offers the data in one or
+//     more representations, such as HTML along with plain text. A write
+//     replaces the whole item.
+//   - The writer owns the clipboard until another write replaces the
+//     item, which signals the channel that the write returned, and the
+//     lost channel of the write.
+//   - Every change of the item increments the sequence number, which
+//     drives the change detection of Watch.
+//   - Data that is written once is removed from the clipboard after the
+//     first read of the data, and the origin metadata does not count as
+//     a read.
+type memory struct {
+	mu    sync.Mutex
+	items []representation
+	seq   uint64
+	// owner signals the writer of the current item that it is replaced.
+	owner chan struct{}
+	// lost signals the writer of the current item that another write
+	// took over the clipboard, see WriteOwned.
+	lost chan struct{}
+	// once indicates the item is removed after its first read.
+	once bool
+}
+
+// system returns the clipboard functions that are backed by m.
+func (m *memory) system() system {
+	return system{
+		read:      m.read,
+		readData:  m.readData,
+		has:       m.has,
+		prober:    func() func(Format) bool { return m.has },
+		imageInfo: func() (int, int, string, error) { return readImageInfo(m.readData) },
+		write: func(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+			reps, err := memoryItem(t, buf)
+			if err != nil {
+				return nil, err
+			}
+			return m.put(append(reps, extra...), false), nil
+		},
+		writeOnce: func(t Format, buf []byte, extra []representation) (<-chan struct{}, error) {
+			reps, err := memoryItem(t, buf)
+			if err != nil {
+				return nil, err
+			}
+			return m.put(append(reps, extra...), true), nil
+		},
+		writeData: func(mime string, buf []byte, extra []representation) (<-chan struct{}, error) {
+			return m.put(append([]representation{{mime: mime, data: buf}}, extra...), false), nil
+		},
+		sequence: func() (uint64, bool) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return m.seq, true
+		},
+		lost: func() <-chan struct{} {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return m.lost
+		},
+		// There is no handle of the in-memory clipboard.
+		rawCall:  func(fn func(uintptr) error) error { return ErrUnsupported },
+		announce: func(msg string) error { return nil },
+		readSelection: func(s Selection, t Format) ([]byte, error) {
+			if s != SelectionClipboard {
+				return nil, ErrUnsupported
+			}
+			return m.read(t)
+		},
+		virtualFiles: func(dir string) ([]string, error) {
+			return nil, ErrUnavailable
+		},
+		locked: func() bool { return false },
+		info: func() Info {
+			return Info{Backend: BackendMemory, Protocol: "memory", SequenceNumber: true}
+		},
+		writeOSAScript: func(kind OSAScriptKind, buf []byte) (<-chan struct{}, error) {
+			return nil, ErrUnsupported
+		},
+	}
+}
+
+// memoryItem returns the representations that the in-memory clipboard
+// offers the data of format t as, which are the ones that the platform
+// clipboards offer as well.
+func memoryItem(t Format, buf []byte) ([]representation, error) {
+	mime := mimeOf(t)
+	if t == FmtImageRaw {
+		mime = sniffImage(buf)
+	}
+	if mime == "" {
+		return nil, ErrUnsupported
+	}
+	reps := []representation{{mime: mime, data: buf}}
+	switch t {
+	case FmtURL:
+		reps = append(reps, representation{mime: mimeText, data: buf})
+	case FmtColor:
+		if _, err := decodeColor(buf); err != nil {
+			return nil, err
+		}
+	}
+	return reps, nil
+}
+
+// put replaces the item of the clipboard with the given representations,
+// and returns the channel that signals the item is replaced.
+func (m *memory) put(reps []representation, once bool) <-chan struct{} {
+	items := make([]representation, 0, len(reps))
+	for _, r := range reps {
+		items = append(items, representation{mime: r.mime, data: append([]byte(nil), r.data...)})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.lost != nil {
+		m.lost <- struct{}{}
+		close(m.lost)
+	}
+	m.replace(items)
+	m.once = once
+	m.owner = make(chan struct{}, 1)
+	m.lost = make(chan struct{}, 1)
+	return m.owner
+}
+
+// replace replaces the item of the clipboard, and signals the owner of
+// the previous item. The ownership of the previous item ends without
+// being lost to another write. The caller must hold m.mu.
+func (m *memory) replace(items []representation) {
+	m.items = items
+	m.seq++
+	m.once = false
+	m.lost = nil
+	if m.owner != nil {
+		m.owner <- struct{}{}
+		close(m.owner)
+		m.owner = nil
+	}
+}
+
+// lookup returns the data of the given MIME type, and removes the item
+// if it is written once. It returns ErrUnavailable if the item does not
+// offer the MIME type.
+func (m *memory) lookup(mime string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.items {
+		if r.mime != mime {
+			continue
+		}
+		b := append([]byte(nil), r.data...)
+		if m.once && mime != mimeOrigin {
+			m.replace(nil)
+		}
+		return b, nil
+	}
+	return nil, ErrUnavailable
+}
+
+func (m *memory) offers(mime string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.items {
+		if r.mime == mime {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memory) read(t Format) ([]byte, error) {
+	switch t {
+	case FmtImageRaw:
+		m.mu.Lock()
+		mime := ""
+		for _, r := range m.items {
+			if strings.HasPrefix(r.mime, "image/") {
+				mime = r.mime
+				break
+			}
+		}
+		m.mu.Unlock()
+		if mime == "" {
+			return nil, ErrUnavailable
+		}
+		return m.lookup(mime)
+	case FmtURL:
+		if !m.offers(mimeURL) {
+			if b, err := m.lookup(mimeText); err == nil {
+				if u := textURL(b); u != nil {
+					return u, nil
+				}
+			}
+		}
+	}
+
+	mime := mimeOf(t)
+	if mime == "" {
+		return nil, ErrUnsupported
+	}
+	if b, err := m.lookup(mime); err == nil {
+		return b, nil
+	}
+	// Like the platform clipboards, other representations are converted
+	// using the registered converters.
+	return negotiate(mime, m.lookup)
+}
+
+func (m *memory) readData(mime string) ([]byte, error) { return m.lookup(mime) }
+
+func (m *memory) has(t Format) bool {
+	if t == FmtImageRaw {
+		t = FmtImage
+	}
+	mime := mimeOf(t)
+	if mime == "" {
+		return false
+	}
+	if m.offers(mime) {
+		return true
+	}
+	for _, from := range convertible(mime) {
+		if m.offers(from) {
+			return true
+		}
+	}
+	return false
+}
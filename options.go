@@ -0,0 +1,197 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// Option configures the clipboard in Init.
+type Option func(*config)
+
+// config is the configuration of the clipboard.
+type config struct {
+	display       string
+	xdisplay      unsafe.Pointer
+	backend       Backend
+	pollInterval  time.Duration
+	maxTextLength int
+	listener      uintptr
+	ocr           func(png []byte) (string, error)
+	readEnding    LineEnding
+	writeEnding   LineEnding
+	// ignoreOrientation is the negation of WithEXIFOrientation, so that
+	// the orientation is applied by default.
+	ignoreOrientation bool
+	sideChannel       bool
+	persist           bool
+	// readTimeout is negative if the timeout is detected by Init.
+	readTimeout time.Duration
+	// err is the error of an option, which fails Init.
+	err error
+}
+
+// Backend represents the system facility that implements the clipboard.
+type Backend int
+
+// All sorts of backends
+const (
+	// BackendAuto selects the backend of the current platform.
+	BackendAuto Backend = iota
+	// BackendX11 indicates the X11 selections, it is only supported
+	// on Linux.
+	BackendX11
+	// BackendWayland indicates the Wayland data device protocol,
+	// it is not supported yet.
+	BackendWayland
+	// BackendMemory indicates a clipboard in the memory of the process,
+	// which is supported on all platforms, including the ones without
+	// Cgo. It suits tests and headless environments, and serves as the
+	// reference implementation of the semantics of the clipboard, such
+	// as the ownership of writes and the change detection of Watch.
+	BackendMemory
+)
+
+// String returns the name of the backend.
+func (b Backend) String() string {
+	switch b {
+	case BackendAuto:
+		return "auto"
+	case BackendX11:
+		return "x11"
+	case BackendWayland:
+		return "wayland"
+	case BackendMemory:
+		return "memory"
+	}
+	return fmt.Sprintf("Backend(%d)", int(b))
+}
+
+// WithDisplay specifies the name of the display to connect, such as
+// ":1". By default, the DISPLAY environment variable is used. The
+// option only affects the X11 backend.
+func WithDisplay(name string) Option {
+	return func(c *config) { c.display = name }
+}
+
+// WithExistingDisplay specifies the Display* of an Xlib connection of
+// the host application to share, such as the one of GLFW or SDL, instead
+// of connecting to the display. The option only affects the X11 backend.
+//
+// The display must be opened after Xlib is initialized for threads, which
+// is the case if Init is called before the display is opened, or the host
+// called XInitThreads. Reads lock the display using XLockDisplay while
+// they wait for the owner of the clipboard, hence the event loop of the
+// host is blocked until the read finishes. Writes still serve the data
+// using a separate connection, as the selection events cannot be shared
+// with the event loop of the host. The display must outlive the use of
+// the package.
+func WithExistingDisplay(d unsafe.Pointer) Option {
+	return func(c *config) { c.xdisplay = d }
+}
+
+// WithBackend specifies the backend to use. By default, the backend of
+// the current platform is selected. Init returns an error if the given
+// backend is not supported on the current platform.
+func WithBackend(b Backend) Option {
+	return func(c *config) { c.backend = b }
+}
+
+// WithReadTimeout specifies the maximum time that a read waits for the
+// owner of the clipboard to deliver the data, which overrides the
+// ReadTimeout of the display tuning that is detected by Init. Slow
+// applications, such as Java IDEs, may need a longer timeout. A zero
+// duration waits forever. A read that exceeds the timeout fails with
+// ErrTimeout. The option only affects the X11 backend.
+func WithReadTimeout(d time.Duration) Option {
+	return func(c *config) {
+		if d >= 0 {
+			c.readTimeout = d
+		}
+	}
+}
+
+// WithListenerWindow registers the given window handle (HWND) of the
+// host application as a clipboard format listener, for applications that
+// already run a Win32 message loop, such as the ones using SDL, GLFW or
+// Ebiten. The window procedure of the host must call NotifyUpdate when it
+// receives WM_CLIPBOARDUPDATE, and the change detection of Watch then
+// relies on the forwarded notifications instead of polling. The option
+// only affects Windows.
+func WithListenerWindow(hwnd uintptr) Option {
+	return func(c *config) { c.listener = hwnd }
+}
+
+// WithMaxTextLength specifies the maximum number of characters that a
+// text read scans for the terminator of the text, which is bounded by
+// the size of the clipboard data by default. A text read fails with
+// ErrCorrupt if the terminator is missing within the bound. The option
+// only affects Windows, where text is NUL terminated.
+func WithMaxTextLength(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxTextLength = n
+		}
+	}
+}
+
+// WithPersistence asks the clipboard manager of the desktop to save the
+// data of every write, so that the data survives the exit of the process,
+// using the SAVE_TARGETS protocol of the CLIPBOARD_MANAGER selection. On
+// X11, the data is otherwise served by the writing process, and vanishes
+// once it exits. Data written by WriteSensitive or WriteOnce is never
+// saved. The option has no effect if no clipboard manager is running.
+// Some clipboard managers take over the clipboard immediately after they
+// saved the data, which signals the change channel of the write. The
+// option only affects the X11 backend, as the clipboards of the other
+// platforms keep the data already.
+func WithPersistence() Option {
+	return func(c *config) { c.persist = true }
+}
+
+// WithPollInterval specifies the interval of the change detection of
+// Watch and WatchEvents, and of the change channels that writes return on
+// macOS and Windows. The default interval is one second. A shorter
+// interval suits interactive tools, and a longer one saves battery. See
+// WatchInterval to configure the interval of a single watcher.
+//
+// On macOS, the change count of the pasteboard is checked at the
+// interval, but no more often than every 50ms, by a timer of a dedicated
+// dispatch queue, which the system may coalesce with other timers. The
+// watchers are notified as soon as the timer detects a change.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.pollInterval = d
+		}
+	}
+}
+
+// WithReadLineEnding specifies the line endings that reads of FmtText
+// convert the text to, for instance, LineEndingLF to read the same text
+// on all platforms. By default, the text is read as is.
+func WithReadLineEnding(e LineEnding) Option {
+	return func(c *config) { c.readEnding = e }
+}
+
+// WithWriteLineEnding specifies the line endings that writes of FmtText
+// convert the text to, for instance, LineEndingCRLF for applications on
+// Windows that expect CRLF. By default, the text is written as is.
+func WithWriteLineEnding(e LineEnding) Option {
+	return func(c *config) { c.writeEnding = e }
+}
+
+// WithOCR specifies the optical character recognition engine that
+// ReadTextOrOCR uses to recognize the text of a copied image, such as a
+// screenshot. The engine receives the PNG encoded image and returns the
+// recognized text, for instance, using a binding of Tesseract. The
+// package does not ship an engine.
+func WithOCR(fn func(png []byte) (string, error)) Option {
+	return func(c *config) { c.ocr = fn }
+}
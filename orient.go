@@ -0,0 +1,153 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// ignoreOrientation indicates JPEG images are converted without applying
+// their EXIF orientation, see WithEXIFOrientation.
+var ignoreOrientation bool
+
+// WithEXIFOrientation specifies whether the conversion of JPEG images to
+// PNG applies the EXIF orientation of the images, which is the default.
+// Photos that are copied from cameras and phones are often stored
+// sideways along with the orientation to display them, which PNG cannot
+// carry, hence the pasted images would be rotated otherwise.
+func WithEXIFOrientation(apply bool) Option {
+	return func(c *config) { c.ignoreOrientation = !apply }
+}
+
+// jpegToPNG converts a JPEG image to PNG, and applies the EXIF orientation
+// of the image unless the orientation is ignored.
+func jpegToPNG(src []byte) ([]byte, error) {
+	img, err := jpeg.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	if !ignoreOrientation {
+		img = orient(img, exifOrientation(src))
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exifOrientation returns the EXIF orientation of the given JPEG image,
+// which ranges from 1 to 8, or 1 if the image carries no orientation.
+func exifOrientation(jpg []byte) int {
+	if len(jpg) < 4 || jpg[0] != 0xff || jpg[1] != 0xd8 {
+		return 1
+	}
+	for p := 2; p+4 <= len(jpg); {
+		if jpg[p] != 0xff {
+			return 1
+		}
+		marker := jpg[p+1]
+		if marker == 0xd9 || marker == 0xda {
+			// The metadata precedes the scan of the image.
+			return 1
+		}
+		n := int(binary.BigEndian.Uint16(jpg[p+2:]))
+		if n < 2 || p+2+n > len(jpg) {
+			return 1
+		}
+		seg := jpg[p+4 : p+2+n]
+		if marker == 0xe1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		p += 2 + n
+	}
+	return 1
+}
+
+// tiffOrientation returns the orientation tag of the first image file
+// directory of the given TIFF structure of EXIF data.
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(b[4:]))
+	if ifd < 8 || ifd+2 > len(b) {
+		return 1
+	}
+	n := int(order.Uint16(b[ifd:]))
+	for i := 0; i < n; i++ {
+		e := ifd + 2 + 12*i
+		if e+12 > len(b) {
+			return 1
+		}
+		// The orientation is a single SHORT stored in the value field.
+		if order.Uint16(b[e:]) == 0x0112 && order.Uint16(b[e+2:]) == 3 {
+			if o := int(order.Uint16(b[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient transforms the given image stored in the given EXIF orientation
+// to the upright image.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// The orientations from 5 to 8 transpose the image.
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flip horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotate by 180 degrees
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip vertically
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate counterclockwise
+				dx, dy = y, w-1-x
+			}
+			s, d := src.PixOffset(x, y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+		}
+	}
+	return dst
+}
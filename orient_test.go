@@ -0,0 +1,85 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+// exifJPEG returns a JPEG image of 32x16 pixels, whose left half is red
+// and right half is blue, that carries the given EXIF orientation.
+func exifJPEG(t *testing.T, orientation byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if x >= 16 {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("failed to encode jpeg: %v", err)
+	}
+
+	// A big endian TIFF structure with an orientation entry in IFD0.
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08,
+		0x00, 0x01,
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xff, 0xe1, byte((len(seg) + 2) >> 8), byte(len(seg) + 2)}, seg...)
+
+	data := b.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func TestEXIFOrientation(t *testing.T) {
+	red := func(c color.Color) bool {
+		r, _, b, _ := c.RGBA()
+		return r > 0xc000 && b < 0x4000
+	}
+
+	tests := []struct {
+		orientation   byte
+		width, height int
+		// redAt is a pixel of the upright image that must be red.
+		redAt image.Point
+	}{
+		{1, 32, 16, image.Pt(4, 8)},
+		{3, 32, 16, image.Pt(28, 8)},
+		{6, 16, 32, image.Pt(8, 4)},
+		{8, 16, 32, image.Pt(8, 28)},
+	}
+	for _, tt := range tests {
+		out, err := clipboard.Convert("image/jpeg", "image/png", exifJPEG(t, tt.orientation))
+		if err != nil {
+			t.Fatalf("failed to convert jpeg of orientation %d: %v", tt.orientation, err)
+		}
+		img, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("failed to decode converted png: %v", err)
+		}
+		if got := img.Bounds().Size(); got != image.Pt(tt.width, tt.height) {
+			t.Fatalf("orientation %d size mismatch, got: %v, want: %dx%d", tt.orientation, got, tt.width, tt.height)
+		}
+		if !red(img.At(tt.redAt.X, tt.redAt.Y)) {
+			t.Fatalf("orientation %d is not applied, pixel %v is %v", tt.orientation, tt.redAt, img.At(tt.redAt.X, tt.redAt.Y))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// mimeOrigin is the MIME type of the origin metadata that is written
+// along with the clipboard data.
+const mimeOrigin = "application/x-golang-design-clipboard-origin"
+
+// Origin describes the process that wrote the clipboard data. The
+// origin is written by this package along with the data of every
+// write, so that other processes using this package can display the
+// provenance of the data, or ignore the data they wrote themselves.
+type Origin struct {
+	// App is the name of the application that wrote the data, which
+	// is the name of the executable unless changed by SetOriginApp.
+	App string `json:"app"`
+	// PID is the process id of the application.
+	PID int `json:"pid"`
+	// Time is the time when the data was written.
+	Time time.Time `json:"time"`
+	// Sensitive indicates the data was written by WriteSensitive,
+	// hence clipboard managers should not record it.
+	Sensitive bool `json:"sensitive"`
+}
+
+var (
+	originMu  sync.Mutex
+	originApp string
+)
+
+func init() {
+	if len(os.Args) > 0 {
+		originApp = filepath.Base(os.Args[0])
+	}
+}
+
+// SetOriginApp sets the application name that is written as the origin
+// of the clipboard data. By default, it is the name of the executable.
+func SetOriginApp(name string) {
+	originMu.Lock()
+	defer originMu.Unlock()
+
+	originApp = name
+}
+
+// ReadOrigin returns the origin of the current clipboard data. It
+// returns an error if the data was not written by this package, or
+// the platform does not support reading the origin, which is the case
+// on iOS and Android at the moment.
+func ReadOrigin() (Origin, error) {
+	var o Origin
+	if err := ready(); err != nil {
+		return o, err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	buf, err := sys.readData(mimeOrigin)
+	if err != nil {
+		return o, err
+	}
+	if len(buf) == 0 {
+		return o, ErrUnavailable
+	}
+	// Use a decoder as some platforms pad the data.
+	err = json.NewDecoder(bytes.NewReader(buf)).Decode(&o)
+	return o, err
+}
+
+// WriteSensitive is like WriteErr but marks the data as sensitive in
+// its origin, for instance, passwords, so that cooperating clipboard
+// managers can choose to not record it.
+func WriteSensitive(t Format, buf []byte) (<-chan struct{}, error) {
+	w, err := writeAll(t, buf, modeSensitive)
+	return w.changed, err
+}
+
+// WriteOnce is like WriteSensitive but the data is served to a single
+// paste: once an application has received the data, the clipboard is
+// cleared. This suits password managers that do not want to leave
+// secrets on the clipboard. The returned channel receives a signal once
+// the data has been pasted, or the clipboard has been overwritten.
+//
+// Any read of the data counts as the paste, including reads of this
+// package, Watch, and clipboard managers that record the clipboard. Reads
+// of the origin metadata, which cooperating clipboard managers use to
+// skip sensitive data, do not count.
+//
+// WriteOnce is only supported on Linux at the moment, as other platforms
+// take over the data from the writer rather than letting it serve pastes,
+// and it returns ErrUnsupported elsewhere.
+func WriteOnce(t Format, buf []byte) (<-chan struct{}, error) {
+	w, err := writeAll(t, buf, modeOnce)
+	return w.changed, err
+}
+
+// origin returns the origin metadata of a write, which carries the
+// reference to the side channel of the write if ref is not empty.
+func origin(sensitive bool, ref sideRef) representation {
+	originMu.Lock()
+	app := originApp
+	originMu.Unlock()
+
+	ref.Origin = Origin{
+		App:       app,
+		PID:       os.Getpid(),
+		Time:      time.Now(),
+		Sensitive: sensitive,
+	}
+	b, _ := json.Marshal(ref)
+	return representation{mime: mimeOrigin, data: b}
+}
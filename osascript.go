@@ -0,0 +1,66 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"fmt"
+	"os"
+)
+
+// OSAScriptKind represents the kind of data that is written by
+// WriteOSAScript.
+type OSAScriptKind int
+
+// All kinds of data that AppleScript can set to the clipboard.
+const (
+	// OSAScriptText indicates UTF-8 encoded plain text, as
+	// `set the clipboard to "text"` does.
+	OSAScriptText OSAScriptKind = iota
+	// OSAScriptStyledText indicates RTF encoded styled text, as
+	// `set the clipboard to (read file as «class RTF »)` does.
+	OSAScriptStyledText
+	// OSAScriptFiles indicates newline separated absolute file
+	// paths, as `set the clipboard to POSIX file "/path"` does.
+	OSAScriptFiles
+)
+
+// WriteOSAScript writes the given buffer to the clipboard exactly the
+// way AppleScript's `set the clipboard to` does, so that automations
+// mixing osascript and Go programs observe consistent results. Like
+// Write, the returned channel receives a signal if the clipboard has
+// been overwritten from this write.
+//
+// WriteOSAScript is only supported on macOS, and it fails on other
+// platforms. Failed writes are treated the same as Write does.
+func WriteOSAScript(kind OSAScriptKind, buf []byte) <-chan struct{} {
+	changed, err := WriteOSAScriptErr(kind, buf)
+	if err != nil {
+		if debug {
+			fmt.Fprintf(os.Stderr, "write to clipboard err: %v\n", err)
+		}
+		return failedWrite()
+	}
+	return changed
+}
+
+// WriteOSAScriptErr is like WriteOSAScript but returns an error if the
+// write fails.
+func WriteOSAScriptErr(kind OSAScriptKind, buf []byte) (<-chan struct{}, error) {
+	if err := ready(); err != nil {
+		return nil, err
+	}
+	release, err := arbitrate()
+	if err != nil {
+		return nil, err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	changed, err := sys.writeOSAScript(kind, buf)
+	release()
+	return changed, err
+}
@@ -0,0 +1,13 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build !darwin || ios || !cgo
+
+package clipboard
+
+func writeOSAScript(kind OSAScriptKind, buf []byte) (<-chan struct{}, error) {
+	return nil, ErrUnsupported
+}
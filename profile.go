@@ -0,0 +1,75 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	profileMu sync.Mutex
+	profiles  = map[string][]Option{}
+)
+
+// RegisterProfile registers the given options as a named profile, such
+// as "remote-server", which WithProfile selects. Applications define the
+// option bundles once, and select them by name, for instance, from a
+// command line flag or a configuration file:
+//
+//	clipboard.RegisterProfile("remote-server",
+//		clipboard.WithDisplay(":1"),
+//		clipboard.WithReadTimeout(5*time.Second))
+//	err := clipboard.Init(clipboard.WithProfile(*profile))
+//
+// Registering a profile with a name that already has a profile replaces
+// the existing one. Registering a name without options unregisters the
+// profile.
+func RegisterProfile(name string, opts ...Option) {
+	profileMu.Lock()
+	defer profileMu.Unlock()
+
+	if len(opts) == 0 {
+		delete(profiles, name)
+		return
+	}
+	profiles[name] = append([]Option(nil), opts...)
+}
+
+// Profiles returns the names of the registered profiles in sorted order.
+func Profiles() []string {
+	profileMu.Lock()
+	defer profileMu.Unlock()
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile applies the options of the named profile that is
+// registered by RegisterProfile. Options that follow WithProfile in Init
+// override the ones of the profile. Init returns an error if no profile
+// is registered with the name.
+func WithProfile(name string) Option {
+	return func(c *config) {
+		profileMu.Lock()
+		opts, ok := profiles[name]
+		profileMu.Unlock()
+
+		if !ok {
+			c.err = fmt.Errorf("unknown profile: %s", name)
+			return
+		}
+		for _, opt := range opts {
+			opt(c)
+		}
+	}
+}
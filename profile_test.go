@@ -0,0 +1,39 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestProfile(t *testing.T) {
+	clipboard.RegisterProfile("remote-server", clipboard.WithDisplay(":1"))
+	clipboard.RegisterProfile("local", clipboard.WithDisplay(":0"))
+	defer clipboard.RegisterProfile("remote-server")
+	defer clipboard.RegisterProfile("local")
+
+	if got, want := clipboard.Profiles(), []string{"local", "remote-server"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("profiles mismatch, got: %v, want: %v", got, want)
+	}
+
+	d, err := clipboard.ProfileDisplay(clipboard.WithProfile("remote-server"))
+	if err != nil || d != ":1" {
+		t.Fatalf("profile is not applied, got: %q, %v", d, err)
+	}
+	d, _ = clipboard.ProfileDisplay(clipboard.WithProfile("remote-server"), clipboard.WithDisplay(":2"))
+	if d != ":2" {
+		t.Fatalf("options after the profile should override it, got: %q", d)
+	}
+
+	clipboard.RegisterProfile("local")
+	if _, err := clipboard.ProfileDisplay(clipboard.WithProfile("local")); err == nil {
+		t.Fatalf("expect an error for an unregistered profile")
+	}
+}
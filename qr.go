@@ -0,0 +1,42 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build qr
+
+package clipboard
+
+// QR codes are not supported by the standard library. When the package
+// is built with the qr build tag, WriteQR and ReadQR encode and decode QR
+// codes using the platform facilities (CoreImage on macOS, libqrencode
+// and libzbar on Linux):
+//
+//	go build -tags qr
+
+// WriteQR writes a PNG encoded QR code image of the given text to the
+// clipboard, for instance, to share a link with a phone. Like WriteErr,
+// the returned channel receives a signal if the clipboard has been
+// overwritten from this write.
+func WriteQR(text string) (<-chan struct{}, error) {
+	buf, err := qrEncode(text)
+	if err != nil {
+		return nil, err
+	}
+	return WriteErr(FmtImage, buf)
+}
+
+// ReadQR returns the text of the QR code of the image of the clipboard,
+// such as a copied screenshot. It returns ErrUnavailable if the clipboard
+// holds no image, and an error if the image has no QR code.
+func ReadQR() (string, error) {
+	buf, err := ReadErr(FmtImage)
+	if err != nil {
+		return "", err
+	}
+	if len(buf) == 0 {
+		return "", ErrUnavailable
+	}
+	return qrDecode(buf)
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build darwin && !ios && qr && cgo
+
+package clipboard
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation -framework Cocoa -framework CoreImage
+#import <Foundation/Foundation.h>
+
+unsigned int clipboard_qr_encode(const char *text, void **out);
+unsigned int clipboard_qr_decode(const void *bytes, NSInteger n, void **out);
+*/
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// qrEncode encodes the given text as a QR code image using CoreImage.
+func qrEncode(text string) ([]byte, error) {
+	cs := C.CString(text)
+	defer C.free(unsafe.Pointer(cs))
+
+	var data unsafe.Pointer
+	n := C.clipboard_qr_encode(cs, &data)
+	if data == nil {
+		return nil, errors.New("failed to encode QR code")
+	}
+	defer C.free(data)
+	return C.GoBytes(data, C.int(n)), nil
+}
+
+// qrDecode decodes the QR code of the given image using CoreImage.
+func qrDecode(buf []byte) (string, error) {
+	if len(buf) == 0 {
+		return "", errors.New("empty image data")
+	}
+
+	var data unsafe.Pointer
+	n := C.clipboard_qr_decode(unsafe.Pointer(&buf[0]), C.NSInteger(len(buf)), &data)
+	if data == nil {
+		return "", errors.New("no QR code in the image")
+	}
+	defer C.free(data)
+	return C.GoStringN((*C.char)(data), C.int(n)), nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build darwin && !ios && qr
+
+#import <Foundation/Foundation.h>
+#import <Cocoa/Cocoa.h>
+#import <CoreImage/CoreImage.h>
+
+// clipboard_qr_encode encodes the given text as a QR code with the medium
+// error correction level, and returns the PNG encoded image whose modules
+// are 8 pixels wide.
+unsigned int clipboard_qr_encode(const char *text, void **out) {
+	NSData *msg = [[NSString stringWithUTF8String:text] dataUsingEncoding:NSUTF8StringEncoding];
+	CIFilter *filter = [CIFilter filterWithName:@"CIQRCodeGenerator"];
+	[filter setValue:msg forKey:@"inputMessage"];
+	[filter setValue:@"M" forKey:@"inputCorrectionLevel"];
+	CIImage *img = [filter.outputImage imageByApplyingTransform:CGAffineTransformMakeScale(8, 8)];
+	if (img == nil) {
+		return 0;
+	}
+
+	CIContext *ctx = [CIContext contextWithOptions:nil];
+	CGImageRef cg = [ctx createCGImage:img fromRect:img.extent];
+	if (cg == NULL) {
+		return 0;
+	}
+	NSBitmapImageRep *rep = [[NSBitmapImageRep alloc] initWithCGImage:cg];
+	CGImageRelease(cg);
+	NSData *png = [rep representationUsingType: NSBitmapImageFileTypePNG
+		properties: @{}];
+	if (png == nil) {
+		return 0;
+	}
+	NSUInteger siz = [png length];
+	*out = malloc(siz);
+	[png getBytes: *out length: siz];
+	return siz;
+}
+
+// clipboard_qr_decode decodes the first QR code of the given image, and
+// returns the UTF-8 encoded message of the QR code.
+unsigned int clipboard_qr_decode(const void *bytes, NSInteger n, void **out) {
+	NSData *data = [NSData dataWithBytes: bytes length: n];
+	CIImage *img = [CIImage imageWithData:data];
+	if (img == nil) {
+		return 0;
+	}
+	CIDetector *detector = [CIDetector detectorOfType:CIDetectorTypeQRCode
+		context:nil options:@{CIDetectorAccuracy: CIDetectorAccuracyHigh}];
+	for (CIFeature *f in [detector featuresInImage:img]) {
+		if (![f isKindOfClass:[CIQRCodeFeature class]]) {
+			continue;
+		}
+		NSString *msg = ((CIQRCodeFeature *)f).messageString;
+		if (msg == nil) {
+			continue;
+		}
+		const char *s = [msg UTF8String];
+		NSUInteger siz = strlen(s);
+		*out = malloc(siz + 1);
+		memcpy(*out, s, siz + 1);
+		return siz;
+	}
+	return 0;
+}
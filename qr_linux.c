@@ -0,0 +1,154 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build linux && !android && qr
+
+#include <stdlib.h>
+#include <string.h>
+#include <dlfcn.h>
+
+// Declarations of the used libqrencode API, see:
+// https://github.com/fukuchi/libqrencode/blob/master/qrencode.h
+typedef struct {
+	int version;
+	int width;
+	unsigned char *data;
+} QRcode;
+
+enum {
+	QR_ECLEVEL_M = 1,
+	QR_MODE_8    = 2,
+};
+
+// Declarations of the used libzbar API, see:
+// https://github.com/mchehab/zbar/blob/master/include/zbar.h
+typedef struct zbar_image_scanner_s zbar_image_scanner_t;
+typedef struct zbar_image_s zbar_image_t;
+typedef struct zbar_symbol_s zbar_symbol_t;
+
+enum {
+	ZBAR_QRCODE = 64,
+};
+
+void *libqrencode;
+void *libzbar;
+
+QRcode *(*P_QRcode_encodeString)(const char*, int, int, int, int);
+void (*P_QRcode_free)(QRcode*);
+
+zbar_image_scanner_t *(*P_zbar_image_scanner_create)(void);
+void (*P_zbar_image_scanner_destroy)(zbar_image_scanner_t*);
+zbar_image_t *(*P_zbar_image_create)(void);
+void (*P_zbar_image_destroy)(zbar_image_t*);
+void (*P_zbar_image_set_format)(zbar_image_t*, unsigned long);
+void (*P_zbar_image_set_size)(zbar_image_t*, unsigned, unsigned);
+void (*P_zbar_image_set_data)(zbar_image_t*, const void*, unsigned long, void*);
+int (*P_zbar_scan_image)(zbar_image_scanner_t*, zbar_image_t*);
+const zbar_symbol_t *(*P_zbar_image_first_symbol)(const zbar_image_t*);
+const zbar_symbol_t *(*P_zbar_symbol_next)(const zbar_symbol_t*);
+int (*P_zbar_symbol_get_type)(const zbar_symbol_t*);
+const char *(*P_zbar_symbol_get_data)(const zbar_symbol_t*);
+unsigned int (*P_zbar_symbol_get_data_length)(const zbar_symbol_t*);
+
+int initQRencode() {
+	if (libqrencode) {
+		return 1;
+	}
+	libqrencode = dlopen("libqrencode.so.4", RTLD_LAZY);
+	if (!libqrencode) {
+		libqrencode = dlopen("libqrencode.so", RTLD_LAZY);
+	}
+	if (!libqrencode) {
+		return 0;
+	}
+	P_QRcode_encodeString = (QRcode *(*)(const char*, int, int, int, int)) dlsym(libqrencode, "QRcode_encodeString");
+	P_QRcode_free = (void (*)(QRcode*)) dlsym(libqrencode, "QRcode_free");
+	return 1;
+}
+
+int initZbar() {
+	if (libzbar) {
+		return 1;
+	}
+	libzbar = dlopen("libzbar.so.0", RTLD_LAZY);
+	if (!libzbar) {
+		libzbar = dlopen("libzbar.so", RTLD_LAZY);
+	}
+	if (!libzbar) {
+		return 0;
+	}
+	P_zbar_image_scanner_create = (zbar_image_scanner_t *(*)(void)) dlsym(libzbar, "zbar_image_scanner_create");
+	P_zbar_image_scanner_destroy = (void (*)(zbar_image_scanner_t*)) dlsym(libzbar, "zbar_image_scanner_destroy");
+	P_zbar_image_create = (zbar_image_t *(*)(void)) dlsym(libzbar, "zbar_image_create");
+	P_zbar_image_destroy = (void (*)(zbar_image_t*)) dlsym(libzbar, "zbar_image_destroy");
+	P_zbar_image_set_format = (void (*)(zbar_image_t*, unsigned long)) dlsym(libzbar, "zbar_image_set_format");
+	P_zbar_image_set_size = (void (*)(zbar_image_t*, unsigned, unsigned)) dlsym(libzbar, "zbar_image_set_size");
+	P_zbar_image_set_data = (void (*)(zbar_image_t*, const void*, unsigned long, void*)) dlsym(libzbar, "zbar_image_set_data");
+	P_zbar_scan_image = (int (*)(zbar_image_scanner_t*, zbar_image_t*)) dlsym(libzbar, "zbar_scan_image");
+	P_zbar_image_first_symbol = (const zbar_symbol_t *(*)(const zbar_image_t*)) dlsym(libzbar, "zbar_image_first_symbol");
+	P_zbar_symbol_next = (const zbar_symbol_t *(*)(const zbar_symbol_t*)) dlsym(libzbar, "zbar_symbol_next");
+	P_zbar_symbol_get_type = (int (*)(const zbar_symbol_t*)) dlsym(libzbar, "zbar_symbol_get_type");
+	P_zbar_symbol_get_data = (const char *(*)(const zbar_symbol_t*)) dlsym(libzbar, "zbar_symbol_get_data");
+	P_zbar_symbol_get_data_length = (unsigned int (*)(const zbar_symbol_t*)) dlsym(libzbar, "zbar_symbol_get_data_length");
+	return 1;
+}
+
+// qr_encode encodes the given text as a QR code with the medium error
+// correction level. The modules are written into out row by row, where
+// the least significant bit of a module indicates it is dark, and the
+// caller is responsible for the free of the out buffer.
+int qr_encode(const char *text, unsigned char **out, int *width) {
+	if (!initQRencode()) {
+		return -1;
+	}
+
+	QRcode *qr = (*P_QRcode_encodeString)(text, 0, QR_ECLEVEL_M, QR_MODE_8, 1);
+	if (qr == NULL) {
+		return -2;
+	}
+	size_t n = (size_t)qr->width * qr->width;
+	*out = (unsigned char *)malloc(n);
+	memcpy(*out, qr->data, n);
+	*width = qr->width;
+	(*P_QRcode_free)(qr);
+	return 0;
+}
+
+// qr_decode decodes the first QR code of the given 8-bit grayscale image
+// of size w x h. The data of the QR code is written into out and its size
+// is returned, and the caller is responsible for the free of the out
+// buffer. It returns -1 if libzbar is not available, and -2 if there is
+// no QR code in the image.
+int qr_decode(const void *gray, int w, int h, char **out) {
+	if (!initZbar()) {
+		return -1;
+	}
+
+	zbar_image_scanner_t *scanner = (*P_zbar_image_scanner_create)();
+	zbar_image_t *img = (*P_zbar_image_create)();
+	// The fourcc of 8-bit grayscale images.
+	(*P_zbar_image_set_format)(img, 'Y' | '8' << 8 | '0' << 16 | '0' << 24);
+	(*P_zbar_image_set_size)(img, w, h);
+	(*P_zbar_image_set_data)(img, gray, (unsigned long)w * h, NULL);
+
+	int ret = -2;
+	if ((*P_zbar_scan_image)(scanner, img) > 0) {
+		const zbar_symbol_t *sym = (*P_zbar_image_first_symbol)(img);
+		for (; sym != NULL; sym = (*P_zbar_symbol_next)(sym)) {
+			if ((*P_zbar_symbol_get_type)(sym) != ZBAR_QRCODE) {
+				continue;
+			}
+			unsigned int n = (*P_zbar_symbol_get_data_length)(sym);
+			*out = (char *)malloc(n + 1);
+			memcpy(*out, (*P_zbar_symbol_get_data)(sym), n);
+			ret = (int)n;
+			break;
+		}
+	}
+	(*P_zbar_image_destroy)(img);
+	(*P_zbar_image_scanner_destroy)(scanner);
+	return ret;
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build linux && !android && qr && cgo
+
+package clipboard
+
+/*
+#cgo LDFLAGS: -ldl
+#include <stdlib.h>
+
+int qr_encode(const char *text, unsigned char **out, int *width);
+int qr_decode(const void *gray, int w, int h, char **out);
+*/
+import "C"
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"unsafe"
+)
+
+const (
+	// qrModuleSize is the number of pixels of a module of encoded QR
+	// codes, which keeps them readable by cameras.
+	qrModuleSize = 8
+	// qrQuietZone is the number of modules of the margin around encoded
+	// QR codes, as required by the QR code specification.
+	qrQuietZone = 4
+)
+
+// qrEncode encodes the given text as a QR code image using libqrencode,
+// which is loaded at runtime. Install libqrencode4 to enable encoding.
+func qrEncode(text string) ([]byte, error) {
+	cs := C.CString(text)
+	defer C.free(unsafe.Pointer(cs))
+
+	var (
+		data  *C.uchar
+		width C.int
+	)
+	if C.qr_encode(cs, &data, &width) != 0 {
+		return nil, errors.New("failed to encode QR code, is libqrencode installed?")
+	}
+	defer C.free(unsafe.Pointer(data))
+
+	w := int(width)
+	modules := C.GoBytes(unsafe.Pointer(data), C.int(w*w))
+	size := (w + 2*qrQuietZone) * qrModuleSize
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for y := 0; y < w; y++ {
+		for x := 0; x < w; x++ {
+			// The least significant bit indicates a dark module.
+			if modules[y*w+x]&1 == 0 {
+				continue
+			}
+			r := image.Rect(x, y, x+1, y+1).Add(image.Pt(qrQuietZone, qrQuietZone))
+			r.Min, r.Max = r.Min.Mul(qrModuleSize), r.Max.Mul(qrModuleSize)
+			draw.Draw(img, r, image.Black, image.Point{}, draw.Src)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// qrDecode decodes the QR code of the given PNG image using libzbar,
+// which is loaded at runtime. Install libzbar0 to enable decoding.
+func qrDecode(buf []byte) (string, error) {
+	m, err := png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return "", err
+	}
+	b := m.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			gray.Set(x, y, color.GrayModel.Convert(m.At(b.Min.X+x, b.Min.Y+y)))
+		}
+	}
+	if len(gray.Pix) == 0 {
+		return "", errors.New("empty image")
+	}
+
+	var out *C.char
+	n := C.qr_decode(unsafe.Pointer(&gray.Pix[0]), C.int(b.Dx()), C.int(b.Dy()), &out)
+	switch {
+	case n == -1:
+		return "", errors.New("failed to decode QR code, is libzbar installed?")
+	case n < 0:
+		return "", errors.New("no QR code in the image")
+	}
+	defer C.free(unsafe.Pointer(out))
+	return C.GoStringN(out, n), nil
+}
@@ -0,0 +1,13 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build qr && !(((darwin && !ios) || (linux && !android)) && cgo)
+
+package clipboard
+
+func qrEncode(text string) ([]byte, error) { return nil, ErrUnsupported }
+
+func qrDecode(buf []byte) (string, error) { return "", ErrUnsupported }
@@ -0,0 +1,38 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+//go:build qr
+
+package clipboard_test
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestClipboardQR(t *testing.T) {
+	if val, ok := os.LookupEnv("CGO_ENABLED"); ok && val == "0" {
+		t.Skip("CGO_ENABLED is set to 0")
+	}
+	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
+		t.Skip("QR codes are only supported on macOS and Linux")
+	}
+
+	want := "https://golang.design/x/clipboard"
+	if _, err := clipboard.WriteQR(want); err != nil {
+		t.Fatalf("failed to write QR code: %v", err)
+	}
+	got, err := clipboard.ReadQR()
+	if err != nil {
+		t.Fatalf("failed to read QR code: %v", err)
+	}
+	if got != want {
+		t.Fatalf("read QR code mismatch, got: %s, want: %s", got, want)
+	}
+}
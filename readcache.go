@@ -0,0 +1,115 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var readCaching int32
+
+// SetReadCache sets whether reads return the data that was previously
+// read in the same format while the sequence number of the clipboard is
+// unchanged, see Sequence, instead of transferring the data from the
+// clipboard again. This suits applications that read the clipboard
+// repeatedly, for instance, whenever their window receives the focus.
+// The cache is disabled by default.
+//
+// The cache is invalidated whenever the sequence number changes, which
+// includes the writes of this process, and the change detection of
+// Watch drops the stale data. Reads are not cached on platforms without
+// a sequence number.
+func SetReadCache(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&readCaching, 1)
+	} else {
+		atomic.StoreInt32(&readCaching, 0)
+		cache.invalidate(0, false)
+	}
+}
+
+// readCache holds the data of the formats that are read at a sequence
+// number of the clipboard.
+type readCache struct {
+	mu   sync.Mutex
+	seq  uint64
+	data map[Format][]byte
+}
+
+var cache readCache
+
+// get returns a copy of the cached data of format t at the given
+// sequence number.
+func (c *readCache) get(seq uint64, t Format) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.data == nil || c.seq != seq {
+		return nil, false
+	}
+	b, ok := c.data[t]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), b...), true
+}
+
+// put caches a copy of the data of format t that is read at the given
+// sequence number, which must be taken before the read, so that data of
+// a later change is never cached for an earlier sequence number.
+func (c *readCache) put(seq uint64, t Format, buf []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.data == nil || c.seq != seq {
+		c.seq, c.data = seq, map[Format][]byte{}
+	}
+	c.data[t] = append([]byte(nil), buf...)
+}
+
+// invalidate drops the cached data unless it is of the given sequence
+// number.
+func (c *readCache) invalidate(seq uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !ok || c.seq != seq {
+		c.data = nil
+	}
+}
+
+// readCached reads the clipboard data in format t from the side channel
+// or the platform, or from the cache if it is enabled. The caller must
+// hold the lock.
+func readCached(t Format) ([]byte, error) {
+	if atomic.LoadInt32(&readCaching) == 0 {
+		return readRaw(t)
+	}
+	seq, ok := sys.sequence()
+	if !ok {
+		return readRaw(t)
+	}
+	if buf, hit := cache.get(seq, t); hit {
+		return buf, nil
+	}
+	buf, err := readRaw(t)
+	if err != nil {
+		return nil, err
+	}
+	cache.put(seq, t, buf)
+	return buf, nil
+}
+
+// readRaw reads the clipboard data in format t from the side channel of
+// the writer, or the platform otherwise. The caller must hold the lock.
+func readRaw(t Format) ([]byte, error) {
+	if buf, ok := readSide(t); ok {
+		return buf, nil
+	}
+	return sys.read(t)
+}
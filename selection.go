@@ -0,0 +1,46 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+// Selection is a selection of X11, which legacy applications use besides
+// the clipboard, see ReadSelection.
+type Selection int
+
+// All X11 selections that can be read.
+const (
+	// SelectionClipboard is the CLIPBOARD selection, which is the
+	// clipboard that all other functions of the package address.
+	SelectionClipboard Selection = iota
+	// SelectionPrimary is the PRIMARY selection, which holds the
+	// currently selected text and is pasted by the middle mouse button.
+	SelectionPrimary
+	// SelectionSecondary is the SECONDARY selection, which a few legacy
+	// applications use to exchange data without disturbing PRIMARY.
+	SelectionSecondary
+)
+
+// ReadSelection is like ReadErr but reads the given X11 selection. If the
+// selection has no owner, text is read from the cut buffer CUT_BUFFER0 of
+// the root window instead, which legacy applications, such as xterm,
+// still store the copied text in.
+//
+// ReadSelection is only supported on Linux. On other platforms, reading
+// SelectionClipboard is the same as ReadErr, and other selections return
+// ErrUnsupported.
+func ReadSelection(s Selection, t Format) ([]byte, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := ready(); err != nil {
+		return nil, err
+	}
+	buf, err := sys.readSelection(s, t)
+	if err != nil {
+		return nil, err
+	}
+	return checked(t, buf)
+}
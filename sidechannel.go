@@ -0,0 +1,250 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// useSideChannel indicates the side channel is enabled, see
+// WithSideChannel.
+var useSideChannel bool
+
+// WithSideChannel enables the side channel for transfers between
+// processes of the same machine that both use this package. Writes offer
+// their data through a local socket, whose address and a token of the
+// write are stored in the origin metadata, see ReadOrigin. Reads of the
+// data of such a write fetch it through the socket rather than the
+// clipboard, which makes transfers of hundreds of megabytes, such as
+// large images, instant instead of being copied through the X server or
+// the pasteboard chunk by chunk.
+//
+// The data is still written to the clipboard as usual, so that other
+// applications can paste it. Reads fall back to the clipboard if the
+// writer does not offer a side channel, for instance, the writer has
+// exited. WriteOnce does not use the side channel, as it must serve a
+// single paste.
+func WithSideChannel() Option {
+	return func(c *config) { c.sideChannel = true }
+}
+
+// sideRef is the reference to the side channel of a write, which is
+// stored in the origin metadata along with the Origin.
+type sideRef struct {
+	Origin
+	Channel string `json:"channel,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// sideChannel serves the data of the latest write of the process to the
+// readers of other processes.
+type sideChannel struct {
+	mu    sync.Mutex
+	ln    net.Listener
+	addr  string
+	token string
+	reps  map[string][]byte
+}
+
+var side sideChannel
+
+// offer serves the given representations of a write through the side
+// channel, and returns the reference of the write. The representations
+// are keyed by their MIME types.
+func (sc *sideChannel) offer(reps map[string][]byte) (sideRef, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.ln == nil {
+		ln, err := net.Listen("unix", sideAddr())
+		if err != nil {
+			return sideRef{}, err
+		}
+		sc.ln, sc.addr = ln, ln.Addr().String()
+		go sc.serve(ln)
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return sideRef{}, err
+	}
+	sc.token = hex.EncodeToString(b)
+	sc.reps = reps
+	return sideRef{Channel: sc.addr, Token: sc.token}, nil
+}
+
+// withdraw stops serving the write of the given token, for instance, the
+// clipboard has been overwritten, so that its data can be released.
+func (sc *sideChannel) withdraw(token string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.token == token {
+		sc.token, sc.reps = "", nil
+	}
+}
+
+// lookup returns the data of the given MIME type of the write of the
+// given token.
+func (sc *sideChannel) lookup(token, mime string) ([]byte, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if token == "" || token != sc.token {
+		return nil, false
+	}
+	b, ok := sc.reps[mime]
+	return b, ok
+}
+
+func (sc *sideChannel) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go sc.handle(conn)
+	}
+}
+
+// handle serves a request of the side channel, which is a line of the
+// token and the MIME type separated by a space. The response is a byte
+// that indicates whether the data is available, followed by the length
+// of the data as a 64-bit big endian integer and the data.
+func (sc *sideChannel) handle(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(sideTimeout))
+	line, err := bufio.NewReader(io.LimitReader(conn, 512)).ReadString('\n')
+	if err != nil {
+		return
+	}
+	req := strings.SplitN(strings.TrimSuffix(line, "\n"), " ", 2)
+	if len(req) != 2 {
+		conn.Write([]byte{0})
+		return
+	}
+	b, ok := sc.lookup(req[0], req[1])
+	if !ok {
+		conn.Write([]byte{0})
+		return
+	}
+	head := make([]byte, 9)
+	head[0] = 1
+	binary.BigEndian.PutUint64(head[1:], uint64(len(b)))
+	conn.Write(head)
+	conn.Write(b)
+}
+
+// sideTimeout is the timeout of connecting and requesting the side
+// channel. The transfer of the data is not limited.
+const sideTimeout = time.Second
+
+// fetch reads the data of the given MIME type of the write of ref
+// through its side channel. It reports false if the data is not
+// available through the side channel.
+func (ref sideRef) fetch(mime string) ([]byte, bool) {
+	if ref.Channel == "" || ref.Token == "" || mime == "" {
+		return nil, false
+	}
+	conn, err := net.DialTimeout("unix", ref.Channel, sideTimeout)
+	if err != nil {
+		return nil, false
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(sideTimeout))
+	if _, err := fmt.Fprintf(conn, "%s %s\n", ref.Token, mime); err != nil {
+		return nil, false
+	}
+	r := bufio.NewReader(conn)
+	head := make([]byte, 9)
+	if _, err := io.ReadFull(r, head[:1]); err != nil || head[0] != 1 {
+		return nil, false
+	}
+	if _, err := io.ReadFull(r, head[1:]); err != nil {
+		return nil, false
+	}
+	conn.SetDeadline(time.Time{})
+	n := binary.BigEndian.Uint64(head[1:])
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// offerSide offers the data of a write in format t along with its more
+// representations through the side channel, and returns the reference of
+// the write, which is empty if the side channel is not available.
+func offerSide(t Format, buf []byte, more []representation) sideRef {
+	mime := mimeOf(t)
+	if mime == "" {
+		return sideRef{}
+	}
+	reps := map[string][]byte{mime: buf}
+	for _, r := range more {
+		reps[r.mime] = r.data
+	}
+	ref, err := side.offer(reps)
+	if err != nil {
+		if debug {
+			fmt.Fprintf(os.Stderr, "failed to offer the side channel: %v\n", err)
+		}
+		return sideRef{}
+	}
+	return ref
+}
+
+// readSide reads the clipboard data in format t through the side channel
+// of the writer of the clipboard if the writer is another process that
+// offers one. The caller must hold the lock.
+func readSide(t Format) ([]byte, bool) {
+	if !useSideChannel {
+		return nil, false
+	}
+	buf, err := sys.readData(mimeOrigin)
+	if err != nil || len(buf) == 0 {
+		return nil, false
+	}
+	var ref sideRef
+	if err := json.NewDecoder(bytes.NewReader(buf)).Decode(&ref); err != nil {
+		return nil, false
+	}
+	if ref.PID == os.Getpid() {
+		// The clipboard serves the own writes without a round trip.
+		return nil, false
+	}
+	return ref.fetch(mimeOf(t))
+}
+
+// sideAddr returns the address of the side channel of the process. On
+// Linux, the socket is in the abstract namespace, hence leaves no file
+// behind. The requests are authenticated by the tokens of the writes,
+// which are only known to the readers of the clipboard.
+func sideAddr() string {
+	name := fmt.Sprintf("golang-design-clipboard-%d", os.Getpid())
+	if runtime.GOOS == "linux" {
+		return "@" + name
+	}
+	path := filepath.Join(os.TempDir(), name+".sock")
+	os.Remove(path)
+	return path
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestSideChannel(t *testing.T) {
+	want := bytes.Repeat([]byte("golang.design/x/clipboard"), 1<<16)
+	reps := map[string][]byte{"image/png": want, "text/plain": []byte("x")}
+
+	got, ok := clipboard.SideFetch(reps, "image/png", "")
+	if !ok {
+		t.Fatalf("failed to fetch through the side channel")
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("fetched data mismatch, got %d bytes, want %d bytes", len(got), len(want))
+	}
+	if _, ok := clipboard.SideFetch(reps, "text/html", ""); ok {
+		t.Fatalf("expect no data of a representation that is not offered")
+	}
+	if _, ok := clipboard.SideFetch(reps, "image/png", "invalid"); ok {
+		t.Fatalf("expect no data for an invalid token")
+	}
+}
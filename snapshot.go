@@ -0,0 +1,76 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Snapshot holds the clipboard data in different formats at a point
+// in time. Formats that are not available are absent from a snapshot.
+type Snapshot map[Format][]byte
+
+// TakeSnapshot reads the clipboard data in the given formats. If no
+// format is given, all supported formats are read.
+func TakeSnapshot(formats ...Format) Snapshot {
+	if len(formats) == 0 {
+		formats = allFormats
+	}
+	s := Snapshot{}
+	for _, t := range formats {
+		if b := Read(t); b != nil {
+			s[t] = b
+		}
+	}
+	return s
+}
+
+// Changes describes the differences between two snapshots. The formats
+// of each kind of change are sorted.
+type Changes struct {
+	// Added are the formats that are only available in the next snapshot.
+	Added []Format
+	// Removed are the formats that are only available in the previous
+	// snapshot.
+	Removed []Format
+	// Modified are the formats that are available in both snapshots
+	// but with different data.
+	Modified []Format
+}
+
+// Empty reports whether there is no change.
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// Diff returns the changes from snapshot prev to snapshot next, which
+// is useful for history UIs showing "image replaced text", for example:
+//
+//	c := clipboard.Diff(prev, next)
+//	// c.Added: [image], c.Removed: [text]
+func Diff(prev, next Snapshot) Changes {
+	var c Changes
+	for t, b := range next {
+		old, ok := prev[t]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, t)
+		case !bytes.Equal(old, b):
+			c.Modified = append(c.Modified, t)
+		}
+	}
+	for t := range prev {
+		if _, ok := next[t]; !ok {
+			c.Removed = append(c.Removed, t)
+		}
+	}
+	for _, fs := range [][]Format{c.Added, c.Removed, c.Modified} {
+		sort.Slice(fs, func(i, j int) bool { return fs[i] < fs[j] })
+	}
+	return c
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestDiff(t *testing.T) {
+	text := []byte("golang.design/x/clipboard")
+	img := []byte{0x89, 'P', 'N', 'G'}
+
+	tests := []struct {
+		name       string
+		prev, next clipboard.Snapshot
+		want       clipboard.Changes
+	}{
+		{
+			name: "unchanged",
+			prev: clipboard.Snapshot{clipboard.FmtText: text},
+			next: clipboard.Snapshot{clipboard.FmtText: text},
+		},
+		{
+			name: "image-replaced-text",
+			prev: clipboard.Snapshot{clipboard.FmtText: text},
+			next: clipboard.Snapshot{clipboard.FmtImage: img},
+			want: clipboard.Changes{
+				Added:   []clipboard.Format{clipboard.FmtImage},
+				Removed: []clipboard.Format{clipboard.FmtText},
+			},
+		},
+		{
+			name: "modified",
+			prev: clipboard.Snapshot{clipboard.FmtText: text, clipboard.FmtImage: img},
+			next: clipboard.Snapshot{clipboard.FmtText: []byte("text"), clipboard.FmtImage: img},
+			want: clipboard.Changes{
+				Modified: []clipboard.Format{clipboard.FmtText},
+			},
+		},
+		{
+			name: "from-empty",
+			prev: nil,
+			next: clipboard.Snapshot{clipboard.FmtImage: img, clipboard.FmtText: text},
+			want: clipboard.Changes{
+				Added: []clipboard.Format{clipboard.FmtText, clipboard.FmtImage},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clipboard.Diff(tt.prev, tt.next)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Diff mismatch, got: %+v, want: %+v", got, tt.want)
+			}
+			if got.Empty() != tt.want.Empty() {
+				t.Fatalf("Empty mismatch, got: %v, want: %v", got.Empty(), tt.want.Empty())
+			}
+		})
+	}
+}
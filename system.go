@@ -0,0 +1,54 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+// system is the implementation of the clipboard that Init selects. Each
+// platform implements the functions of the platform clipboard, and
+// BackendMemory replaces them with the in-memory clipboard.
+type system struct {
+	read      func(t Format) ([]byte, error)
+	readData  func(mime string) ([]byte, error)
+	has       func(t Format) bool
+	prober    func() func(Format) bool
+	imageInfo func() (int, int, string, error)
+	write     func(t Format, buf []byte, extra []representation) (<-chan struct{}, error)
+	writeOnce func(t Format, buf []byte, extra []representation) (<-chan struct{}, error)
+	writeData func(mime string, buf []byte, extra []representation) (<-chan struct{}, error)
+	sequence  func() (uint64, bool)
+	lost      func() <-chan struct{}
+	rawCall   func(fn func(uintptr) error) error
+	announce  func(msg string) error
+	info      func() Info
+	locked    func() bool
+
+	readSelection  func(s Selection, t Format) ([]byte, error)
+	virtualFiles   func(dir string) ([]string, error)
+	writeOSAScript func(kind OSAScriptKind, buf []byte) (<-chan struct{}, error)
+}
+
+// sys is the clipboard that is selected by Init, which must not change
+// after Init succeeded.
+var sys = system{
+	read:      read,
+	readData:  readData,
+	has:       has,
+	prober:    prober,
+	imageInfo: imageInfo,
+	write:     write,
+	writeOnce: writeOnce,
+	writeData: writeData,
+	sequence:  sequence,
+	lost:      lost,
+	rawCall:   rawCall,
+	announce:  announce,
+	info:      info,
+	locked:    locked,
+
+	readSelection:  readSelection,
+	virtualFiles:   virtualFiles,
+	writeOSAScript: writeOSAScript,
+}
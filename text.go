@@ -0,0 +1,84 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// LineEnding represents the line endings of text.
+type LineEnding int
+
+// All sorts of line endings
+const (
+	// LineEndingKeep keeps the line endings of text as is.
+	LineEndingKeep LineEnding = iota
+	// LineEndingLF converts the line endings of text to LF, which is
+	// the convention of macOS and Linux.
+	LineEndingLF
+	// LineEndingCRLF converts the line endings of text to CRLF, which
+	// is the convention of Windows.
+	LineEndingCRLF
+)
+
+// String returns the name of the line ending.
+func (e LineEnding) String() string {
+	switch e {
+	case LineEndingKeep:
+		return "keep"
+	case LineEndingLF:
+		return "lf"
+	case LineEndingCRLF:
+		return "crlf"
+	}
+	return fmt.Sprintf("LineEnding(%d)", int(e))
+}
+
+// The line endings of FmtText that are converted by reads and writes,
+// see WithReadLineEnding and WithWriteLineEnding.
+var (
+	readLineEnding  LineEnding
+	writeLineEnding LineEnding
+)
+
+// convertLineEndings converts the line endings of the given text to e.
+// CRLF and lone CR are both considered as line endings.
+func convertLineEndings(buf []byte, e LineEnding) []byte {
+	if e == LineEndingKeep {
+		return buf
+	}
+	buf = bytes.ReplaceAll(buf, []byte("\r\n"), []byte("\n"))
+	buf = bytes.ReplaceAll(buf, []byte("\r"), []byte("\n"))
+	if e == LineEndingCRLF {
+		buf = bytes.ReplaceAll(buf, []byte("\n"), []byte("\r\n"))
+	}
+	return buf
+}
+
+// decodeLatin1 returns the UTF-8 encoding of the given Latin-1 encoded
+// text, which is the encoding of the STRING target of X11.
+func decodeLatin1(buf []byte) []byte {
+	r := make([]rune, len(buf))
+	for i, b := range buf {
+		r[i] = rune(b)
+	}
+	return []byte(string(r))
+}
+
+// encodeLatin1 returns the Latin-1 encoding of the given UTF-8 encoded
+// text, where characters outside of Latin-1 are replaced by '?'.
+func encodeLatin1(buf []byte) []byte {
+	out := make([]byte, 0, len(buf))
+	for _, r := range string(buf) {
+		if r > 0xff {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
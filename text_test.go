@@ -0,0 +1,44 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestLineEndings(t *testing.T) {
+	in := []byte("a\r\nb\nc\rd")
+	tests := []struct {
+		e    clipboard.LineEnding
+		want string
+	}{
+		{clipboard.LineEndingKeep, "a\r\nb\nc\rd"},
+		{clipboard.LineEndingLF, "a\nb\nc\nd"},
+		{clipboard.LineEndingCRLF, "a\r\nb\r\nc\r\nd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.e.String(), func(t *testing.T) {
+			if got := string(clipboard.ConvertLines(in, tt.e)); got != tt.want {
+				t.Fatalf("converted text mismatch, got: %q, want: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeLatin1(t *testing.T) {
+	if got := string(clipboard.DecodeLatin1([]byte("caf\xe9"))); got != "café" {
+		t.Fatalf("decoded text mismatch, got: %q, want: %q", got, "café")
+	}
+}
+
+func TestEncodeLatin1(t *testing.T) {
+	if got := string(clipboard.EncodeLatin1([]byte("café €"))); got != "caf\xe9 ?" {
+		t.Fatalf("encoded text mismatch, got: %q, want: %q", got, "caf\xe9 ?")
+	}
+}
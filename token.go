@@ -0,0 +1,64 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import "sync"
+
+// tokenTable maps small integer tokens to values of the Go side, such as
+// channels, which allows the C side to refer to them without holding any
+// Go pointer. Unlike runtime/cgo.Handle, the table reuses its slots, hence
+// it does not allocate once it has grown to the number of concurrent
+// tokens, which is small as writes are serialized.
+type tokenTable struct {
+	mu    sync.Mutex
+	slots []interface{}
+}
+
+// tokens are the tokens of the channels that receive the status of
+// ongoing writes, and of the renderers of their representations.
+var tokens tokenTable
+
+// put stores the given value and returns its token, which is never zero.
+func (t *tokenTable) put(v interface{}) uintptr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.slots {
+		if t.slots[i] == nil {
+			t.slots[i] = v
+			return uintptr(i + 1)
+		}
+	}
+	t.slots = append(t.slots, v)
+	return uintptr(len(t.slots))
+}
+
+// get returns the value of the given token, or nil if the token is
+// unknown.
+func (t *tokenTable) get(token uintptr) interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if token == 0 || token > uintptr(len(t.slots)) {
+		return nil
+	}
+	return t.slots[token-1]
+}
+
+// take removes the value of the given token and returns it, or nil if
+// the token is unknown.
+func (t *tokenTable) take(token uintptr) interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if token == 0 || token > uintptr(len(t.slots)) {
+		return nil
+	}
+	v := t.slots[token-1]
+	t.slots[token-1] = nil
+	return v
+}
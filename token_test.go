@@ -0,0 +1,42 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestToken(t *testing.T) {
+	c1, c2 := make(chan int), make(chan int)
+	t1 := clipboard.PutToken(c1)
+	t2 := clipboard.PutToken(c2)
+	if t1 == 0 || t2 == 0 || t1 == t2 {
+		t.Fatalf("invalid tokens: %v, %v", t1, t2)
+	}
+	if got := clipboard.TakeToken(t1); got != c1 {
+		t.Fatalf("token %v refers to a wrong channel", t1)
+	}
+	if got := clipboard.TakeToken(t1); got != nil {
+		t.Fatalf("token %v is not removed after take", t1)
+	}
+	if got := clipboard.TakeToken(0); got != nil {
+		t.Fatalf("zero token should refer to no channel")
+	}
+
+	// The slot of a taken token is reused without allocation.
+	allocs := testing.AllocsPerRun(100, func() {
+		clipboard.TakeToken(clipboard.PutToken(c1))
+	})
+	if allocs != 0 {
+		t.Fatalf("token round trip allocates %v times", allocs)
+	}
+	if got := clipboard.TakeToken(t2); got != c2 {
+		t.Fatalf("token %v refers to a wrong channel", t2)
+	}
+}
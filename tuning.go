@@ -0,0 +1,91 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DisplayTuning holds the tunables of clipboard transfers over a display
+// connection. The tunables are honored by the X11 backend on Linux, and
+// Init detects them automatically based on the display connection.
+//
+// Over ssh -X or slow VNC/X connections, transferring large images at
+// once can freeze applications. For remote displays, the data are
+// transferred incrementally in smaller chunks, and reads wait longer
+// for the selection owner to respond.
+type DisplayTuning struct {
+	// Remote reports whether the display connection is remote.
+	Remote bool
+	// Latency is the measured round trip time to the display server.
+	Latency time.Duration
+	// ReadTimeout is the maximum time to wait for the clipboard
+	// owner to deliver the data. Zero means waiting forever. For data
+	// that the owner transfers incrementally, the timeout applies to
+	// each chunk.
+	ReadTimeout time.Duration
+	// ChunkSize is the maximum number of bytes transferred at once.
+	// Larger data are transferred incrementally. Zero means the
+	// maximum request size of the display server.
+	ChunkSize int
+}
+
+const (
+	// remoteLatency is the round trip time above which a display
+	// connection is considered remote.
+	remoteLatency = 10 * time.Millisecond
+
+	localReadTimeout  = 5 * time.Second
+	remoteReadTimeout = 30 * time.Second
+	remoteChunkSize   = 64 << 10
+)
+
+var (
+	tuningMu sync.Mutex
+	tuning   = tuneFor("", 0)
+)
+
+// Tuning returns the current display tuning.
+func Tuning() DisplayTuning {
+	tuningMu.Lock()
+	defer tuningMu.Unlock()
+	return tuning
+}
+
+// SetTuning overrides the display tuning detected by Init.
+func SetTuning(t DisplayTuning) {
+	tuningMu.Lock()
+	defer tuningMu.Unlock()
+	tuning = t
+}
+
+// tuneFor returns the display tuning for a given display name, such as
+// the value of $DISPLAY, and the measured latency of the connection.
+func tuneFor(display string, latency time.Duration) DisplayTuning {
+	// A display name with a host name, for instance localhost:10.0
+	// that is used by ssh -X, is connected over the network.
+	host := display
+	if i := strings.LastIndex(display, ":"); i >= 0 {
+		host = display[:i]
+	}
+	remote := (host != "" && host != "unix") || latency > remoteLatency
+
+	if !remote {
+		return DisplayTuning{
+			Latency:     latency,
+			ReadTimeout: localReadTimeout,
+		}
+	}
+	return DisplayTuning{
+		Remote:      true,
+		Latency:     latency,
+		ReadTimeout: remoteReadTimeout,
+		ChunkSize:   remoteChunkSize,
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.design/x/clipboard"
+)
+
+func TestTuneFor(t *testing.T) {
+	tests := []struct {
+		display string
+		latency time.Duration
+		remote  bool
+	}{
+		{":0", time.Millisecond, false},
+		{":99.0", 0, false},
+		{"unix:0", 0, false},
+		{"localhost:10.0", time.Millisecond, true},
+		{"192.168.1.2:0", 0, true},
+		{":0", 50 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		got := clipboard.TuneFor(tt.display, tt.latency)
+		if got.Remote != tt.remote {
+			t.Fatalf("TuneFor(%q, %v) remote mismatch, got: %v, want: %v", tt.display, tt.latency, got.Remote, tt.remote)
+		}
+		if got.Remote && got.ChunkSize == 0 {
+			t.Fatalf("TuneFor(%q, %v) should transfer remote data in chunks", tt.display, tt.latency)
+		}
+		if got.ReadTimeout <= 0 {
+			t.Fatalf("TuneFor(%q, %v) should bound reads with a timeout", tt.display, tt.latency)
+		}
+	}
+}
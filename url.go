@@ -0,0 +1,44 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.design/x/clipboard/formats"
+)
+
+// decodeURL16 decodes a URL that is encoded in UTF-16LE, with or without
+// a byte order mark, to the data of FmtURL. As URLs start with an ASCII
+// scheme, data whose second byte is not NUL is considered UTF-8 encoded.
+// The URL ends at the first NUL or newline, as text/x-moz-url puts the
+// title of the link on the second line.
+func decodeURL16(buf []byte) []byte {
+	if len(buf) >= 2 && (buf[1] == 0 || bytes.HasPrefix(buf, []byte{0xff, 0xfe})) {
+		buf = []byte(formats.DecodeUTF16(buf))
+	}
+	if i := bytes.IndexAny(buf, "\x00\n"); i >= 0 {
+		buf = buf[:i]
+	}
+	return bytes.TrimSpace(buf)
+}
+
+// textURL returns the given text if it is an absolute URL, otherwise
+// nil. It allows reading a URL that is only offered as plain text.
+func textURL(buf []byte) []byte {
+	s := strings.TrimSpace(string(buf))
+	if s == "" || strings.ContainsAny(s, " \t\r\n") {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || !u.IsAbs() || (u.Host == "" && u.Opaque == "" && u.Path == "") {
+		return nil
+	}
+	return []byte(s)
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"testing"
+
+	"golang.design/x/clipboard"
+	"golang.design/x/clipboard/formats"
+)
+
+func TestURL16(t *testing.T) {
+	u := []byte("https://golang.design/数据")
+	if got := clipboard.DecodeURL16(formats.EncodeUTF16(string(u))); string(got) != string(u) {
+		t.Fatalf("decoded url mismatch, got: %q, want: %q", got, u)
+	}
+
+	// text/x-moz-url puts the title on the second line.
+	moz := formats.EncodeUTF16("https://golang.design\ngolang.design")
+	if got := string(clipboard.DecodeURL16(moz)); got != "https://golang.design" {
+		t.Fatalf("decoded url mismatch, got: %q", got)
+	}
+	if got := string(clipboard.DecodeURL16([]byte("https://golang.design\x00"))); got != "https://golang.design" {
+		t.Fatalf("decoded url mismatch, got: %q", got)
+	}
+}
+
+func TestTextURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://golang.design", "https://golang.design"},
+		{" mailto:gopher@golang.design\n", "mailto:gopher@golang.design"},
+		{"golang.design", ""},
+		{"see https://golang.design", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := string(clipboard.TextURL([]byte(tt.in))); got != tt.want {
+			t.Fatalf("text url of %q mismatch, got: %q, want: %q", tt.in, got, tt.want)
+		}
+	}
+}
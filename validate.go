@@ -0,0 +1,110 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+var strictRead int32
+
+// SetStrictRead sets whether the clipboard data is validated before it
+// is returned by a read. The validation is disabled by default.
+//
+// The clipboard data is written by other applications and must be
+// considered untrusted. In strict mode, data that fails the validation,
+// for instance, images without a valid PNG signature or oversized
+// images, is rejected as a whole instead of being partially decoded.
+// ReadErr returns a *MalformedError for rejected data, and Read returns
+// nil. This is useful for security-sensitive applications that feed the
+// clipboard data into further parsers.
+func SetStrictRead(strict bool) {
+	if strict {
+		atomic.StoreInt32(&strictRead, 1)
+	} else {
+		atomic.StoreInt32(&strictRead, 0)
+	}
+}
+
+// MalformedError is returned by reads in strict mode if the clipboard
+// data fails the validation.
+type MalformedError struct {
+	Format Format
+	Reason string
+}
+
+func (e *MalformedError) Error() string {
+	return fmt.Sprintf("clipboard: malformed %v data: %s", e.Format, e.Reason)
+}
+
+// maxImagePixels is the maximum number of pixels of an image that is
+// accepted in strict mode, which is large enough for 8K screenshots.
+const maxImagePixels = 1 << 27
+
+// pngSignature is the leading bytes of every PNG encoded data.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// validate checks whether buf is well-formed data of format t, and
+// returns a *MalformedError if it is not.
+func validate(t Format, buf []byte) error {
+	if len(buf) == 0 {
+		return nil
+	}
+	malformed := func(format string, args ...interface{}) error {
+		return &MalformedError{Format: t, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	switch t {
+	case FmtText, FmtHTML, FmtFiles, FmtURL:
+		if !utf8.Valid(buf) {
+			return malformed("invalid UTF-8 encoding")
+		}
+	case FmtRTF:
+		if !bytes.HasPrefix(buf, []byte(`{\rtf`)) {
+			return malformed("missing RTF header")
+		}
+	case FmtVCard, FmtICal:
+		if !utf8.Valid(buf) {
+			return malformed("invalid UTF-8 encoding")
+		}
+		begin := "BEGIN:VCARD"
+		if t == FmtICal {
+			begin = "BEGIN:VCALENDAR"
+		}
+		s := strings.TrimLeft(string(buf), "\ufeff \t\r\n")
+		if len(s) < len(begin) || !strings.EqualFold(s[:len(begin)], begin) {
+			return malformed("missing %s", begin)
+		}
+	case FmtColor:
+		if len(buf) != colorSize {
+			return malformed("color data of %d bytes, want %d", len(buf), colorSize)
+		}
+	case FmtImage:
+		if !bytes.HasPrefix(buf, pngSignature) {
+			return malformed("missing PNG signature")
+		}
+		// Check the dimensions before decoding, so that a small payload
+		// that claims a huge image cannot exhaust the memory.
+		cfg, err := png.DecodeConfig(bytes.NewReader(buf))
+		if err != nil {
+			return malformed("invalid PNG header: %v", err)
+		}
+		if cfg.Width <= 0 || cfg.Height <= 0 ||
+			int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+			return malformed("image size %dx%d out of range", cfg.Width, cfg.Height)
+		}
+		if _, err := png.Decode(bytes.NewReader(buf)); err != nil {
+			return malformed("invalid PNG data: %v", err)
+		}
+	}
+	return nil
+}
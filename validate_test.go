@@ -0,0 +1,63 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"golang.design/x/clipboard"
+)
+
+func TestValidate(t *testing.T) {
+	data, err := os.ReadFile("tests/testdata/clipboard.png")
+	if err != nil {
+		t.Fatalf("failed to read gold file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		t    clipboard.Format
+		buf  []byte
+		ok   bool
+	}{
+		{"text", clipboard.FmtText, []byte("golang.design/x/clipboard"), true},
+		{"text-invalid-utf8", clipboard.FmtText, []byte("golang\xffdesign"), false},
+		{"rtf", clipboard.FmtRTF, []byte(`{\rtf1\ansi golang.design}`), true},
+		{"rtf-no-header", clipboard.FmtRTF, []byte("golang.design"), false},
+		{"image", clipboard.FmtImage, data, true},
+		{"image-no-signature", clipboard.FmtImage, data[8:], false},
+		{"image-truncated", clipboard.FmtImage, data[:len(data)/2], false},
+		{"color", clipboard.FmtColor, []byte{0xff, 0xff, 0, 0, 0, 0, 0xff, 0xff}, true},
+		{"color-truncated", clipboard.FmtColor, []byte{0xff, 0xff, 0, 0}, false},
+		{"vcard", clipboard.FmtVCard, []byte("BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Gopher\r\nEND:VCARD\r\n"), true},
+		{"vcard-lower-case", clipboard.FmtVCard, []byte("\r\nbegin:vcard\r\nend:vcard\r\n"), true},
+		{"vcard-no-begin", clipboard.FmtVCard, []byte("FN:Gopher"), false},
+		{"ical", clipboard.FmtICal, []byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nEND:VCALENDAR\r\n"), true},
+		{"ical-vcard", clipboard.FmtICal, []byte("BEGIN:VCARD\r\nEND:VCARD\r\n"), false},
+		{"empty", clipboard.FmtImage, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := clipboard.Validate(tt.t, tt.buf)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("expect valid data, got: %v", err)
+				}
+				return
+			}
+			var merr *clipboard.MalformedError
+			if !errors.As(err, &merr) {
+				t.Fatalf("expect a MalformedError, got: %v", err)
+			}
+			if merr.Format != tt.t {
+				t.Fatalf("format mismatch, got: %v, want: %v", merr.Format, tt.t)
+			}
+		})
+	}
+}
@@ -0,0 +1,1037 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// EventKind represents the kind of a clipboard change.
+type EventKind int
+
+// All kinds of clipboard changes
+const (
+	// EventChanged indicates the clipboard data in the watched format
+	// has been changed.
+	EventChanged EventKind = iota
+	// EventCleared indicates the clipboard no longer holds data in the
+	// watched format, or holds empty data. For instance, the user has
+	// cleared the clipboard, or copied data in another format.
+	EventCleared
+	// EventError indicates the clipboard cannot be read in the watched
+	// format, for instance, the connection to the X server is lost. The
+	// watcher keeps checking the clipboard, and reports the next error
+	// only if it differs from the previous one, or EventReconnected once
+	// the clipboard can be read again.
+	EventError
+	// EventReconnected indicates the clipboard can be read again in the
+	// watched format after EventError, for instance, the X server is
+	// reachable again after a restart. The watcher resumes delivering
+	// changes, and a change that happened meanwhile follows as
+	// EventChanged or EventCleared.
+	EventReconnected
+	// EventSuspended indicates the clipboard has become inaccessible as
+	// the session is locked, or the secure desktop of a UAC prompt is
+	// shown on Windows. The watcher stops reading the clipboard, hence
+	// reports no errors meanwhile, until EventResumed.
+	EventSuspended
+	// EventResumed indicates the clipboard is accessible again after
+	// EventSuspended. A change that happened meanwhile follows as
+	// EventChanged or EventCleared.
+	EventResumed
+)
+
+// Event represents a change of the clipboard.
+type Event struct {
+	Kind   EventKind
+	Format Format
+	// Data is the clipboard data if Kind is EventChanged, except for
+	// events of WatchLazy.
+	Data []byte
+	// Payload is the handle to fetch the clipboard data if Kind is
+	// EventChanged and the event is delivered by WatchLazy.
+	Payload *Payload
+	// Err is the error of the read if Kind is EventError.
+	Err error
+	// Size is the length of Data, or of the data of Payload if it is
+	// known without fetching the data.
+	Size int
+	// Seq is the sequence number of the clipboard when the change was
+	// detected, which is zero on platforms without a sequence number,
+	// see Sequence. Events of the same change share the same Seq, so
+	// that consumers can deduplicate and order events.
+	Seq uint64
+	// Time is the time when the change was detected.
+	Time time.Time
+}
+
+// WatchEvents is like Watch but delivers events, which also report
+// the clipboard becomes cleared in the desired format, so that clipboard
+// managers can reflect cleared states instead of showing stale content,
+// and the errors of reading the clipboard, so that applications can
+// detect a broken watcher instead of waiting for changes forever.
+//
+// WatchEvents watches all the given formats using a single channel, and
+// the Format of an event tells the format that changed, for instance:
+//
+//	for e := range clipboard.WatchEvents(ctx, clipboard.FmtText, clipboard.FmtImage) {
+//		switch e.Format {
+//		case clipboard.FmtText:
+//			// ...
+//		case clipboard.FmtImage:
+//			// ...
+//		}
+//	}
+//
+// The returned channel will be closed if the given context is canceled.
+func WatchEvents(ctx context.Context, t Format, more ...Format) <-chan Event {
+	return watchEvents(ctx, append([]Format{t}, more...), watchConfig{})
+}
+
+// WatchLazy is like WatchEvents but delivers a Payload instead of the
+// Data of changes, so that consumers can decide whether to transfer the
+// data of each change, for instance, to skip huge images. On platforms
+// with a sequence number, see Sequence, the changes are detected without
+// reading the data, which saves the overhead of watching formats with
+// large data, such as FmtImage. Read errors are then reported by Fetch
+// rather than EventError. Elsewhere, the data is read to detect changes
+// as WatchEvents does.
+func WatchLazy(ctx context.Context, t Format, more ...Format) <-chan Event {
+	return watchEvents(ctx, append([]Format{t}, more...), watchConfig{lazy: true})
+}
+
+// Payload is a handle of the clipboard data of a change, see WatchLazy.
+type Payload struct {
+	t     Format
+	seq   uint64
+	seqOK bool
+	// data is the data that is read when the change was detected, which
+	// is nil if the data is fetched on demand.
+	data []byte
+}
+
+// errStale indicates the clipboard has changed since the event of a
+// payload, hence the data of the payload is gone.
+var errStale = fmt.Errorf("%w: the clipboard has changed since the event", ErrUnavailable)
+
+// Fetch reads the clipboard data of the payload. The read is given up
+// once the given context is done, see ReadCtx. It returns an error that
+// wraps ErrUnavailable if the clipboard has changed again since the
+// event, as the data of the change is no longer available.
+func (p *Payload) Fetch(ctx context.Context) ([]byte, error) {
+	if p.data != nil {
+		return p.data, nil
+	}
+	if cnt, ok := Sequence(); ok && p.seqOK && cnt != p.seq {
+		return nil, errStale
+	}
+	b, err := ReadCtx(ctx, p.t)
+	if err != nil {
+		return nil, err
+	}
+	// The clipboard may have changed during the read.
+	if cnt, ok := Sequence(); ok && p.seqOK && cnt != p.seq {
+		return nil, errStale
+	}
+	return b, nil
+}
+
+// watchEvents watches the given formats using a single channel of
+// events, and configures the watchers of the formats using c.
+func watchEvents(ctx context.Context, fmts []Format, c watchConfig) <-chan Event {
+	var formats []Format
+	seen := map[Format]bool{}
+	for _, f := range fmts {
+		if !seen[f] {
+			seen[f] = true
+			formats = append(formats, f)
+		}
+	}
+	recv := make(chan Event, len(formats))
+	if !InitDone() {
+		close(recv)
+		return recv
+	}
+
+	// Buffer two events, so that a reconnection or a resumption is not
+	// dropped for the change that it reveals.
+	c.buffer = 2
+	var wg sync.WaitGroup
+	for _, f := range formats {
+		s := mon.subscribe(f, true, c)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer mon.unsubscribe(s)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e := <-s.mail:
+					select {
+					case recv <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(recv)
+	}()
+	return recv
+}
+
+// WatchAll watches changes of the clipboard in any format, and delivers
+// a snapshot of the clipboard data in all available formats whenever the
+// clipboard changes, for instance, to record text, images and files of a
+// clipboard manager using a single watcher. A snapshot without formats
+// indicates the clipboard is cleared. Use Diff to find out the formats
+// that a change added, removed or modified.
+//
+// The returned channel will be closed if the given context is canceled.
+func WatchAll(ctx context.Context) <-chan Snapshot {
+	recv := make(chan Snapshot, 1)
+	if !InitDone() {
+		close(recv)
+		return recv
+	}
+	s := mon.subscribeAll()
+	go func() {
+		defer mon.unsubscribe(s)
+		defer close(recv)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case snap := <-s.snaps:
+				select {
+				case recv <- snap:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return recv
+}
+
+// WatchFiles watches the clipboard for copied files, and delivers their
+// paths as PasteFiles returns, so that applications can paste files into
+// their windows whenever files are copied, for instance:
+//
+//	for paths := range clipboard.WatchFiles(ctx, "") {
+//		for _, p := range paths {
+//			// Open the file in the window.
+//		}
+//	}
+//
+// Files that only exist as clipboard data, such as the file promises of
+// macOS and the virtual files of Windows, are saved into dir, see
+// PasteFiles. On platforms without a sequence number, see Sequence, only
+// the files of file managers are watched.
+//
+// The returned channel will be closed if the given context is canceled.
+func WatchFiles(ctx context.Context, dir string) <-chan []string {
+	recv := make(chan []string, 1)
+	if !InitDone() {
+		close(recv)
+		return recv
+	}
+
+	var s *subscriber
+	if _, ok := sys.sequence(); ok {
+		s = mon.subscribeChanges()
+	} else {
+		s = mon.subscribe(FmtFiles, false, watchConfig{})
+	}
+	go func() {
+		defer mon.unsubscribe(s)
+		defer close(recv)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.mail:
+				paths, err := PasteFiles(dir)
+				if err != nil || len(paths) == 0 {
+					continue
+				}
+				select {
+				case recv <- paths:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return recv
+}
+
+// OnChange calls fn with the clipboard data whenever any change of
+// clipboard data in the desired format happens, until the returned
+// cancel function is called. This suits GUI frameworks whose event
+// loops prefer callbacks over draining channels.
+//
+// fn is called sequentially on a separate goroutine. If fn is slower
+// than the changes of the clipboard, the changes are coalesced and fn
+// only receives the latest data. An ongoing call of fn is not
+// interrupted by cancel, but no further call is made.
+func OnChange(t Format, fn func([]byte)) (cancel func()) {
+	if !InitDone() {
+		return func() {}
+	}
+	s := mon.subscribe(t, false, watchConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer mon.unsubscribe(s)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-s.mail:
+				if ctx.Err() != nil {
+					return
+				}
+				fn(e.Data)
+			}
+		}
+	}()
+	return cancel
+}
+
+// WatchOption configures a watcher of WatchWithOptions.
+type WatchOption func(*watchConfig)
+
+// watchConfig is the configuration of a watcher.
+type watchConfig struct {
+	interval   time.Duration
+	ignoreSelf bool
+	buffer     int
+	policy     Backpressure
+	filters    []func([]byte) bool
+	lazy       bool
+}
+
+// Backpressure is the policy of a watcher whose receiver is slower than
+// the changes of the clipboard, for instance, during a drag selection
+// that changes the primary selection continuously.
+type Backpressure int
+
+// All sorts of backpressure policies
+const (
+	// DropOldest drops the oldest buffered change for the latest one,
+	// hence the receiver always catches up with the latest data. It is
+	// the default policy.
+	DropOldest Backpressure = iota
+	// DropNewest drops the latest change if the buffer is full, hence
+	// the receiver receives the first changes of a burst.
+	DropNewest
+	// Block waits for the receiver, hence no change is dropped. Note
+	// that the changes of all watchers are detected by a single loop,
+	// which is blocked while a watcher blocks.
+	Block
+)
+
+// WatchBuffer specifies the number of changes that the watcher buffers
+// for a receiver that is slower than the changes of the clipboard, which
+// is one by default. See WatchBackpressure for what happens when the
+// buffer is full.
+func WatchBuffer(n int) WatchOption {
+	return func(c *watchConfig) {
+		if n > 0 {
+			c.buffer = n
+		}
+	}
+}
+
+// WatchBackpressure specifies the policy of the watcher when its buffer
+// is full, which is DropOldest by default.
+func WatchBackpressure(p Backpressure) WatchOption {
+	return func(c *watchConfig) { c.policy = p }
+}
+
+// WatchInterval specifies the interval that the watcher checks the
+// clipboard at, which overrides the interval of WithPollInterval for the
+// watcher, for instance, a short interval for an interactive tool while
+// other watchers of the process keep saving battery. A non-positive
+// interval uses the interval of WithPollInterval.
+func WatchInterval(d time.Duration) WatchOption {
+	return func(c *watchConfig) { c.interval = d }
+}
+
+// WatchIgnoreSelf specifies that the watcher ignores the changes that
+// the writes of formats of this process made, such as Write and
+// WriteRich, so that applications that both write and watch the
+// clipboard do not receive their own writes back. Changes
+// are told apart by the sequence number of the clipboard after a write,
+// see Sequence. On platforms without a sequence number, a change is
+// ignored if its data equals the data that the process wrote last time.
+func WatchIgnoreSelf() WatchOption {
+	return func(c *watchConfig) { c.ignoreSelf = true }
+}
+
+// WatchFilter specifies that the watcher only delivers the changed data
+// for which fn returns true, for instance, clipboard history tools may
+// skip secrets. fn is called on the change detection loop before the
+// data is buffered, hence it should return quickly. The filters of
+// multiple WatchFilter, WatchSize and WatchMatch options must all pass.
+func WatchFilter(fn func([]byte) bool) WatchOption {
+	return func(c *watchConfig) {
+		if fn != nil {
+			c.filters = append(c.filters, fn)
+		}
+	}
+}
+
+// WatchSize specifies that the watcher only delivers the changed data
+// whose length is at least min and at most max bytes, for instance, to
+// skip huge images. A non-positive max does not limit the length.
+func WatchSize(min, max int) WatchOption {
+	return WatchFilter(func(b []byte) bool {
+		return len(b) >= min && (max <= 0 || len(b) <= max)
+	})
+}
+
+// WatchMatch specifies that the watcher only delivers the changed data
+// that matches the given regular expression, which suits watchers of
+// text, for instance, to only receive copied links.
+func WatchMatch(re *regexp.Regexp) WatchOption {
+	return WatchFilter(re.Match)
+}
+
+// WatchWithOptions is like Watch but configures the watcher using the
+// given options, see WatchInterval, WatchIgnoreSelf, WatchBuffer,
+// WatchBackpressure and WatchFilter.
+func WatchWithOptions(ctx context.Context, t Format, opts ...WatchOption) <-chan []byte {
+	var c watchConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return watch(ctx, t, c)
+}
+
+// NotifyUpdate notifies the change detection of Watch that the clipboard
+// may have changed, which checks the clipboard immediately instead of
+// waiting for the next poll. Host applications call it when they receive
+// a clipboard notification of the platform, such as WM_CLIPBOARDUPDATE
+// for the window of WithListenerWindow.
+func NotifyUpdate() {
+	select {
+	case mon.kick <- struct{}{}:
+	default:
+	}
+	writes.notify()
+}
+
+// SuspendWatchers pauses all watchers of the package, such as Watch,
+// WatchEvents and OnChange, without unsubscribing them, for instance,
+// while a password manager types a secret through the clipboard. Once it
+// returns, the watchers neither read the clipboard nor deliver changes
+// until ResumeWatchers is called. Changes that are already delivered
+// into the channels of the watchers are kept.
+//
+// Calls nest: the watchers resume once every SuspendWatchers has been
+// matched by a ResumeWatchers.
+func SuspendWatchers() {
+	mon.mu.Lock()
+	mon.suspended++
+	mon.mu.Unlock()
+
+	// Wait for an ongoing poll, which may have started before.
+	mon.polling.Lock()
+	mon.polling.Unlock()
+}
+
+// ResumeWatchers resumes the watchers that are paused by SuspendWatchers.
+// The changes of the clipboard while the watchers are suspended are not
+// delivered: the watchers take the clipboard as of resuming as the
+// baseline, and deliver the changes after it.
+func ResumeWatchers() {
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+
+	if mon.suspended == 0 {
+		return
+	}
+	mon.suspended--
+	if mon.suspended > 0 {
+		return
+	}
+	for s := range mon.subs {
+		s.rebase = true
+	}
+	// Take the baseline of all watchers right away.
+	select {
+	case mon.kick <- struct{}{}:
+	default:
+	}
+}
+
+// own is the latest write of this process, see WatchIgnoreSelf.
+var own struct {
+	sync.Mutex
+	seq   uint64
+	seqOK bool
+	t     Format
+	data  []byte
+}
+
+// recordWrite records a write of this process, which results in the
+// given sequence number of the clipboard if seqOK is true.
+func recordWrite(seq uint64, seqOK bool, t Format, data []byte) {
+	own.Lock()
+	defer own.Unlock()
+
+	own.seq, own.seqOK = seq, seqOK
+	own.t, own.data = t, data
+}
+
+// isOwnWrite reports whether the change of the clipboard to the given
+// sequence number, or to the given data of format t if the platform has
+// no sequence number, is made by the latest write of this process.
+func isOwnWrite(seq uint64, seqOK bool, t Format, data []byte) bool {
+	own.Lock()
+	defer own.Unlock()
+
+	if seqOK {
+		return own.seqOK && own.seq == seq
+	}
+	return own.t == t && bytes.Equal(own.data, data)
+}
+
+// defaultPollInterval is the default interval of the change detection.
+// not sure if we are too slow or the user too fast :)
+const defaultPollInterval = time.Second
+
+// monitor is the single change detection loop of the package. All
+// watchers subscribe to the monitor, hence having many watchers only
+// costs one platform polling loop. The loop ticks at the shortest
+// interval of the subscribers, and each subscriber is checked when its
+// own interval elapsed.
+type monitor struct {
+	mu       sync.Mutex
+	subs     map[*subscriber]struct{}
+	stop     chan struct{}
+	interval time.Duration
+	// tick is the interval of the running loop.
+	tick time.Duration
+	// kick receives notifications of NotifyUpdate.
+	kick chan struct{}
+
+	// polling serializes polls, as a stopped loop may still be
+	// polling when a new loop starts.
+	polling sync.Mutex
+	// suspended is the number of SuspendWatchers calls that are not
+	// resumed yet, the clipboard is not polled if it is positive.
+	suspended int
+	// locked indicates the session is locked, which was told to the
+	// subscribers of events by EventSuspended.
+	locked bool
+}
+
+// subscriber is a watcher of clipboard changes in a format, or in any
+// format.
+type subscriber struct {
+	t Format
+	// all indicates the subscriber watches changes in any format, and
+	// receives snapshots instead of events.
+	all bool
+	// changes indicates the subscriber receives an event without data
+	// whenever the sequence number changes, see subscribeChanges.
+	changes bool
+	// events indicates whether the subscriber is interested in
+	// EventCleared and EventError events.
+	events bool
+	// interval is the interval of the subscriber, which is the interval
+	// of the monitor if it is not positive.
+	interval time.Duration
+	// ignoreSelf indicates the subscriber ignores the changes of the
+	// writes of this process.
+	ignoreSelf bool
+	// filters are the filters of the changed data, see WatchFilter.
+	filters []func([]byte) bool
+	// lazy indicates the subscriber receives payloads instead of the
+	// changed data, see WatchLazy.
+	lazy bool
+	// due is the time when the subscriber is checked next time.
+	due time.Time
+	// rebase indicates the subscriber takes the clipboard as the
+	// baseline without delivering the change, after ResumeWatchers.
+	rebase bool
+	// count is the change count that is observed when the data was
+	// delivered last time, used on platforms with a change count.
+	count uint64
+	// last is the data that was delivered last time, used on platforms
+	// without a change count.
+	last []byte
+	// empty indicates the clipboard is known to hold no data in the
+	// watched format.
+	empty bool
+	// failed is the error that was delivered last time, which is reset
+	// by a successful read.
+	failed error
+	// snap is the snapshot that was delivered last time, used by
+	// subscribers of all formats on platforms without a change count.
+	snap Snapshot
+	// mail holds the events that are not yet delivered, which is the
+	// latest event unless configured otherwise.
+	mail chan Event
+	// policy is the policy of a full mailbox.
+	policy Backpressure
+	// done is closed when the subscriber is unsubscribed.
+	done chan struct{}
+	// snaps holds the latest snapshot that is not yet delivered.
+	snaps chan Snapshot
+}
+
+var mon = &monitor{
+	subs:     map[*subscriber]struct{}{},
+	interval: defaultPollInterval,
+	kick:     make(chan struct{}, 1),
+}
+
+// setInterval sets the interval of the change detection, which takes
+// effect when the change detection loop starts. A non-positive interval
+// disables polling, and the clipboard is only checked on NotifyUpdate.
+func (m *monitor) setInterval(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.interval = d
+}
+
+// changeInterval returns the interval that the change channels of writes
+// check the clipboard at, see tracker, which is the interval of the
+// monitor unless polling is disabled.
+func changeInterval() time.Duration {
+	mon.mu.Lock()
+	defer mon.mu.Unlock()
+
+	if mon.interval <= 0 {
+		return defaultPollInterval
+	}
+	return mon.interval
+}
+
+// subscribe registers a subscriber for changes of format t, and starts
+// the change detection loop if it is not running yet. The subscriber
+// receives EventCleared and EventError events if events is true, and is
+// configured by the given configuration of the watcher.
+func (m *monitor) subscribe(t Format, events bool, c watchConfig) *subscriber {
+	if c.buffer <= 0 {
+		c.buffer = 1
+	}
+	s := &subscriber{
+		t:          t,
+		events:     events,
+		interval:   c.interval,
+		ignoreSelf: c.ignoreSelf,
+		filters:    c.filters,
+		lazy:       c.lazy,
+		mail:       make(chan Event, c.buffer),
+		policy:     c.policy,
+		done:       make(chan struct{}),
+	}
+	if cnt, ok := sys.sequence(); ok {
+		s.count = cnt
+	} else {
+		s.last = Read(t)
+		s.empty = len(s.last) == 0
+	}
+	m.add(s)
+	return s
+}
+
+// subscribeAll registers a subscriber for changes in any format.
+func (m *monitor) subscribeAll() *subscriber {
+	s := &subscriber{all: true, snaps: make(chan Snapshot, 1), done: make(chan struct{})}
+	if cnt, ok := sys.sequence(); ok {
+		s.count = cnt
+	} else {
+		s.snap = TakeSnapshot()
+	}
+	m.add(s)
+	return s
+}
+
+// subscribeChanges registers a subscriber that is notified of every
+// change of the sequence number of the clipboard, without reading the
+// clipboard. It must only be used on platforms with a sequence number.
+func (m *monitor) subscribeChanges() *subscriber {
+	s := &subscriber{changes: true, mail: make(chan Event, 1), done: make(chan struct{})}
+	s.count, _ = sys.sequence()
+	m.add(s)
+	return s
+}
+
+// add adds the subscriber, and starts the change detection loop if it is
+// not running yet.
+func (m *monitor) add(s *subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subs[s] = struct{}{}
+	m.restart()
+}
+
+// unsubscribe removes the subscriber and stops the change detection
+// loop if there is no subscriber anymore.
+func (m *monitor) unsubscribe(s *subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.subs, s)
+	close(s.done)
+	m.restart()
+}
+
+// every returns the interval of the given subscriber.
+func (m *monitor) every(s *subscriber) time.Duration {
+	if s.interval > 0 {
+		return s.interval
+	}
+	return m.interval
+}
+
+// restart starts the change detection loop at the shortest interval of
+// the subscribers, or stops the loop if there is no subscriber. A running
+// loop is only restarted if its interval changes. The caller must hold
+// m.mu.
+func (m *monitor) restart() {
+	if len(m.subs) == 0 {
+		if m.stop != nil {
+			close(m.stop)
+			m.stop = nil
+		}
+		return
+	}
+
+	tick := m.shortest()
+	if m.stop != nil {
+		if tick == m.tick {
+			return
+		}
+		close(m.stop)
+	}
+	m.stop = make(chan struct{})
+	m.tick = tick
+	go m.run(m.stop, tick)
+}
+
+// shortest returns the shortest positive interval of the subscribers, or
+// zero if none of the subscribers polls.
+func (m *monitor) shortest() time.Duration {
+	var tick time.Duration
+	for s := range m.subs {
+		if d := m.every(s); d > 0 && (tick == 0 || d < tick) {
+			tick = d
+		}
+	}
+	return tick
+}
+
+func (m *monitor) run(stop <-chan struct{}, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ti := time.NewTicker(interval)
+		defer ti.Stop()
+		tick = ti.C
+	}
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick:
+			m.poll(interval)
+		case <-m.kick:
+			m.poll(0)
+		}
+	}
+}
+
+// poll checks the clipboard for changes and notifies the subscribers
+// that are due at a tick of the given interval, or all subscribers if
+// tick is zero, for instance, on NotifyUpdate. The clipboard is read at
+// most once per format.
+func (m *monitor) poll(tick time.Duration) {
+	m.polling.Lock()
+	defer m.polling.Unlock()
+
+	now := time.Now()
+	m.mu.Lock()
+	if m.suspended > 0 {
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	if m.pauseLocked(now) {
+		return
+	}
+
+	m.mu.Lock()
+	subs := make([]*subscriber, 0, len(m.subs))
+	for s := range m.subs {
+		// Tolerate ticks that arrive slightly early.
+		if tick > 0 && now.Add(tick/2).Before(s.due) {
+			continue
+		}
+		s.due = now.Add(m.every(s))
+		subs = append(subs, s)
+	}
+	m.mu.Unlock()
+
+	cnt, ok := sys.sequence()
+	if ok {
+		// Drop the data of the reads before the change.
+		cache.invalidate(cnt, true)
+	}
+	type result struct {
+		b   []byte
+		err error
+	}
+	reads := map[Format]result{}
+	read := func(t Format) ([]byte, error) {
+		r, cached := reads[t]
+		if !cached {
+			r.b, r.err = ReadErr(t)
+			if r.err == ErrUnavailable {
+				// The clipboard holds no data in the format.
+				r.b, r.err = nil, nil
+			}
+			reads[t] = r
+		}
+		return r.b, r.err
+	}
+	var offered func(Format) bool
+	probe := func(t Format) bool {
+		if offered == nil {
+			offered = sys.prober()
+		}
+		return offered(t)
+	}
+	var snap Snapshot
+	snapshot := func() Snapshot {
+		if snap == nil {
+			snap = Snapshot{}
+			for _, t := range allFormats {
+				if !probe(t) {
+					continue
+				}
+				if b, _ := read(t); b != nil {
+					snap[t] = b
+				}
+			}
+		}
+		return snap
+	}
+
+	for _, s := range subs {
+		m.mu.Lock()
+		rebase := s.rebase
+		s.rebase = false
+		m.mu.Unlock()
+		if rebase {
+			switch {
+			case ok:
+				s.count = cnt
+			case s.all:
+				s.snap = snapshot()
+			default:
+				if b, err := read(s.t); err == nil {
+					s.last, s.empty = b, len(b) == 0
+				}
+			}
+			continue
+		}
+		if ok && s.count == cnt {
+			continue
+		}
+		if s.changes {
+			if ok {
+				s.count = cnt
+				s.deliver(Event{Kind: EventChanged, Seq: cnt, Time: now})
+			}
+			continue
+		}
+		if s.all {
+			next := snapshot()
+			if ok {
+				s.count = cnt
+			} else if Diff(s.snap, next).Empty() {
+				continue
+			} else {
+				s.snap = next
+			}
+			s.deliverSnapshot(next)
+			continue
+		}
+		if s.lazy && ok {
+			// The sequence number tells the change, hence the data is
+			// only read once the payload is fetched.
+			if !probe(s.t) {
+				if !s.empty {
+					s.empty = true
+					s.deliver(Event{Kind: EventCleared, Format: s.t, Seq: cnt, Time: now})
+				}
+				continue
+			}
+			s.count = cnt
+			s.empty = false
+			if s.ignoreSelf && isOwnWrite(cnt, ok, s.t, nil) {
+				continue
+			}
+			p := &Payload{t: s.t, seq: cnt, seqOK: ok}
+			s.deliver(Event{Kind: EventChanged, Format: s.t, Payload: p, Seq: cnt, Time: now})
+			continue
+		}
+		b, err := read(s.t)
+		if err != nil {
+			if s.events && (s.failed == nil || s.failed.Error() != err.Error()) {
+				s.deliver(Event{Kind: EventError, Format: s.t, Err: err, Seq: cnt, Time: now})
+			}
+			s.failed = err
+			continue
+		}
+		if s.failed != nil && s.events {
+			s.deliver(Event{Kind: EventReconnected, Format: s.t, Seq: cnt, Time: now})
+		}
+		s.failed = nil
+		if len(b) == 0 {
+			// Data in the format that presents later changes the change
+			// count again, hence the clipboard is not read until then.
+			if ok {
+				s.count = cnt
+			}
+			if !s.empty {
+				s.empty = true
+				s.last = nil
+				if s.events {
+					s.deliver(Event{Kind: EventCleared, Format: s.t, Seq: cnt, Time: now})
+				}
+			}
+			continue
+		}
+		if ok {
+			s.count = cnt
+		} else if bytes.Equal(s.last, b) {
+			continue
+		}
+		s.last = b
+		s.empty = false
+		if s.ignoreSelf && isOwnWrite(cnt, ok, s.t, b) {
+			continue
+		}
+		if !s.accepts(b) {
+			continue
+		}
+		e := Event{Kind: EventChanged, Format: s.t, Data: b, Size: len(b), Seq: cnt, Time: now}
+		if s.lazy {
+			e.Data, e.Payload = nil, &Payload{t: s.t, seq: cnt, seqOK: ok, data: b}
+		}
+		s.deliver(e)
+	}
+}
+
+// pauseLocked reports whether the session is locked, in which case the
+// clipboard is not polled, so that the watchers do not report the errors
+// of an inaccessible clipboard. The subscribers of events are told the
+// transitions by EventSuspended and EventResumed. The caller must hold
+// m.polling.
+func (m *monitor) pauseLocked(now time.Time) bool {
+	locked := sys.locked()
+
+	m.mu.Lock()
+	if locked == m.locked {
+		m.mu.Unlock()
+		return locked
+	}
+	m.locked = locked
+	subs := make([]*subscriber, 0, len(m.subs))
+	for s := range m.subs {
+		if s.events {
+			subs = append(subs, s)
+		}
+	}
+	m.mu.Unlock()
+
+	kind := EventResumed
+	if locked {
+		kind = EventSuspended
+	}
+	for _, s := range subs {
+		s.deliver(Event{Kind: kind, Format: s.t, Time: now})
+	}
+	return locked
+}
+
+// accepts reports whether the changed data passes the filters of the
+// subscriber.
+func (s *subscriber) accepts(b []byte) bool {
+	for _, fn := range s.filters {
+		if !fn(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver puts the event into the mailbox of the subscriber according to
+// its backpressure policy. By default, a subscriber that is slower than
+// the changes of the clipboard only receives the latest event, so that it
+// cannot block other subscribers.
+func (s *subscriber) deliver(e Event) {
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.mail <- e:
+		default:
+		}
+		return
+	case Block:
+		select {
+		case s.mail <- e:
+		case <-s.done:
+		}
+		return
+	}
+	for {
+		select {
+		case s.mail <- e:
+			return
+		default:
+		}
+		select {
+		case <-s.mail:
+		default:
+		}
+	}
+}
+
+// deliverSnapshot is like deliver but for snapshots of subscribers of all
+// formats.
+func (s *subscriber) deliverSnapshot(snap Snapshot) {
+	for {
+		select {
+		case s.snaps <- snap:
+			return
+		default:
+		}
+		select {
+		case <-s.snaps:
+		default:
+		}
+	}
+}
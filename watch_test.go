@@ -0,0 +1,56 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package clipboard_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.design/x/clipboard"
+)
+
+func TestWatchTick(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		watchers []time.Duration
+		want     time.Duration
+	}{
+		{time.Second, []time.Duration{0}, time.Second},
+		{time.Second, []time.Duration{0, 100 * time.Millisecond}, 100 * time.Millisecond},
+		{time.Second, []time.Duration{5 * time.Second}, 5 * time.Second},
+		{0, []time.Duration{0}, 0},
+		{0, []time.Duration{0, 200 * time.Millisecond}, 200 * time.Millisecond},
+		{time.Second, []time.Duration{-time.Second}, time.Second},
+	}
+	for _, tt := range tests {
+		got := clipboard.WatchTick(tt.interval, tt.watchers...)
+		if got != tt.want {
+			t.Fatalf("WatchTick(%v, %v) mismatch, got: %v, want: %v", tt.interval, tt.watchers, got, tt.want)
+		}
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	tests := []struct {
+		policy clipboard.Backpressure
+		buffer int
+		want   []int
+	}{
+		{clipboard.DropOldest, 1, []int{4}},
+		{clipboard.DropOldest, 3, []int{2, 3, 4}},
+		{clipboard.DropNewest, 1, []int{0}},
+		{clipboard.DropNewest, 3, []int{0, 1, 2}},
+		{clipboard.Block, 1, []int{0, 1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		got := clipboard.Backpressured(tt.policy, tt.buffer, 5)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("Backpressured(%v, %v) mismatch, got: %v, want: %v", tt.policy, tt.buffer, got, tt.want)
+		}
+	}
+}